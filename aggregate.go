@@ -0,0 +1,156 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// envVarKeys are the keys of the environment variables read by Parse regardless of the configuration
+// structure and sources, relative to the environment variable prefix.
+var envVarKeys = []string{"CONFIG", "CONFIG_FILE", "CONFIG_FORMAT", "CONFIG_URI", "ERRORS", "LINT", "CA_FILE", "TLS_SKIP_VERIFY", "IP_FAMILY", "ALPHA", "EMERGENCY_CONFIG"}
+
+// Service describes how a binary parses its configuration, to be aggregated with the ones of the other
+// binaries deployed along with it.
+type Service struct {
+	// Name is the name of the binary, e.g. api.
+	Name string
+
+	// EnvVarPrefix is the environment variable prefix the binary passes to Parse.
+	EnvVarPrefix string
+
+	// Conf is the configuration structure the binary passes to Parse, holding its defaults.
+	Conf interface{}
+
+	// Options are the options the binary passes to Parse, if any.
+	Options []Option
+}
+
+// ServiceSurface is the configuration surface of a binary, the options and environment variables it reads.
+type ServiceSurface struct {
+	// Name is the name of the binary.
+	Name string `json:"name"`
+
+	// Prefix is the environment variable prefix of the binary, in upper case followed by an underscore.
+	Prefix string `json:"prefix"`
+
+	// Fields lists the dotted JSON paths of the options of the binary, e.g. database.host, in the order
+	// their fields are declared.
+	Fields []string `json:"fields"`
+
+	// EnvVars lists the environment variables read by the binary, besides the ones of placeholders and
+	// of the options specific to the sources of its configuration.
+	EnvVars []string `json:"envVars"`
+}
+
+// Surface is the unified configuration surface of several binaries, e.g. the ones of a monorepo.
+type Surface struct {
+	// Services lists the configuration surfaces of the binaries, in order.
+	Services []ServiceSurface `json:"services"`
+
+	// Collisions describes the environment variables and prefixes shared by binaries, which would read
+	// each other's configuration when deployed on the same host.
+	Collisions []string `json:"collisions"`
+}
+
+// Aggregate returns the unified configuration surface of the specified services, listing the options and
+// environment variables of each of them, and detecting the collisions between their environment variable
+// prefixes: services sharing a prefix, prefixes nested in others e.g. APP_ and APP_DB_, and environment
+// variables read by several services.
+func Aggregate(services ...Service) (*Surface, error) {
+	surface := &Surface{Services: make([]ServiceSurface, len(services)), Collisions: []string{}}
+
+	for i, s := range services {
+		prefix, err := normalizePrefix(s.EnvVarPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid service [%v]: %v", s.Name, err)
+		}
+
+		data, err := json.Marshal(s.Conf)
+		if err != nil {
+			return nil, fmt.Errorf("invalid service [%v]: %v", s.Name, err)
+		}
+
+		order := leafOrder(data)
+
+		surface.Services[i] = ServiceSurface{Name: s.Name, Prefix: prefix, Fields: make([]string, 0, len(order)), EnvVars: []string{}}
+
+		for path := range order {
+			surface.Services[i].Fields = append(surface.Services[i].Fields, path)
+		}
+
+		sortPaths(surface.Services[i].Fields, order)
+
+		keys := envVarKeys
+
+		if t := reflect.TypeOf(s.Conf); t != nil {
+			if t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+
+			if t.Kind() == reflect.Struct {
+				keys = keys[:len(keys):len(keys)]
+				for _, binding := range envBindings(newOptions(s.Options), t, "", "", make(map[reflect.Type]bool)) {
+					keys = append(keys, binding.key)
+				}
+			}
+		}
+
+		for _, key := range keys {
+			surface.Services[i].EnvVars = append(surface.Services[i].EnvVars, prefix+key)
+		}
+	}
+
+	for i, a := range surface.Services {
+		for _, b := range surface.Services[i+1:] {
+			surface.Collisions = append(surface.Collisions, collisions(a, b)...)
+		}
+	}
+
+	return surface, nil
+}
+
+// collisions describes the collisions between the environment variables of the services a and b.
+func collisions(a, b ServiceSurface) []string {
+	switch {
+	case a.Prefix == b.Prefix:
+		return []string{fmt.Sprintf("services [%v] and [%v] share the environment variable prefix [%v]", a.Name, b.Name, a.Prefix)}
+	case strings.HasPrefix(b.Prefix, a.Prefix):
+		a, b = b, a
+		fallthrough
+	case strings.HasPrefix(a.Prefix, b.Prefix):
+		found := []string{fmt.Sprintf("environment variable prefix [%v] of service [%v] is nested in prefix [%v] of service [%v]", a.Prefix, a.Name, b.Prefix, b.Name)}
+
+		vars := make(map[string]bool, len(b.EnvVars))
+		for _, name := range b.EnvVars {
+			vars[name] = true
+		}
+
+		for _, name := range a.EnvVars {
+			if vars[name] {
+				found = append(found, fmt.Sprintf("services [%v] and [%v] both read the environment variable [%v]", a.Name, b.Name, name))
+			}
+		}
+
+		return found
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	type dbConf struct {
+		Host string `json:"host"`
+		Port int    `json:"port" env:"PORT"`
+	}
+
+	type apiConf struct {
+		Name string `json:"name"`
+		DB   dbConf `json:"db"`
+	}
+
+	type dbServiceConf struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+
+	surface, err := Aggregate(
+		Service{Name: "api", EnvVarPrefix: "app", Conf: &apiConf{}, Options: []Option{WithAutoEnv()}},
+		Service{Name: "db", EnvVarPrefix: "APP_DB", Conf: dbServiceConf{}, Options: []Option{WithAutoEnv()}},
+		Service{Name: "worker", EnvVarPrefix: "WORKER", Conf: &testConf{}},
+		Service{Name: "cron", EnvVarPrefix: "App", Conf: &testConf{}},
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	core := func(prefix string, keys ...string) []string {
+		names := []string{}
+		for _, key := range append(envVarKeys[:len(envVarKeys):len(envVarKeys)], keys...) {
+			names = append(names, prefix+key)
+		}
+		return names
+	}
+
+	expected := &Surface{
+		Services: []ServiceSurface{
+			{Name: "api", Prefix: "APP_", Fields: []string{"name", "db.host", "db.port"}, EnvVars: core("APP_", "NAME", "DB_HOST", "PORT")},
+			{Name: "db", Prefix: "APP_DB_", Fields: []string{"host", "port"}, EnvVars: core("APP_DB_", "HOST", "PORT")},
+			{Name: "worker", Prefix: "WORKER_", Fields: []string{"id", "name", "online"}, EnvVars: core("WORKER_")},
+			{Name: "cron", Prefix: "APP_", Fields: []string{"id", "name", "online"}, EnvVars: core("APP_")},
+		},
+		Collisions: []string{
+			"environment variable prefix [APP_DB_] of service [db] is nested in prefix [APP_] of service [api]",
+			"services [db] and [api] both read the environment variable [APP_DB_HOST]",
+			"services [api] and [cron] share the environment variable prefix [APP_]",
+			"environment variable prefix [APP_DB_] of service [db] is nested in prefix [APP_] of service [cron]",
+		},
+	}
+
+	if !reflect.DeepEqual(surface, expected) {
+		t.Errorf("expected surface: %+v, but found: %+v", expected, surface)
+	}
+
+	for _, s := range []Service{{Name: "x", EnvVarPrefix: "1X", Conf: &testConf{}}, {Name: "y", EnvVarPrefix: "Y", Conf: make(chan int)}} {
+		if _, err = Aggregate(s); err == nil || !strings.HasPrefix(err.Error(), "invalid service ["+s.Name+"]") {
+			t.Errorf("expected error of invalid service [%v], but found: %v", s.Name, err)
+		}
+	}
+}
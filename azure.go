@@ -0,0 +1,254 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// keyVaultResource is the resource managed identity tokens are requested for to access Azure Key Vault.
+	keyVaultResource = "https://vault.azure.net"
+
+	// keyVaultRefContentType is the content type of Azure App Configuration values referencing Key Vault secrets.
+	keyVaultRefContentType = "application/vnd.microsoft.appconfig.keyvaultref+json"
+)
+
+// fetchKeyVault returns the value of the secret located by an azkv://<vault>/<secret>[/<version>]
+// URI as the whole configuration document.
+func fetchKeyVault(ctx context.Context, o *options, u *url.URL) ([]byte, error) {
+	val, err := keyVaultSecret(ctx, o, u.Host+u.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(val), nil
+}
+
+// keyVaultSecret returns the value of the secret referenced as <vault>/<secret>[/<version>] from
+// Azure Key Vault, authenticated with the managed identity of the running host. The vault is either
+// a vault name or a fully qualified host name of a vault in a sovereign cloud.
+func keyVaultSecret(ctx context.Context, o *options, ref string) (string, error) {
+	parts := strings.Split(strings.Trim(ref, "/"), "/")
+
+	if len(parts) < 2 || len(parts) > 3 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", fmt.Errorf("secret reference [%v] must be in the form <vault>/<secret>[/<version>]", ref)
+	}
+
+	host := parts[0]
+	if !strings.Contains(host, ".") {
+		host += ".vault.azure.net"
+	}
+
+	secret := url.PathEscape(parts[1])
+	if len(parts) == 3 {
+		secret += "/" + url.PathEscape(parts[2])
+	}
+
+	token, err := azureToken(ctx, o, keyVaultResource)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("https://%v/secrets/%v?api-version=7.4", host, secret), nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	body, err := doRequest(o.httpClient, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret [%v]: %v", ref, err)
+	}
+
+	res := &struct {
+		Value *string `json:"value"`
+	}{}
+
+	if err = json.Unmarshal(body, res); err != nil || res.Value == nil {
+		return "", fmt.Errorf("failed to read secret [%v]: response carries no value", ref)
+	}
+
+	return *res.Value, nil
+}
+
+// azureToken obtains an access token for the specified resource using the managed identity of
+// the running host, either through the identity endpoint of App Service, Functions and Container Apps,
+// or otherwise through the instance metadata service. $AZURE_CLIENT_ID selects a user-assigned identity.
+func azureToken(ctx context.Context, o *options, resource string) (string, error) {
+	var (
+		query = url.Values{"resource": {resource}}
+		req   *http.Request
+		err   error
+	)
+
+	if clientID := platformEnv(o, "AZURE_CLIENT_ID"); len(clientID) > 0 {
+		query.Set("client_id", clientID)
+	}
+
+	if endpoint, header := platformEnv(o, "IDENTITY_ENDPOINT"), platformEnv(o, "IDENTITY_HEADER"); len(endpoint) > 0 && len(header) > 0 {
+		query.Set("api-version", "2019-08-01")
+
+		if req, err = http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil); err != nil {
+			return "", err
+		}
+
+		req.Header.Set("X-IDENTITY-HEADER", header)
+	} else {
+		query.Set("api-version", "2018-02-01")
+
+		if req, err = http.NewRequestWithContext(ctx, http.MethodGet, "http://169.254.169.254/metadata/identity/oauth2/token?"+query.Encode(), nil); err != nil {
+			return "", err
+		}
+
+		req.Header.Set("Metadata", "true")
+	}
+
+	token, err := accessToken(o, req)
+	if err != nil {
+		return "", errors.New("managed identity: " + err.Error())
+	}
+
+	return token, nil
+}
+
+// fetchAppConfig assembles a configuration document out of the key-values stored in Azure App
+// Configuration, located by an azappconfig://<store>[?key=<filter>][&label=<label>...][&separator=<sep>]
+// URI. The key filter defaults to all keys, its literal prefix before a trailing "*" is trimmed from
+// the keys, and the remainder is split by the separator, ":" by default, into nested objects.
+// Multiple labels may be specified, in which case values of later labels override earlier ones,
+// e.g. ?label=%00&label=production where %00 selects the key-values without a label.
+// Values of JSON content types are inserted as JSON values, and Key Vault references are resolved.
+func fetchAppConfig(ctx context.Context, o *options, u *url.URL) ([]byte, error) {
+	var (
+		query     = u.Query()
+		host      = u.Host
+		filter    = query.Get("key")
+		labels    = query["label"]
+		separator = query.Get("separator")
+		tree      = make(map[string]interface{})
+	)
+
+	if len(host) == 0 {
+		return nil, errors.New("URI must be in the form azappconfig://<store>[?key=<filter>][&label=<label>]")
+	}
+
+	if !strings.Contains(host, ".") {
+		host += ".azconfig.io"
+	}
+
+	if len(filter) == 0 {
+		filter = "*"
+	}
+
+	if len(labels) == 0 {
+		labels = []string{""}
+	}
+
+	if len(separator) == 0 {
+		separator = ":"
+	}
+
+	token, err := azureToken(ctx, o, "https://"+host)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, label := range labels {
+		q := url.Values{"key": {filter}, "api-version": {"1.0"}}
+		if len(label) > 0 {
+			q.Set("label", label)
+		}
+
+		for next := "/kv?" + q.Encode(); len(next) > 0; {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+host+next, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			body, err := doRequest(o.httpClient, req)
+			if err != nil {
+				return nil, err
+			}
+
+			page := &struct {
+				Items []struct {
+					Key         string `json:"key"`
+					Value       string `json:"value"`
+					ContentType string `json:"content_type"`
+				} `json:"items"`
+				NextLink string `json:"@nextLink"`
+			}{}
+
+			if err = json.Unmarshal(body, page); err != nil {
+				return nil, fmt.Errorf("invalid key-values response: %v", err)
+			}
+
+			for _, item := range page.Items {
+				var val interface{} = item.Value
+
+				switch ct := strings.ToLower(item.ContentType); {
+				case strings.HasPrefix(ct, keyVaultRefContentType):
+					if val, err = appConfigSecret(ctx, o, item.Value); err != nil {
+						return nil, fmt.Errorf("key [%v]: %v", item.Key, err)
+					}
+				case strings.HasPrefix(ct, "application/json") || (strings.HasPrefix(ct, "application/") && strings.Contains(ct, "+json")):
+					if err = json.Unmarshal([]byte(item.Value), &val); err != nil {
+						return nil, fmt.Errorf("key [%v] holds invalid JSON: %v", item.Key, err)
+					}
+				}
+
+				key := strings.TrimPrefix(item.Key, strings.TrimSuffix(filter, "*"))
+
+				if err = setPath(tree, strings.Split(key, separator), val); err != nil {
+					return nil, err
+				}
+			}
+
+			next = page.NextLink
+		}
+	}
+
+	return json.Marshal(tree)
+}
+
+// appConfigSecret resolves the value of an Azure App Configuration Key Vault reference.
+func appConfigSecret(ctx context.Context, o *options, ref string) (string, error) {
+	res := &struct {
+		URI string `json:"uri"`
+	}{}
+
+	if err := json.Unmarshal([]byte(ref), res); err != nil {
+		return "", fmt.Errorf("invalid Key Vault reference: %v", err)
+	}
+
+	u, err := url.Parse(res.URI)
+	if err != nil || !strings.HasPrefix(u.Path, "/secrets/") {
+		return "", fmt.Errorf("invalid Key Vault reference [%v]", res.URI)
+	}
+
+	return keyVaultSecret(ctx, o, u.Host+strings.TrimPrefix(u.Path, "/secrets"))
+}
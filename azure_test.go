@@ -0,0 +1,134 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCliKeyVault(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metadata/identity/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("resource") != keyVaultResource {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"access_token":"vault-token"}`))
+	})
+	mux.HandleFunc("/secrets/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer vault-token" || r.Host != "myvault.vault.azure.net" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/secrets/app-config":
+			w.Write([]byte(`{"value":"{\"id\":4,\"name\":\"${azkv:myvault/app-name/v2}\",\"online\":true}"}`))
+		case "/secrets/app-name/v2":
+			w.Write([]byte(`{"value":"Carol"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	t.Setenv("IDENTITY_ENDPOINT", "")
+	client := &http.Client{Transport: rewriteTransport(srv.URL)}
+
+	cases := []struct {
+		args     []string
+		expected testConf
+		err      string
+	}{
+		{[]string{"", "-config-uri", "azkv://myvault/app-config"}, testConf{ID: 4, Name: "Carol", Online: true}, ""},
+		{[]string{"", "-config", `{"id":5,"name":"${azkv:myvault/app-name/v2}"}`}, testConf{ID: 5, Name: "Carol"}, ""},
+		{[]string{"", "-config", `{"id":6,"name":"${unknown:myvault/app-name}"}`}, testConf{ID: 6, Name: "${unknown:myvault/app-name}"}, ""},
+		{[]string{"", "-config", `{"name":"${azkv:myvault}"}`}, testConf{}, "secret reference [myvault] must be in the form <vault>/<secret>[/<version>]"},
+		{[]string{"", "-config", `{"name":"${azkv:myvault/missing}"}`}, testConf{}, "failed to read secret [myvault/missing]: unexpected response status [404 Not Found]: 404 page not found"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: c.args}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf, func(o *options) { o.httpClient = client })
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && err.Error() != c.err) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
+
+func TestCliAppConfig(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/identity", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-IDENTITY-HEADER") != "identity-secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"access_token":"` + r.URL.Query().Get("resource") + `"}`))
+	})
+	mux.HandleFunc("/secrets/db-password", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"value":"p@ss"}`))
+	})
+	mux.HandleFunc("/kv", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer https://store.azconfig.io" || r.URL.Query().Get("key") != "app:*" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Query().Get("label") + "/" + r.URL.Query().Get("page") {
+		case "\x00/":
+			w.Write([]byte(`{"items":[{"key":"app:name","value":"Dave"},{"key":"app:db:host","value":"localhost"}],"@nextLink":"/kv?key=app:*&page=2"}`))
+		case "/2", "\x00/2":
+			w.Write([]byte(`{"items":[{"key":"app:online","value":"true","content_type":"application/json"}]}`))
+		case "prod/":
+			w.Write([]byte(`{"items":[{"key":"app:db:host","value":"db.prod"},{"key":"app:db:password","value":"{\"uri\":\"https://vault.vault.azure.net/secrets/db-password\"}","content_type":"` + keyVaultRefContentType + `;charset=utf-8"}]}`))
+		default:
+			w.Write([]byte(`{"items":[]}`))
+		}
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	t.Setenv("IDENTITY_ENDPOINT", srv.URL+"/identity")
+	t.Setenv("IDENTITY_HEADER", "identity-secret")
+	client := &http.Client{Transport: rewriteTransport(srv.URL)}
+
+	conf := &struct {
+		Name   string `json:"name"`
+		Online bool   `json:"online"`
+		DB     struct {
+			Host     string `json:"host"`
+			Password string `json:"password"`
+		} `json:"db"`
+	}{}
+
+	_, err := withMockedArgs(&input{args: []string{"", "-config-uri", "azappconfig://store?key=app:*&label=%00&label=prod"}}, func(in *input) (string, error) {
+		return Parse("TEST", "", nil, conf, func(o *options) { o.httpClient = client })
+	})
+
+	if err != nil || conf.Name != "Dave" || !conf.Online || conf.DB.Host != "db.prod" || conf.DB.Password != "p@ss" {
+		t.Errorf("expected output: ({Name:Dave Online:true DB:{Host:db.prod Password:p@ss}}, <nil>), but found: (%+v, %v)", *conf, err)
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// stages are the stages of loading the configuration whose durations are reported when the
+// startup budget is exceeded, in the order they are reported.
+var stages = []string{"fetch", "decrypt", "encrypt", "resolve", "decode", "validate"}
+
+// track starts timing the specified stage, and returns the function to call when the stage ends.
+func track(o *options, stage string) func() {
+	start := time.Now()
+
+	return func() {
+		o.stages[stage] += time.Since(start)
+	}
+}
+
+// budgetError reports that loading the configuration took longer than its budget, breaking the elapsed
+// time down by stage, along with the error it ended with if any.
+func budgetError(o *options, elapsed time.Duration, err error) error {
+	breakdown := make([]string, len(stages))
+
+	for i, stage := range stages {
+		breakdown[i] = fmt.Sprintf("%v %v", stage, o.stages[stage].Round(time.Millisecond))
+	}
+
+	msg := fmt.Sprintf("loading the configuration exceeded its budget of %v after %v, time spent per stage: %v",
+		o.budget, elapsed.Round(time.Millisecond), strings.Join(breakdown, ", "))
+
+	if err != nil {
+		return fmt.Errorf("%v: %v", msg, err)
+	}
+
+	return errors.New(msg)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCliBudget(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.Write([]byte(`{"id": 45, "name": "Erin"}`))
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		uri      string
+		budget   time.Duration
+		expected testConf
+		err      string
+	}{
+		{srv.URL + "/fast", time.Second, testConf{ID: 45, Name: "Erin"}, ""},
+		{srv.URL + "/slow", 50 * time.Millisecond, testConf{}, "loading the configuration exceeded its budget of 50ms after"},
+		{srv.URL + "/slow", 50 * time.Millisecond, testConf{}, ", time spent per stage: fetch "},
+		{srv.URL + "/slow", 50 * time.Millisecond, testConf{}, "decrypt 0s, encrypt 0s, resolve 0s, decode 0s, validate 0s: failed to fetch configuration from"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf, WithBudget(c.budget))
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
+
+func TestCliBudgetStages(t *testing.T) {
+	conf := &testConf{}
+
+	// validation is timed on its own, apart from decoding.
+	_, err := withMockedArgs(&input{args: []string{"", "-config", `{"id": 1}`}}, func(in *input) (string, error) {
+		return Parse("TEST", "", nil, conf, WithBudget(50*time.Millisecond), WithValidationHook(func(interface{}, Provenance) error {
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		}))
+	})
+
+	m := regexp.MustCompile(`decode ([0-9]+)(?:ms|s), validate ([0-9]+)ms$`).FindStringSubmatch(fmt.Sprint(err))
+	if m == nil {
+		t.Fatalf("expected the budget to be exceeded while validating, but found: %v", err)
+	}
+
+	if decode, _ := strconv.Atoi(m[1]); decode >= 50 {
+		t.Errorf("expected decoding to take less than 50ms, but found: %v", err)
+	}
+
+	if validate, _ := strconv.Atoi(m[2]); validate < 100 {
+		t.Errorf("expected validating to take at least 100ms, but found: %v", err)
+	}
+}
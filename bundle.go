@@ -0,0 +1,176 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+)
+
+// bundleManifest is the name of the file describing the content of a configuration bundle.
+const bundleManifest = "manifest.json"
+
+// manifest describes how the documents of a configuration bundle are merged.
+type manifest struct {
+	// Format is the format of the layers whose extension does not tell it, defaults to json.
+	Format string `json:"format"`
+
+	// Layers are the paths of the documents in the bundle, merged in order such that
+	// the values of a layer override the ones of the layers before it.
+	Layers []string `json:"layers"`
+}
+
+// isBundle reports whether doc is a tar or zip archive, gzip compressed archives are decompressed beforehand.
+func isBundle(doc []byte) bool {
+	return bytes.HasPrefix(doc, []byte("PK\x03\x04")) ||
+		(len(doc) > 262 && bytes.Equal(doc[257:262], []byte("ustar")))
+}
+
+// gunzip returns doc decompressed when it is gzip compressed, whether it is a plain document or a bundle,
+// otherwise doc as it is.
+func gunzip(doc []byte) ([]byte, error) {
+	if !bytes.HasPrefix(doc, []byte{0x1f, 0x8b}) {
+		return doc, nil
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(doc))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip compressed configuration: %v", err)
+	}
+	defer gr.Close()
+
+	if doc, err = io.ReadAll(gr); err != nil {
+		return nil, fmt.Errorf("invalid gzip compressed configuration: %v", err)
+	}
+
+	return doc, nil
+}
+
+// unbundle merges the layers of a configuration bundle archive into a single JSON document,
+// following the order described by the manifest.json file at the root of the archive e.g.
+//
+//	{"layers": ["base.json", "overlays/production.cue"]}
+func unbundle(o *options, doc []byte) ([]byte, error) {
+	files, err := readArchive(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration bundle: %v", err)
+	}
+
+	data, found := files[bundleManifest]
+	if !found {
+		return nil, fmt.Errorf("invalid configuration bundle: missing %v", bundleManifest)
+	}
+
+	m := &manifest{}
+	if err = json.Unmarshal(stripJSONC(data), m); err != nil {
+		return nil, fmt.Errorf("invalid configuration bundle %v: %v", bundleManifest, err)
+	}
+
+	if len(m.Format) == 0 {
+		m.Format = "json"
+	}
+
+	var merged interface{} = make(map[string]interface{})
+
+	for _, layer := range m.Layers {
+		data, found := files[path.Clean(layer)]
+		if !found {
+			return nil, fmt.Errorf("invalid configuration bundle: missing layer [%v]", layer)
+		}
+
+		format := formatOf(layer)
+		if len(format) == 0 {
+			format = m.Format
+		}
+
+		if data, err = toJSON(o, format, data); err != nil {
+			return nil, fmt.Errorf("invalid configuration bundle layer [%v]: %v", layer, err)
+		}
+
+		var val interface{}
+		if err = json.Unmarshal(data, &val); err != nil {
+			return nil, fmt.Errorf("invalid configuration bundle layer [%v]: %v", layer, err)
+		}
+
+		merged = deepMerge(merged, val)
+	}
+
+	return json.Marshal(merged)
+}
+
+// readArchive returns the regular files of a tar or zip archive by their cleaned paths.
+func readArchive(doc []byte) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+
+	if bytes.HasPrefix(doc, []byte("PK\x03\x04")) {
+		zr, err := zip.NewReader(bytes.NewReader(doc), int64(len(doc)))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range zr.File {
+			if !f.Mode().IsRegular() {
+				continue
+			}
+
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+
+			data, err := io.ReadAll(rc)
+			rc.Close()
+
+			if err != nil {
+				return nil, err
+			}
+
+			files[path.Clean(f.Name)] = data
+		}
+
+		return files, nil
+	}
+
+	tr := tar.NewReader(bytes.NewReader(doc))
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+
+		files[path.Clean(hdr.Name)] = data
+	}
+}
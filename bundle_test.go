@@ -0,0 +1,123 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// bundleFiles are the files of the configuration bundles served in tests.
+var bundleFiles = map[string]string{
+	"manifest.json": `{"layers": ["base.json", "overlays/prod.cue", "overlays/site"], /* site overrides */ "format": "json",}`,
+	"base.json":     `{"id": 1, "name": "base", "online": false}`,
+	"overlays/prod.cue": `
+		name:   "${NAME}"
+		online: true`,
+	"overlays/site": `{"id": 12}`,
+}
+
+func zipBundle(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+
+	return buf.Bytes()
+}
+
+func tarGzBundle(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: "./" + name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gw.Close()
+
+	return buf.Bytes()
+}
+
+func TestCliBundle(t *testing.T) {
+	broken := map[string]string{"manifest.json": `{"layers": ["missing.json"]}`}
+
+	var gz bytes.Buffer
+
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte(`{"id": 3, "name": "${NAME}"}`))
+	gw.Close()
+
+	bundles := map[string][]byte{
+		"/storage/v1/b/bucket/o/conf.zip":     zipBundle(t, bundleFiles),
+		"/storage/v1/b/bucket/o/conf.tgz":     tarGzBundle(t, bundleFiles),
+		"/storage/v1/b/bucket/o/broken.zip":   zipBundle(t, broken),
+		"/storage/v1/b/bucket/o/nomanif.zip":  zipBundle(t, map[string]string{"base.json": "{}"}),
+		"/storage/v1/b/bucket/o/conf.json.gz": gz.Bytes(),
+		"/storage/v1/b/bucket/o/broken.gz":    gz.Bytes()[:gz.Len()-4],
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bundles[r.URL.Path])
+	}))
+	defer srv.Close()
+
+	t.Setenv("STORAGE_EMULATOR_HOST", strings.TrimPrefix(srv.URL, "http://"))
+	t.Setenv("TEST_NAME", "Ivan")
+
+	cases := []struct {
+		uri      string
+		expected testConf
+		err      string
+	}{
+		{"gs://bucket/conf.zip", testConf{ID: 12, Name: "Ivan", Online: true}, ""},
+		{"gs://bucket/conf.tgz", testConf{ID: 12, Name: "Ivan", Online: true}, ""},
+		{"gs://bucket/broken.zip", testConf{}, "invalid configuration bundle: missing layer [missing.json]"},
+		{"gs://bucket/nomanif.zip", testConf{}, "invalid configuration bundle: missing manifest.json"},
+		{"gs://bucket/conf.json.gz", testConf{ID: 3, Name: "Ivan"}, ""},
+		{"gs://bucket/broken.gz", testConf{}, "invalid gzip compressed configuration: unexpected EOF"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && err.Error() != c.err) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
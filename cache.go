@@ -0,0 +1,154 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cachedDocument is the content of a cache file, before encryption.
+type cachedDocument struct {
+	Format string `json:"format"`
+	Doc    []byte `json:"doc"`
+}
+
+// cachePath returns the path of the cache file of the document located by uri, named after
+// its digest so that the credentials the URI may carry are never written to the disk.
+func cachePath(o *options, uri string) string {
+	sum := sha256.Sum256([]byte(uri))
+	return filepath.Join(o.cacheDir, hex.EncodeToString(sum[:])+".cache")
+}
+
+// cacheAEAD returns the AES-GCM cipher encrypting cache files with key.
+func cacheAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration cache key: %v", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// writeCache encrypts the document located by uri along with its format into its cache file.
+func writeCache(o *options, uri string, doc []byte, format string) error {
+	defer track(o, "encrypt")()
+
+	plain, err := json.Marshal(&cachedDocument{Format: format, Doc: doc})
+	if err != nil {
+		return err
+	}
+
+	return writeSealed(o.cacheKey, cachePath(o, uri), plain)
+}
+
+// readCache decrypts the cache file of the document located by uri, and returns the document
+// along with its format and the time it was cached.
+func readCache(o *options, uri string) ([]byte, string, time.Time, error) {
+	defer track(o, "decrypt")()
+
+	plain, at, err := readSealed(o.cacheKey, cachePath(o, uri))
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+
+	cached := &cachedDocument{}
+	if err = json.Unmarshal(plain, cached); err != nil {
+		return nil, "", time.Time{}, errors.New("cache file is corrupted")
+	}
+
+	return cached.Doc, cached.Format, at, nil
+}
+
+// writeSealed encrypts plain with key into the file at path, which is replaced atomically so that
+// a crash never leaves a partially written cache behind.
+func writeSealed(key []byte, path string, plain []byte) error {
+	aead, err := cacheAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	// the path is authenticated along with the content, so cache files cannot be swapped.
+	if _, err = tmp.Write(aead.Seal(nonce, nonce, plain, []byte(filepath.Base(path)))); err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// readSealed decrypts the file at path written by writeSealed with key, and returns its content
+// along with the time it was written.
+func readSealed(key []byte, path string) ([]byte, time.Time, error) {
+	aead, err := cacheAEAD(key)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	if len(data) < aead.NonceSize() {
+		return nil, time.Time{}, errors.New("cache file is corrupted")
+	}
+
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(filepath.Base(path)))
+	if err != nil {
+		return nil, time.Time{}, errors.New("cache file is corrupted or encrypted with another key")
+	}
+
+	return plain, info.ModTime(), nil
+}
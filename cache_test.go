@@ -0,0 +1,91 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCliCache(t *testing.T) {
+	var down bool
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/cue")
+		w.Write([]byte("id: 39\nname: \"Yolanda\""))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	key := []byte("0123456789abcdef0123456789abcdef")
+	uri := strings.Replace(srv.URL, "://", "://user:s3cr3t@", 1) + "/api"
+
+	cases := []struct {
+		down     bool
+		key      []byte
+		expected testConf
+		err      string
+		warning  string
+	}{
+		{true, key, testConf{}, "unexpected response status [503 Service Unavailable]", ""},
+		{false, key, testConf{ID: 39, Name: "Yolanda"}, "", ""},
+		{true, key, testConf{ID: 39, Name: "Yolanda"}, "", "WARNING: using the configuration cached at"},
+		{true, []byte("fedcba9876543210fedcba9876543210"), testConf{}, "unexpected response status [503 Service Unavailable]", ""},
+		{false, []byte("short"), testConf{ID: 39, Name: "Yolanda"}, "", "WARNING: failed to cache the configuration: invalid configuration cache key"},
+	}
+
+	for _, c := range cases {
+		var logs bytes.Buffer
+
+		down = c.down
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf, WithCache(dir, c.key), WithLogger(log.New(&logs, "", 0)))
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+
+		if !strings.Contains(logs.String(), c.warning) || (len(c.warning) == 0 && logs.Len() > 0) {
+			t.Errorf("expected warning: %q, but found: %q", c.warning, logs.String())
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		data, _ := os.ReadFile(dir + "/" + entry.Name())
+
+		if strings.HasPrefix(entry.Name(), ".tmp-") || bytes.Contains(data, []byte("Yolanda")) || bytes.Contains(data, []byte("s3cr3t")) {
+			t.Errorf("expected only encrypted cache files, but found: %v", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Codec translates the configuration documents of a format to JSON documents and back, so that applications
+// can read configurations of formats that are not supported out of the box.
+type Codec interface {
+	// Detect reports whether doc is written in the format of the codec, which is only asked when the format
+	// is neither specified nor implied by the extension of the document.
+	Detect(doc []byte) bool
+
+	// Unmarshal translates doc into a JSON document.
+	Unmarshal(doc []byte) ([]byte, error)
+
+	// Marshal translates the JSON document doc into the format of the codec.
+	Marshal(doc []byte) ([]byte, error)
+}
+
+// codecs maps the names of the formats registered by RegisterCodec to their codecs.
+var codecs = struct {
+	sync.RWMutex
+	byName map[string]Codec
+}{byName: make(map[string]Codec)}
+
+// RegisterCodec makes codec read the configuration documents of the format of the specified name, selected
+// with the --config-format option or by the extension of the documents, e.g. toml for config.toml, or else
+// detected by the codec. It is meant to be called from init functions, and it panics if codec is nil, if the
+// name is not made of letters or numbers starting with a letter, or if the format is already supported.
+func RegisterCodec(name string, codec Codec) {
+	name = strings.ToLower(name)
+
+	if !schemeRegex.MatchString(name) || codec == nil {
+		panic("config: RegisterCodec requires a name of letters or numbers and a codec")
+	}
+
+	codecs.Lock()
+	defer codecs.Unlock()
+
+	_, found := codecs.byName[name]
+	if _, builtin := formats[name]; found || builtin {
+		panic(fmt.Sprintf("config: RegisterCodec called twice for format [%v]", name))
+	}
+
+	codecs.byName[name] = codec
+}
+
+// codecOf returns the codec registered for the specified format if any.
+func codecOf(format string) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	codec, found := codecs.byName[strings.ToLower(format)]
+
+	return codec, found
+}
+
+// codecNames returns the sorted names of the formats registered by RegisterCodec.
+func codecNames() []string {
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	names := make([]string, 0, len(codecs.byName))
+	for name := range codecs.byName {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// detectFormat returns the format of doc, the first in order of name of the registered formats whose
+// codec detects it, otherwise json.
+func detectFormat(doc []byte) string {
+	for _, name := range codecNames() {
+		if codec, found := codecOf(name); found && codec.Detect(doc) {
+			return name
+		}
+	}
+
+	return "json"
+}
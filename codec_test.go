@@ -0,0 +1,120 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// kvCodec reads documents of key=value lines, starting with a #kv line when detected.
+type kvCodec struct{}
+
+func (kvCodec) Detect(doc []byte) bool {
+	return bytes.HasPrefix(doc, []byte("#kv\n"))
+}
+
+func (kvCodec) Unmarshal(doc []byte) ([]byte, error) {
+	vals := make(map[string]string)
+
+	for _, line := range strings.Split(string(doc), "\n") {
+		if key, val, found := strings.Cut(line, "="); found && !strings.HasPrefix(line, "#") {
+			vals[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+	}
+
+	return json.Marshal(vals)
+}
+
+func (kvCodec) Marshal(doc []byte) ([]byte, error) {
+	vals := make(map[string]string)
+	if err := json.Unmarshal(doc, &vals); err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0, len(vals))
+	for key, val := range vals {
+		lines = append(lines, key+"="+val)
+	}
+
+	sort.Strings(lines)
+
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+func TestCliCodec(t *testing.T) {
+	RegisterCodec("KV", kvCodec{})
+	t.Cleanup(func() {
+		codecs.Lock()
+		delete(codecs.byName, "kv")
+		codecs.Unlock()
+	})
+
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"conf.kv":  "name = ${NAME}\n",
+		"conf.txt": "#kv\nname = detected\n",
+	}
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		args     []string
+		expected string
+		err      string
+	}{
+		{[]string{"-config-uri", "file://" + filepath.Join(dir, "conf.kv")}, "codec", ""},
+		{[]string{"-config-uri", "file://" + filepath.Join(dir, "conf.txt")}, "detected", ""},
+		{[]string{"-config", "#kv\nname = inline"}, "inline", ""},
+		{[]string{"-config", "name = ${NAME}", "-config-format", "kv"}, "codec", ""},
+		{[]string{"-config", "name = x", "-config-format", "toml"}, "", "supported formats are: cue, dhall, json, jsonnet, kv, star"},
+	}
+
+	for _, c := range cases {
+		conf := &struct {
+			Name string `json:"name"`
+		}{}
+
+		_, err := Parse("CODEC", "", nil, conf, WithEnvironment(map[string]string{"CODEC_NAME": "codec"}), WithArgs(append([]string{""}, c.args...)...))
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || conf.Name != c.expected {
+			t.Errorf("expected output: (%v, %v), but found: (%v, %v)", c.expected, c.err, conf.Name, err)
+		}
+	}
+
+	for _, name := range []string{"json", "kv", "k-v"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected registering format [%v] to panic", name)
+				}
+			}()
+
+			RegisterCodec(name, kvCodec{})
+		}()
+	}
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// markerRegex matches the markers standing for the placeholders of a document in placeholderPaths.
+var markerRegex = regexp.MustCompile("\x00([0-9]+)\x00")
+
+// checkEnvCollisions fails when an environment variable is bound to several fields of the configuration
+// structure, or when it is both injected by a placeholder of the JSON document doc and bound to another
+// field than the one the placeholder is the value of, since one of them would silently shadow the other.
+// Placeholders are only located in JSON documents, any other format is only checked for the former.
+func checkEnvCollisions(o *options, format, doc string) error {
+	if o.confType == nil || o.confType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	bindings := envBindings(o, o.confType, "", "", make(map[reflect.Type]bool))
+	if len(bindings) == 0 {
+		return nil
+	}
+
+	bound := make(map[string]string, len(bindings))
+
+	for _, binding := range bindings {
+		name := envCollisionName(o, o.envVarPrefix+binding.key)
+
+		if path, found := bound[name]; found && path != binding.path {
+			return fmt.Errorf("environment variable [$%v] is bound to both fields [%v] and [%v]", o.envVarPrefix+binding.key, path, binding.path)
+		}
+
+		bound[name] = binding.path
+	}
+
+	if !strings.EqualFold(format, "json") {
+		return nil
+	}
+
+	targets := placeholderTargets(o, doc)
+
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		path, found := bound[envCollisionName(o, name)]
+		if !found {
+			continue
+		}
+
+		sort.Strings(targets[name])
+
+		// keys match the names of fields regardless of their case, as when decoding.
+		for _, target := range targets[name] {
+			if !strings.EqualFold(target, path) {
+				return fmt.Errorf("environment variable [$%v] is injected into [%v] by a placeholder while bound to field [%v], which silently overrides it", name, target, path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// envCollisionName returns the name under which the environment variable of the specified name collides
+// with others, regardless of its case when WithCaseInsensitiveEnv is set.
+func envCollisionName(o *options, name string) string {
+	if o.caseInsensitiveEnv {
+		return strings.ToUpper(name)
+	}
+
+	return name
+}
+
+// placeholderTargets maps the names of the environment variables of the placeholders of the JSON document
+// doc to the dotted JSON paths of the values they are part of, e.g. tags[0] or server.port.
+func placeholderTargets(o *options, doc string) map[string][]string {
+	locs, paths := placeholderPaths(doc)
+
+	targets := make(map[string][]string)
+
+	for i, loc := range locs {
+		if len(paths[i]) == 0 {
+			continue
+		}
+
+		var name string
+		if loc[4] >= 0 {
+			name = doc[loc[4]:loc[5]]
+		} else {
+			name = o.envVarPrefix + doc[loc[6]:loc[7]]
+		}
+
+		targets[name] = append(targets[name], paths[i]...)
+	}
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	return targets
+}
+
+// placeholderPaths returns the locations of the placeholders of the JSON document doc, as matched by
+// placeHolderRegex, along with the dotted JSON paths of the values each of them is part of. Placeholders
+// are replaced by markers, quoted unless they are part of strings, so that the document can be decoded and
+// walked. Escaped placeholders, and the ones of documents that are invalid regardless of their placeholders,
+// have no paths.
+func placeholderPaths(doc string) ([][]int, [][]string) {
+	var (
+		b        strings.Builder
+		last     int
+		inString bool
+		escaped  bool
+		marked   bool
+	)
+
+	locs := placeHolderRegex.FindAllStringSubmatchIndex(doc, -1)
+	paths := make([][]string, len(locs))
+
+	for i, loc := range locs {
+		// the state of the strings of the document is tracked up to the placeholder.
+		for _, c := range doc[last:loc[0]] {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = inString
+			case c == '"':
+				inString = !inString
+			}
+		}
+
+		b.WriteString(doc[last:loc[0]])
+		last = loc[1]
+
+		if isEscaped(doc[loc[0]:loc[1]]) {
+			b.WriteString(doc[loc[0]:loc[1]])
+			continue
+		}
+
+		marker := fmt.Sprintf("\\u0000%v\\u0000", i)
+		if !inString {
+			marker = `"` + marker + `"`
+		}
+
+		b.WriteString(marker)
+		marked = true
+	}
+
+	if !marked {
+		return locs, paths
+	}
+
+	b.WriteString(doc[last:])
+
+	var val interface{}
+	if err := json.Unmarshal([]byte(b.String()), &val); err == nil {
+		walkTargets("", val, paths)
+	}
+
+	return locs, paths
+}
+
+// walkTargets adds the paths of the values of val, located at path, holding markers of placeholders to the
+// paths of the placeholders.
+func walkTargets(path string, val interface{}, paths [][]string) {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		for key, elem := range v {
+			if len(path) > 0 {
+				key = path + "." + key
+			}
+
+			walkTargets(key, elem, paths)
+		}
+	case []interface{}:
+		for i, elem := range v {
+			walkTargets(fmt.Sprintf("%v[%v]", path, i), elem, paths)
+		}
+	case string:
+		for _, m := range markerRegex.FindAllStringSubmatch(v, -1) {
+			if i, err := strconv.Atoi(m[1]); err == nil && i < len(paths) {
+				paths[i] = append(paths[i], path)
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+type collisionConf struct {
+	Server struct {
+		Port int `json:"port" env:"PORT"`
+	} `json:"server"`
+	Metrics struct {
+		Port int `json:"port"`
+	} `json:"metrics"`
+	Tags []string `json:"tags"`
+	Name string   `json:"name"`
+}
+
+type aliasConf struct {
+	Primary   string `json:"primary" env:"HOST"`
+	Secondary string `json:"secondary" env:"HOST"`
+}
+
+func TestCliEnvCollisions(t *testing.T) {
+	cases := []struct {
+		conf   interface{}
+		config string
+		opts   []Option
+		err    string
+	}{
+		{&collisionConf{}, `{"server": {"port": ${PORT}}, "name": "${NAME:-x}"}`, nil, ""},
+		{&collisionConf{}, `{"Server": {"PORT": ${PORT}}}`, nil, ""},
+		{&collisionConf{}, `{"metrics": {"port": ${PORT}}}`, nil, "environment variable [$COL_PORT] is injected into [metrics.port] by a placeholder while bound to field [server.port], which silently overrides it"},
+		{&collisionConf{}, `{"tags": ["a", "${env:COL_PORT}"]}`, nil, "environment variable [$COL_PORT] is injected into [tags[1]] by a placeholder while bound to field [server.port]"},
+		{&collisionConf{}, `// ${PORT}
+{"name": "$${PORT} \"${NAME:-x}\""}`, nil, ""},
+		{&collisionConf{}, `{"name": "\"${PORT}\""}`, nil, "environment variable [$COL_PORT] is injected into [name]"},
+		{&collisionConf{}, `{"metrics": {"port": ${PORT}`, nil, "unexpected end of JSON input"},
+		{&collisionConf{}, `{"metrics": {"port": ${NAME:-1}}}`, []Option{WithAutoEnv()}, "environment variable [$COL_NAME] is injected into [metrics.port] by a placeholder while bound to field [name]"},
+		{&collisionConf{}, `{"metrics": {"port": ${env:col_port}}}`, nil, ""},
+		{&collisionConf{}, `{"metrics": {"port": ${env:col_port}}}`, []Option{WithCaseInsensitiveEnv()}, "environment variable [$col_port] is injected into [metrics.port] by a placeholder while bound to field [server.port]"},
+		{&aliasConf{}, `{}`, nil, "environment variable [$COL_HOST] is bound to both fields [primary] and [secondary]"},
+	}
+
+	for _, c := range cases {
+		opts := append(c.opts, WithEnvironment(map[string]string{"COL_PORT": "1", "col_port": "2"}), WithArgs("", "-config", c.config))
+
+		_, err := Parse("COL", "", nil, c.conf, opts...)
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) {
+			t.Errorf("expected error: %v, but found: %v for %v", c.err, err, c.config)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// secretKeyRegex matches the keys of options whose values look secret, e.g. db_password or apiKey.
+	secretKeyRegex = regexp.MustCompile(`(?i)(passw(or)?d|secret|token|api_?key|private_?key|credential)`)
+
+	// redacted stands for the values of secret options.
+	redacted = json.RawMessage(`"<redacted>"`)
+)
+
+// Difference is an option whose values differ between two configurations.
+type Difference struct {
+	// Path is the dotted JSON path of the option, e.g. database.host.
+	Path string `json:"path"`
+
+	// Before and After are the JSON values of the option in either configuration, nil where it is not set,
+	// and "<redacted>" for secrets.
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Compare returns the options whose values differ between the effective configurations before and after,
+// e.g. the JSON documents of the configurations two instances run with, in the order their fields are
+// declared, so that operators can tell why instances behave differently. Documents are compared by value,
+// regardless of the order of their keys or their formatting. Secret values are redacted: the ones of the
+// fields of the configuration structure conf tagged with `secret:"true"`, if conf is not nil, and the ones
+// whose keys look secret, e.g. password or apiKey.
+func Compare(before, after []byte, conf interface{}) ([]Difference, error) {
+	var (
+		leaves = make([]map[string]interface{}, 2)
+		secret [][]string
+	)
+
+	for i, doc := range [][]byte{before, after} {
+		var val interface{}
+		if err := json.Unmarshal(doc, &val); err != nil {
+			return nil, fmt.Errorf("failed to compare the configurations, invalid JSON document: %v", err)
+		}
+
+		leaves[i] = make(map[string]interface{})
+		flatten("", val, leaves[i])
+	}
+
+	if t := reflect.TypeOf(conf); t != nil {
+		secret = secretFields(t, nil)
+	}
+
+	var paths []string
+
+	for path, val := range leaves[0] {
+		if other, found := leaves[1][path]; !found || !jsonEqual(val, other) {
+			paths = append(paths, path)
+		}
+	}
+
+	for path := range leaves[1] {
+		if _, found := leaves[0][path]; !found {
+			paths = append(paths, path)
+		}
+	}
+
+	sortPaths(paths, leafOrder(before, after))
+
+	diffs := make([]Difference, 0, len(paths))
+
+	for _, path := range paths {
+		diff := Difference{Path: path}
+		hide := isSecret(path, secret)
+
+		for i, raw := range []*json.RawMessage{&diff.Before, &diff.After} {
+			val, found := leaves[i][path]
+			if !found {
+				continue
+			}
+
+			if hide {
+				*raw = redacted
+			} else {
+				*raw, _ = json.Marshal(val)
+			}
+		}
+
+		diffs = append(diffs, diff)
+	}
+
+	return diffs, nil
+}
+
+// secretFields returns the JSON paths, as lists of keys, of the fields of the structure t tagged with
+// `secret:"true"`, located at path.
+func secretFields(t reflect.Type, path []string) [][]string {
+	var fields [][]string
+
+	for _, f := range taggedFields(t, path, "secret", func(value string) bool {
+		secret, _ := strconv.ParseBool(value)
+		return secret
+	}, make(map[reflect.Type]bool)) {
+		fields = append(fields, f.path)
+	}
+
+	return fields
+}
+
+// isSecret reports whether the value of the option of the dotted JSON path is secret, since it belongs to
+// any of the secret fields or any of its keys looks secret.
+func isSecret(path string, secret [][]string) bool {
+	keys := strings.Split(path, ".")
+
+	for _, key := range keys {
+		if secretKeyRegex.MatchString(key) {
+			return true
+		}
+	}
+
+	for _, field := range secret {
+		if len(field) > len(keys) {
+			continue
+		}
+
+		matches := true
+		for i, key := range field {
+			matches = matches && strings.EqualFold(key, keys[i])
+		}
+
+		if matches {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compareSnapshots compares the effective configurations of the files or URIs before and after, e.g. the
+// admin endpoints of two instances, and returns their differences, one per line.
+func compareSnapshots(o *options, before, after string, conf interface{}) (string, error) {
+	docs := make([][]byte, 2)
+
+	for i, src := range []string{before, after} {
+		var err error
+		if strings.Contains(src, "://") {
+			docs[i], _, err = fetchWithRetry(o, src)
+		} else {
+			docs[i], err = os.ReadFile(src)
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("failed to read the configuration [%v] to compare: %v", src, err)
+		}
+	}
+
+	diffs, err := Compare(docs[0], docs[1], conf)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	for _, diff := range diffs {
+		switch {
+		case diff.Before == nil:
+			fmt.Fprintf(&b, "+ %v: %s\n", diff.Path, diff.After)
+		case diff.After == nil:
+			fmt.Fprintf(&b, "- %v: %s\n", diff.Path, diff.Before)
+		default:
+			fmt.Fprintf(&b, "~ %v: %s -> %s\n", diff.Path, diff.Before, diff.After)
+		}
+	}
+
+	if b.Len() == 0 {
+		return "No differences\n", nil
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type compareConf struct {
+	Name     string `json:"name"`
+	Database struct {
+		Host string `json:"host"`
+		DSN  string `json:"dsn" secret:"true"`
+	} `json:"database"`
+	Auth struct {
+		Password string `json:"password"`
+	} `json:"auth"`
+	Replicas int `json:"replicas"`
+}
+
+func TestCompare(t *testing.T) {
+	before := `{"name": "a", "database": {"host": "db1", "dsn": "u:p@db1"}, "auth": {"password": "x"}, "replicas": 2, "removed": true}`
+	after := `{"replicas": 2.0, "database": {"dsn": "u:q@db1", "host": "db2"}, "name": "a", "auth": {"password": "y"}, "added": [1]}`
+
+	raw := func(s string) json.RawMessage {
+		return json.RawMessage(s)
+	}
+
+	expected := []Difference{
+		{"database.host", raw(`"db1"`), raw(`"db2"`)},
+		{"database.dsn", redacted, redacted},
+		{"auth.password", redacted, redacted},
+		{"removed", raw(`true`), nil},
+		{"added", nil, raw(`[1]`)},
+	}
+
+	if diffs, err := Compare([]byte(before), []byte(after), &compareConf{}); err != nil || !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("expected output: (%v, <nil>), but found: (%v, %v)", expected, diffs, err)
+	}
+
+	if diffs, err := Compare([]byte(before), []byte(before), nil); err != nil || len(diffs) != 0 {
+		t.Errorf("expected no differences, but found: (%v, %v)", diffs, err)
+	}
+
+	if _, err := Compare([]byte(before), []byte(`{`), nil); err == nil || !strings.Contains(err.Error(), "invalid JSON document") {
+		t.Errorf("expected invalid documents to fail, but found: %v", err)
+	}
+}
+
+func TestCliCompare(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.json")
+
+	if err := os.WriteFile(file, []byte(`{"name": "a", "database": {"host": "db", "dsn": "s3cr3t"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "b", "database": {"host": "db", "dsn": "other"}, "replicas": 3}`))
+	}))
+	defer srv.Close()
+
+	out, err := Parse("CMP", "", nil, &compareConf{}, WithEnvironment(nil), WithArgs("", "-compare", file, srv.URL+"/config"))
+
+	if expected := "~ name: \"a\" -> \"b\"\n~ database.dsn: \"<redacted>\" -> \"<redacted>\"\n+ replicas: 3\n"; err != nil || out != expected {
+		t.Errorf("expected output: (%q, <nil>), but found: (%q, %v)", expected, out, err)
+	}
+
+	if out, err = Parse("CMP", "", nil, &compareConf{}, WithEnvironment(nil), WithArgs("", "-compare", file, file)); err != nil || out != "No differences\n" {
+		t.Errorf("expected no differences, but found: (%q, %v)", out, err)
+	}
+
+	if _, err = Parse("CMP", "", nil, &compareConf{}, WithEnvironment(nil), WithArgs("", "-compare", file)); err == nil || !strings.Contains(err.Error(), "requires the configurations to compare") {
+		t.Errorf("expected the missing argument to fail, but found: %v", err)
+	}
+
+	if _, err = Parse("CMP", "", nil, &compareConf{}, WithEnvironment(nil), WithArgs("", "-compare", file, filepath.Join(dir, "missing.json"))); err == nil || !strings.Contains(err.Error(), "failed to read the configuration") {
+		t.Errorf("expected the missing file to fail, but found: %v", err)
+	}
+}
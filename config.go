@@ -18,12 +18,17 @@ package config
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // ReleaseInfo a structure containing details about how this binary has been built.
@@ -45,18 +50,11 @@ type ReleaseInfo struct {
 
 var (
 	// envVarPrefixRegex expression must only allow a prefix with the following rules:
-	// 	- All letters must be in uppercase.
+	// 	- All letters must be in uppercase, prefixes are uppercased before they are matched.
 	// 	- Must start with a letter.
 	// 	- Must contain only letters, numbers or underscores.
 	// 	- Must end with a letter or a number.
 	envVarPrefixRegex = regexp.MustCompile("\\A[A-Z][A-Z0-9_]*?[A-Z0-9]\\z")
-
-	// placeHolderRegex expression must only allow a placeholder with the following rules:
-	// 	- All letters must be in uppercase.
-	// 	- Must start with "${" followed by a letter.
-	// 	- Must contain only letters, numbers or underscores.
-	// 	- Must end with a letter or a number followed by a "}".
-	placeHolderRegex = regexp.MustCompile("(?P<PLACEHOLDER>\\$\\{[A-Z][A-Z0-9_]*?[A-Z0-9]\\})")
 )
 
 // EnvWithPrefix returns to functions, the first returns the prefix prepended to the specified string,
@@ -78,9 +76,14 @@ func EnvWithPrefix(prefix string) (getEnvKey func(string) string, getEnv func(st
 	return
 }
 
-// sanitizePlaceholderToken trims the placeholder token of the "${" prefix and the "}" suffix.
-func sanitizePlaceholderToken(envvar string) string {
-	return strings.TrimSuffix(strings.TrimPrefix(envvar, "${"), "}")
+// normalizePrefix makes sure that the format of the environment variable prefix is valid, regardless of
+// its case, and returns it in upper case followed by an underscore, e.g. APP_.
+func normalizePrefix(envVarPrefix string) (string, error) {
+	if matches := envVarPrefixRegex.MatchString(strings.ToUpper(envVarPrefix)); !matches {
+		return "", fmt.Errorf("environment variable prefix [%v] must start with a letter then letters or underscores", envVarPrefix)
+	}
+
+	return strings.Trim(strings.ToUpper(envVarPrefix), "_") + "_", nil
 }
 
 // Parse reads command line arguments and processes them
@@ -88,7 +91,9 @@ func sanitizePlaceholderToken(envvar string) string {
 //
 //		1. Returns usage or help if either -h or --help flag is specified.
 //		2. Returns release information if either -v or --version flag is specified.
-//		3. Parses a JSON string specified by -c or --config flags or define in an environment
+//		3. Returns the names of the environment variables the configuration may be read from,
+//		   one per line, if the --list-env flag is specified.
+//		4. Parses a JSON string specified by -c or --config flags or define in an environment
 //		   variable $<envVarPrefix>_CONFIG where <envVarPrefix> is a string passed as parameter
 //		   envVarPrefix filling the conf object parameter with the parsed configurations
 //		   and then returns an empty string.
@@ -98,25 +103,132 @@ func sanitizePlaceholderToken(envvar string) string {
 // which will be displayed with -v/--version option.
 // And finally the conf parameter must not be nil, it will carry the application configuration
 // parsed from the JSON string passed as an argument along with -c/--config option, or defined
-// as environment variable specified $<envVarPrefix>_CONFIG.
-func Parse(envVarPrefix, description string, info *ReleaseInfo, conf interface{}) (string, error) {
+// as environment variable specified $<envVarPrefix>_CONFIG. The JSON string may contain
+// line or block comments and trailing commas, they are stripped before parsing. Documents too large to be
+// held by environment variables may be read from the file $<envVarPrefix>_CONFIG_FILE points at instead,
+// written in any of the supported formats selected by its extension.
+// The configuration may as well be fetched from the URI specified by --config-uri option,
+// or $<envVarPrefix>_CONFIG_URI environment variable, e.g. gs://<bucket>/<object>, and written
+// in any of the other supported formats selected with the --config-format option, or
+// $<envVarPrefix>_CONFIG_FORMAT environment variable, or else by the extension of the URI.
+// Several URIs separated by spaces may be specified, in which case their documents are merged
+// in order, the values of a document overriding the ones of the documents before it.
+// Sources of other schemes may be added by the application with RegisterSource, and formats with
+// RegisterCodec, whose codecs also detect their documents when the format is not otherwise known.
+// The --convert option translates a document into JSON or a format of RegisterCodec, e.g. --convert in.yaml
+// out.json, checking it against the configuration structure and keeping its placeholders but not its comments,
+// while the --compare option prints the differences between the effective configurations of two instances,
+// see Compare, and the --schema option prints the JSON schema of the configuration, see JSONSchema.
+// Alternative URIs of the same document may be separated by |, e.g. for the config services of
+// several regions, in which case they are failed over in order, preferring the local region.
+// The endpoints of a source may be discovered through DNS SRV records by appending +srv to the
+// scheme of its URI, e.g. https+srv://_config._tcp.internal/api.json, failing over across them.
+// Network based sources honor $HTTPS_PROXY and $NO_PROXY, trust the additional CA certificates
+// of the PEM file specified by $<envVarPrefix>_CA_FILE if any, and skip verifying certificates
+// if $<envVarPrefix>_TLS_SKIP_VERIFY is true, which is only meant for debugging, and connect over
+// the IP family specified by $<envVarPrefix>_IP_FAMILY, one of ipv4, ipv6 or dual which is the default.
+// Fields of the configuration structure tagged with `env:"<name>"` are set to the value of the
+// environment variable $<envVarPrefix>_<name> when defined, overriding the configuration documents,
+// and so is every other field when WithAutoEnv is set, after the variable named after its path.
+// Following the convention of Docker secrets, such fields and placeholders are set to the content of the
+// file $<envVarPrefix>_<name>_FILE points at when $<envVarPrefix>_<name> is undefined. Sections of JSON
+// documents decoded into fields tagged with `envprefix:"<name>"` scope their placeholders, e.g. ${HOST}
+// in the section of `envprefix:"DATABASE"` is the value of $<envVarPrefix>_DATABASE_HOST.
+// During incidents, $<envVarPrefix>_EMERGENCY_CONFIG may hold a break-glass configuration outranking every
+// other source until it expires, e.g. {"expires": "2024-01-02T15:04:05Z", "config": {"replicas": 10}}.
+// Fields tagged with `stability:"alpha"` are experimental, their options are ignored with a warning unless
+// the -enable-alpha-config flag is set or $<envVarPrefix>_ALPHA is true, while the options of fields tagged
+// with `deprecated:"<message>"` are reported with a warning carrying the message, e.g. use server.port instead.
+// Documents of older versions of the schema of the configuration are migrated first, see WithSchemaVersion.
+// Fields holding zero values tagged with `default:"<value>"` are set to their default values before any
+// source is applied, e.g. `default:"8080"` or `default:"1m30s"` for durations, then the configuration, and
+// any value it holds, implementing Defaulter is asked to set its own defaults.
+// Fields tagged with `required:"true"` must not hold zero values once loaded, every missing one is reported,
+// and the ones tagged with constraints must satisfy them, e.g. `min:"1" max:"65535"`, `len:"2"` or
+// `oneof:"debug,info,warn,error"`. The other ones may be reported when still holding zero values, see
+// WithZeroValueWarnings.
+// The configuration, and any value it holds, implementing Validator is then asked to validate itself, and
+// the hooks of WithValidationHook check it as a whole, knowing the source of each of its options.
+// Numeric fields tagged with `spread:"<N>%"`, e.g. polling intervals, are scaled by up to N% either way,
+// deterministically for every instance, to keep a fleet from acting in lockstep, see WithInstanceID.
+// The opts parameters are optional and customize the way the configuration is interpreted.
+func Parse(envVarPrefix, description string, info *ReleaseInfo, conf interface{}, opts ...Option) (_ string, err error) {
 
-	// make sure that the environment variable prefix format is valid.
-	if matches := envVarPrefixRegex.MatchString(envVarPrefix); !matches {
-		return "", fmt.Errorf("environment variable prefix [%v] must start with a letter then letters or underscores", envVarPrefix)
+	if envVarPrefix, err = normalizePrefix(envVarPrefix); err != nil {
+		return "", err
 	}
 
-	envVarPrefix = strings.Trim(strings.ToUpper(envVarPrefix), "_") + "_"
-
 	var (
-		err               error
-		getEnvKey, getEnv = EnvWithPrefix(envVarPrefix)
+		getEnvKey, _      = EnvWithPrefix(envVarPrefix)
 		confRef           []byte
 		output            bytes.Buffer
 		configJSON        string
+		configFormat      string
+		configURI         string
+		errorFormat       string
+		errorPath         string
+		lintMode          string
+		enableAlpha       bool
+		listEnv           bool
 		version           bool
+		diagnostics       bool
+		conversion        bool
+		comparison        bool
+		schemaOnly        bool
+		o                 = newOptions(opts)
 	)
 
+	o.envVarPrefix = envVarPrefix
+
+	for i, prefix := range o.legacyPrefixes {
+		if o.legacyPrefixes[i], err = normalizePrefix(prefix); err != nil {
+			return "", fmt.Errorf("invalid legacy prefix: %v", err)
+		}
+	}
+
+	// environment variables may match regardless of their case when WithCaseInsensitiveEnv is set,
+	// and they may be defined by .env files for local development.
+	getEnv := prefixedEnv(o)
+
+	if err = loadDotEnv(o); err != nil {
+		return "", err
+	}
+
+	// errors are rendered in the requested format once the flags are parsed, last so that every
+	// error is rendered including budget breaches.
+	defer func() {
+		if err != nil && len(errorFormat) > 0 {
+			err = formatError(errorFormat, errorPath, err)
+		}
+	}()
+
+	// the whole load is bounded by the startup budget if any, and its breach is reported
+	// along with the time spent at every stage.
+	if o.budget > 0 {
+		ctx, cancel := context.WithTimeout(o.ctx, o.budget)
+		defer cancel()
+
+		o.ctx = ctx
+		start := time.Now()
+
+		defer func() {
+			if elapsed := time.Since(start); elapsed > o.budget || (err != nil && ctx.Err() != nil) {
+				err = budgetError(o, elapsed, err)
+			}
+		}()
+	}
+
+	if t := reflect.TypeOf(conf); t != nil && t.Kind() == reflect.Ptr {
+		o.confType = t.Elem()
+	}
+
+	// fields tagged with default values are set before any source is applied.
+	if err = applyDefaultTags(conf); err != nil {
+		return "", err
+	}
+
+	callDefaulters(conf)
+
 	// create an indented JSON string example out of the default configuration
 	// to be used as an example in the help/usage output.
 	if confRef, err = json.MarshalIndent(conf, "  ", "  "); err != nil {
@@ -129,18 +241,65 @@ func Parse(envVarPrefix, description string, info *ReleaseInfo, conf interface{}
 
 	fs.StringVar(&configJSON, "config", getEnv("CONFIG", "{}"), fmt.Sprintf("JSON string describing the configuration options, JSON values can be placeholders for environment variables that start with '%v' e.g '${DOMAIN}' is replaced with the value of environment variable '%v', example: %v.", envVarPrefix, getEnvKey("DOMAIN"), string(confRef)))
 
+	fs.StringVar(&configFormat, "config-format", getEnv("CONFIG_FORMAT", ""), fmt.Sprintf("The format of the configuration, one of: %v. Defaults to the extension of the configuration URI if any, otherwise json.", strings.Join(formatNames(), ", ")))
+
+	fs.StringVar(&configURI, "config-uri", getEnv("CONFIG_URI", ""), fmt.Sprintf("URI to fetch the configuration from instead of the JSON string, or several space separated URIs whose documents are merged in order, of the schemes: %v.", strings.Join(schemeNames(), ", ")))
+
+	fs.BoolVar(&comparison, "compare", false, "Compares the effective configurations of the files or URIs of the first and second arguments, e.g. the admin endpoints of two instances, and prints their differences with secrets redacted, then exits.")
+
+	fs.BoolVar(&conversion, "convert", false, "Converts the configuration document of the file of the first argument into the format of the file of the second one, selected by their extensions, keeping its placeholders, then exits.")
+
+	fs.BoolVar(&diagnostics, "diagnostics", false, "Reads a configuration document from the standard input and prints its diagnostics as a JSON array, in the shape of the diagnostics of the Language Server Protocol, then exits.")
+
+	fs.BoolVar(&enableAlpha, "enable-alpha-config", false, fmt.Sprintf("Enables the experimental options, which are ignored otherwise, as does setting '%v' to true.", getEnvKey("ALPHA")))
+
+	fs.StringVar(&errorFormat, "errors", getEnv("ERRORS", "text"), fmt.Sprintf("The format of the errors, one of: %v. The github format renders them as GitHub Actions annotations, and the sarif format as a SARIF log.", strings.Join(errorFormatNames(), ", ")))
+
+	fs.StringVar(&lintMode, "lint", getEnv("LINT", ""), fmt.Sprintf("Checks the configuration documents for duplicate keys, empty values of keys that look required and invalid URLs, reporting the findings as warnings or failing on them, one of: %v.", strings.Join(lintModes, ", ")))
+
+	fs.BoolVar(&listEnv, "list-env", false, "Lists the environment variables the configuration may be read from, one per line, then exits.")
+
+	fs.BoolVar(&schemaOnly, "schema", false, "Prints the JSON schema of the configuration, derived from its fields and their tags, to check the configuration documents against, then exits.")
+
 	fs.BoolVar(&version, "version", false, "Prints the version and exits")
 
+	args := os.Args
+	if o.args != nil {
+		args = o.args
+	}
+
+	if len(args) == 0 {
+		args = []string{""}
+	}
+
 	// start parsing command line arguments, given the parser rules and command line input.
-	if err = fs.Parse(os.Args[1:]); err == flag.ErrHelp {
+	if err = fs.Parse(args[1:]); err == flag.ErrHelp {
 		if len(description) == 0 {
 			description = "No description available."
 		}
-		return fmt.Sprintf("%v - %v\n\n%v", os.Args[0], description, output.String()), nil
+		return fmt.Sprintf("%v - %v\n\n%v", args[0], description, output.String()), nil
 	} else if err != nil {
 		return output.String(), err
 	}
 
+	if _, found := errorFormats[strings.ToLower(errorFormat)]; !found {
+		format := errorFormat
+		errorFormat = ""
+		return "", fmt.Errorf("unsupported error format [%v], supported formats are: %v", format, strings.Join(errorFormatNames(), ", "))
+	}
+
+	if val := getEnv("ALPHA", ""); len(val) > 0 && !enableAlpha {
+		if enableAlpha, err = strconv.ParseBool(val); err != nil {
+			return "", fmt.Errorf("invalid value [%v] of $%v, a boolean is expected", val, getEnvKey("ALPHA"))
+		}
+	}
+
+	o.alpha = enableAlpha
+
+	if o.lint = strings.ToLower(lintMode); len(o.lint) > 0 && o.lint != "error" && o.lint != "warn" {
+		return "", fmt.Errorf("unsupported lint mode [%v], supported modes are: %v", lintMode, strings.Join(lintModes, ", "))
+	}
+
 	// check on parsed options, if any of the conditions below evaluates to true, then a non-empty string
 	// will be returned and the caller of this fuction and the caller should probably output this string
 	// to the stdout then exits.
@@ -156,20 +315,245 @@ func Parse(envVarPrefix, description string, info *ReleaseInfo, conf interface{}
 			info.GoVersion), nil
 	}
 
-	// if this point is reached, it means that user has requested none of the above.
-	// so the application is meant to be run and the configuration JSON string must be parsed.
-	// the JSON string may contain placeholders e.g. ${PASSWORD} which translates
-	// into "I want to inject the value of the environment variable APP_PREFIX_PASSWORD here"
-	// so here all the placeholders are being replaced by their real values.
-	configJSON = placeHolderRegex.ReplaceAllStringFunc(configJSON, func(group string) string {
-		// here the placeholder is prefixed with the environment variable prefix to obtain the key,
-		// and then the value is being read from os.Getenv by the key.
-		return getEnv(sanitizePlaceholderToken(group), "")
+	// operators compare the configurations of instances behaving differently.
+	if comparison {
+		if fs.NArg() != 2 {
+			return "", errors.New("the -compare flag requires the configurations to compare as arguments")
+		}
+
+		return compareSnapshots(o, fs.Arg(0), fs.Arg(1), conf)
+	}
+
+	// deployments migrating from a format to another convert their documents through the application.
+	if conversion {
+		if fs.NArg() != 2 {
+			return "", errors.New("the -convert flag requires the files to convert from and into as arguments")
+		}
+
+		return convert(o, fs.Arg(0), fs.Arg(1))
+	}
+
+	// editors lint configuration documents through the application, and print the diagnostics.
+	if diagnostics {
+		return diagnose(o, configFormat, getEnv)
+	}
+
+	// editors and pipelines check the configuration documents against the schema of the application.
+	if schemaOnly {
+		if conf == nil {
+			return "", errors.New("the -schema flag requires a configuration structure")
+		}
+
+		data, err := JSONSchema(conf)
+
+		return string(data), err
+	}
+
+	// network based sources and resolvers may trust an additional CA bundle, skip verifying
+	// certificates altogether while debugging, or prefer an IP family.
+	skipVerify := false
+	if val := getEnv("TLS_SKIP_VERIFY", ""); len(val) > 0 {
+		if skipVerify, err = strconv.ParseBool(val); err != nil {
+			return "", fmt.Errorf("invalid value [%v] of $%v, a boolean is expected", val, getEnvKey("TLS_SKIP_VERIFY"))
+		}
+	}
+
+	if err = configureNetwork(o, getEnv("CA_FILE", ""), skipVerify, getEnv("IP_FAMILY", "")); err != nil {
+		return "", err
+	}
+
+	uris := strings.Fields(configURI)
+	errorPath = errorFile(uris)
+
+	// large documents may be read from the file of $<envVarPrefix>_CONFIG_FILE rather than $<envVarPrefix>_CONFIG,
+	// unless the document of the command line takes precedence.
+	configFlag := false
+	fs.Visit(func(f *flag.Flag) {
+		configFlag = configFlag || f.Name == "config"
 	})
 
+	if !configFlag && len(uris) == 0 && len(o.precedence) == 0 {
+		doc, file, found, err := configFile(o)
+		if err != nil {
+			return "", err
+		}
+
+		if found {
+			configJSON, errorPath = doc, file
+			if len(configFormat) == 0 {
+				configFormat = formatOf(file)
+			}
+		}
+	}
+
+	if o.spiffe && len(uris) > 0 {
+		client, closer, err := spiffeClient(o.ctx, o, o.spiffeIDs)
+		if err != nil {
+			return "", err
+		}
+		defer closer.Close()
+
+		o.spiffeClient = client
+	}
+
+	// operators audit the environment variables the configuration may be read from.
+	if listEnv || o.envVars != nil {
+		docs := []string{configJSON, envValue(o, "CONFIG")}
+
+		if len(uris) > 0 {
+			fetched, _, err := load(o, uris, configFormat)
+			if err != nil {
+				return "", err
+			}
+
+			docs = append(docs, string(fetched))
+		}
+
+		names := envVarNames(o, docs...)
+
+		if o.envVars != nil {
+			*o.envVars = names
+			return "", nil
+		}
+
+		return strings.Join(names, "\n") + "\n", nil
+	}
+
+	var doc []byte
+
+	if len(o.precedence) > 0 {
+		// the configuration is merged out of the layers of the precedence chain, where the
+		// JSON string of the command line is only a layer if it is explicitly specified.
+		var flagConfig *string
+		if configFlag {
+			flagConfig = &configJSON
+		}
+
+		if doc, err = loadChain(o, getEnv, flagConfig, uris, configFormat); err != nil {
+			return "", err
+		}
+	} else {
+		// if this point is reached, it means that user has requested none of the above.
+		// so the application is meant to be run and the configuration JSON string must be parsed,
+		// unless a URI is specified, in which case the configuration is fetched from there instead.
+		if len(uris) > 0 {
+			fetched, format, err := load(o, uris, configFormat)
+			if err != nil {
+				return "", err
+			}
+
+			configJSON, configFormat = string(fetched), format
+		}
+
+		// configurations written in other formats are translated into JSON, and then merged over
+		// the embedded default configuration.
+		if doc, err = interpret(o, configFormat, configJSON, getEnv); err != nil {
+			return "", err
+		}
+
+		if o.defaultFS != nil {
+			if doc, err = mergeEmbeddedDefault(o, doc, getEnv); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// references to other keys are resolved once the configuration documents are merged.
+	done := track(o, "resolve")
+	doc, err = resolveRefs(doc)
+	done()
+
+	if err != nil {
+		return "", err
+	}
+
+	if err = reportLint(o); err != nil {
+		return "", err
+	}
+
+	// documents of older versions of the schema are upgraded before being checked.
+	if doc, err = migrate(o, doc); err != nil {
+		return "", err
+	}
+
+	// CUE documents are already checked against the CUE schema while being evaluated.
+	if len(o.cueSchema) > 0 && (len(o.precedence) > 0 || !strings.EqualFold(configFormat, "cue")) {
+		done := track(o, "validate")
+		doc, err = evalCUE(doc, o.cueSchema)
+		done()
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// documents pushed by deploy tooling may be checked against a JSON schema.
+	if err = validateJSONSchema(o, doc); err != nil {
+		return "", err
+	}
+
 	if conf != nil {
-		// now the JSON string is ready, it needs to be parsed into the supplied configuration structure.
-		if err = json.Unmarshal([]byte(strings.TrimSpace(configJSON)), conf); err != nil {
+		var sources *provenanceTracker
+
+		done := track(o, "decode")
+		doc, sources, err = decodeConf(o, confRef, doc, conf)
+		done()
+
+		if err != nil {
+			return "", err
+		}
+
+		// fields tagged with the name of an environment variable are overridden by its value.
+		if err = bindEnv(o, conf); err != nil {
+			return "", err
+		}
+
+		if err = sources.record(OriginEnv, conf); err != nil {
+			return "", err
+		}
+
+		// fleet-wide values are spread across instances.
+		if err = applySpread(o, conf); err != nil {
+			return "", err
+		}
+
+		if err = sources.record(OriginSpread, conf); err != nil {
+			return "", err
+		}
+
+		// the break-glass configuration outranks every other source.
+		if err = applyEmergency(o, getEnv, conf); err != nil {
+			return "", err
+		}
+
+		if err = sources.record(OriginEmergency, conf); err != nil {
+			return "", err
+		}
+
+		done = track(o, "validate")
+		err = checkConf(o, sources, conf)
+		done()
+
+		if err != nil {
+			return "", err
+		}
+
+		if o.usageReport != nil {
+			if err = writeUsageReport(o, info, confRef, doc, conf); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if len(o.receiptSink) > 0 && o.receiptKey != nil {
+		// the receipt is of the effective configuration, including the values overridden by the environment.
+		if conf != nil {
+			if doc, err = json.Marshal(conf); err != nil {
+				return "", err
+			}
+		}
+
+		if err = emitReceipt(o, info, uris, doc); err != nil {
 			return "", err
 		}
 	}
@@ -178,3 +562,71 @@ func Parse(envVarPrefix, description string, info *ReleaseInfo, conf interface{}
 	// to run with the configuration structure filled.
 	return output.String(), nil
 }
+
+// decodeConf decodes the JSON document doc into the configuration structure conf, once checked for the
+// deprecated, alpha and unknown options, and returns the document without the options of alpha fields unless
+// enabled, along with the tracker of the sources of its options, defaults being conf encoded as JSON before
+// it is decoded.
+func decodeConf(o *options, defaults, doc []byte, conf interface{}) ([]byte, *provenanceTracker, error) {
+	// deployments are told about the options to migrate from.
+	if err := checkDeprecated(o, doc); err != nil {
+		return nil, nil, err
+	}
+
+	// options of alpha fields are ignored unless enabled.
+	doc, err := gateAlpha(o, doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// unknown options are most likely typos, unless written for newer versions.
+	if err = reportUnknown(o, doc); err != nil {
+		return nil, nil, err
+	}
+
+	// validation hooks are told which source each option comes from.
+	sources, err := newProvenanceTracker(o, defaults, doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// now the JSON string is ready, it needs to be parsed into the supplied configuration structure.
+	if err = json.Unmarshal(doc, conf); err != nil {
+		return nil, nil, err
+	}
+
+	// the elements of slices are replaced when decoded, so they get their defaults once decoded.
+	if err = applyElementDefaults(conf); err != nil {
+		return nil, nil, err
+	}
+
+	return doc, sources, sources.record(OriginDocument, conf)
+}
+
+// checkConf checks the loaded configuration structure conf, reporting every failure of a check at once,
+// and calls the validation hooks with the sources of its options recorded by sources.
+func checkConf(o *options, sources *provenanceTracker, conf interface{}) error {
+	// options the application cannot do without are reported at once.
+	if err := checkRequired(conf); err != nil {
+		return err
+	}
+
+	if err := checkConstraints(conf); err != nil {
+		return err
+	}
+
+	if err := warnZeroValues(o, conf); err != nil {
+		return err
+	}
+
+	if err := validateTags(o, conf); err != nil {
+		return err
+	}
+
+	// cross-field checks are left to the configuration itself.
+	if err := callValidators(conf); err != nil {
+		return err
+	}
+
+	return callValidationHooks(o, sources, conf)
+}
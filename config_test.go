@@ -45,6 +45,21 @@ type testConf struct {
 	Online bool   `json:"online"`
 }
 
+// usage is the expected usage output of Parse given the prefix TEST and an empty configuration.
+const usage = "Usage:\n" +
+	"  -compare\n    \tCompares the effective configurations of the files or URIs of the first and second arguments, e.g. the admin endpoints of two instances, and prints their differences with secrets redacted, then exits.\n" +
+	"  -config string\n    \tJSON string describing the configuration options, JSON values can be placeholders for environment variables that start with 'TEST_' e.g '${DOMAIN}' is replaced with the value of environment variable 'TEST_DOMAIN', example: {}. (default \"{}\")\n" +
+	"  -config-format string\n    \tThe format of the configuration, one of: cue, dhall, json, jsonnet, star. Defaults to the extension of the configuration URI if any, otherwise json.\n" +
+	"  -config-uri string\n    \tURI to fetch the configuration from instead of the JSON string, or several space separated URIs whose documents are merged in order, of the schemes: azappconfig, azkv, configmap, file, git, gs, http, https, nats, oci, redis, rediss, secret, spring, sql, txt, zk.\n" +
+	"  -convert\n    \tConverts the configuration document of the file of the first argument into the format of the file of the second one, selected by their extensions, keeping its placeholders, then exits.\n" +
+	"  -diagnostics\n    \tReads a configuration document from the standard input and prints its diagnostics as a JSON array, in the shape of the diagnostics of the Language Server Protocol, then exits.\n" +
+	"  -enable-alpha-config\n    \tEnables the experimental options, which are ignored otherwise, as does setting 'TEST_ALPHA' to true.\n" +
+	"  -errors string\n    \tThe format of the errors, one of: github, json, sarif, text. The github format renders them as GitHub Actions annotations, and the sarif format as a SARIF log. (default \"text\")\n" +
+	"  -lint string\n    \tChecks the configuration documents for duplicate keys, empty values of keys that look required and invalid URLs, reporting the findings as warnings or failing on them, one of: error, warn.\n" +
+	"  -list-env\n    \tLists the environment variables the configuration may be read from, one per line, then exits.\n" +
+	"  -schema\n    \tPrints the JSON schema of the configuration, derived from its fields and their tags, to check the configuration documents against, then exits.\n" +
+	"  -version\n    \tPrints the version and exits\n"
+
 var (
 	info = &ReleaseInfo{
 		BuildTimestamp: time.Now().String(),
@@ -80,11 +95,11 @@ func TestCli(t *testing.T) {
 		{&input{prefix: "TEST",
 			conf: conf,
 			args: []string{"", "-usage"},
-		}, &output{"flag provided but not defined: -usage\nUsage:\n  -config string\n    \tJSON string describing the configuration options, JSON values can be placeholders for environment variables that start with 'TEST_' e.g '${DOMAIN}' is replaced with the value of environment variable 'TEST_DOMAIN', example: {}. (default \"{}\")\n  -version\n    \tPrints the version and exits\n", errors.New("flag provided but not defined: -usage")}},
+		}, &output{"flag provided but not defined: -usage\n" + usage, errors.New("flag provided but not defined: -usage")}},
 		{&input{prefix: "TEST",
 			conf: conf,
 			args: []string{"rego", "-help"},
-		}, &output{"rego - No description available.\n\nUsage:\n  -config string\n    \tJSON string describing the configuration options, JSON values can be placeholders for environment variables that start with 'TEST_' e.g '${DOMAIN}' is replaced with the value of environment variable 'TEST_DOMAIN', example: {}. (default \"{}\")\n  -version\n    \tPrints the version and exits\n", nil}},
+		}, &output{"rego - No description available.\n\n" + usage, nil}},
 		{&input{prefix: "TEST",
 			conf: &conf,
 			args: []string{"", "-version"},
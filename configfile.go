@@ -0,0 +1,42 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// configFile returns the document of the file $<envVarPrefix>_CONFIG_FILE points at, an alternative to
+// $<envVarPrefix>_CONFIG for documents too large to be held by environment variables, along with its path
+// and whether it is defined. Defining both variables is an error since either would be ignored.
+func configFile(o *options) (doc, file string, found bool, err error) {
+	if file = envValue(o, "CONFIG_FILE"); len(file) == 0 {
+		return "", "", false, nil
+	}
+
+	if _, defined := lookupPrefixedEnv(o, "CONFIG"); defined {
+		return "", file, true, fmt.Errorf("$%vCONFIG and $%vCONFIG_FILE must not be both defined", o.envVarPrefix, o.envVarPrefix)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", file, true, fmt.Errorf("failed to read the configuration file [%v] of $%vCONFIG_FILE: %v", file, o.envVarPrefix, err)
+	}
+
+	return string(data), file, true, nil
+}
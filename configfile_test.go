@@ -0,0 +1,74 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type configFileConf struct {
+	Name string `json:"name"`
+	Port int    `json:"port"`
+}
+
+func TestCliConfigFile(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"conf.json": `{"name": "${NAME}", "port": 80}`,
+		"conf.star": `config = {"name": "star", "port": 81}`,
+	}
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		env      map[string]string
+		args     []string
+		opts     []Option
+		expected configFileConf
+		err      string
+	}{
+		{map[string]string{"CF_CONFIG_FILE": filepath.Join(dir, "conf.json"), "CF_NAME": "json"}, nil, nil, configFileConf{"json", 80}, ""},
+		{map[string]string{"CF_CONFIG_FILE": filepath.Join(dir, "conf.star")}, nil, []Option{WithStarlark()}, configFileConf{"star", 81}, ""},
+		{map[string]string{"CF_CONFIG_FILE": filepath.Join(dir, "conf.star")}, []string{"-config", `{"name": "flag"}`}, nil, configFileConf{"flag", 0}, ""},
+		{map[string]string{"CF_CONFIG_FILE": filepath.Join(dir, "conf.star")}, nil, []Option{WithStarlark(), WithPrecedence(LayerDefaults, LayerEnv)}, configFileConf{"star", 81}, ""},
+		{map[string]string{"CF_CONFIG_FILE": filepath.Join(dir, "conf.json"), "CF_CONFIG": `{}`}, nil, nil, configFileConf{}, "$CF_CONFIG and $CF_CONFIG_FILE must not be both defined"},
+		{map[string]string{"CF_CONFIG_FILE": filepath.Join(dir, "missing.json")}, nil, nil, configFileConf{}, "failed to read the configuration file"},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(strings.Join(c.args, " "), func(t *testing.T) {
+			t.Parallel()
+
+			conf := &configFileConf{}
+
+			_, err := Parse("CF", "", nil, conf, append(c.opts, WithEnvironment(c.env), WithArgs(append([]string{""}, c.args...)...))...)
+
+			if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+				t.Errorf("expected output: (%v, %v), but found: (%v, %v)", c.expected, c.err, *conf, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package configtest provides helpers isolating the tests of applications parsing their configuration
+// with the config package, so that they may run in parallel without sharing environment variables.
+package configtest
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/adzr/config"
+)
+
+var (
+	// prefixes counts the prefixes generated so far, keeping them unique even for tests of the same name.
+	prefixes uint64
+
+	// invalidRegex matches the characters that may not be part of an environment variable prefix.
+	invalidRegex = regexp.MustCompile("[^A-Z0-9]+")
+)
+
+// Prefix returns an environment variable prefix unique to the test t and to this call, made of the
+// name of the test, e.g. TESTPARSE_VALID_3 for the subtest TestParse/valid.
+func Prefix(t testing.TB) string {
+	name := strings.Trim(invalidRegex.ReplaceAllString(strings.ToUpper(t.Name()), "_"), "_")
+	if len(name) == 0 || name[0] < 'A' || name[0] > 'Z' {
+		name = "TEST_" + name
+	}
+
+	return strings.TrimSuffix(name, "_") + "_" + strconv.FormatUint(atomic.AddUint64(&prefixes, 1), 10)
+}
+
+// Env is a sandbox of environment variables scoped to a test, which are seen by config.Parse instead
+// of the environment of the process when it is given the options of the sandbox.
+type Env struct {
+	mu     sync.Mutex
+	prefix string
+	vars   map[string]string
+}
+
+// NewEnv returns an empty sandbox of environment variables for the test t, under a prefix unique to it.
+func NewEnv(t testing.TB) *Env {
+	return &Env{prefix: Prefix(t), vars: make(map[string]string)}
+}
+
+// Prefix returns the environment variable prefix of the sandbox, to be passed to config.Parse.
+func (e *Env) Prefix() string {
+	return e.prefix
+}
+
+// Set sets the environment variable named after the prefix of the sandbox followed by key,
+// e.g. Set("CONFIG", `{"port": 8080}`).
+func (e *Env) Set(key, val string) {
+	e.SetRaw(e.prefix+"_"+key, val)
+}
+
+// SetRaw sets the environment variable of the specified name regardless of the prefix of the sandbox,
+// e.g. for placeholders of the form ${env:HOSTNAME}.
+func (e *Env) SetRaw(name, val string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.vars[name] = val
+}
+
+// Options returns the options making config.Parse look the environment variables up in the sandbox,
+// and read the command line arguments args, which do not include the name of the program, instead
+// of os.Args.
+func (e *Env) Options(args ...string) []config.Option {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return []config.Option{
+		config.WithEnvironment(e.vars),
+		config.WithArgs(append([]string{e.prefix}, args...)...),
+	}
+}
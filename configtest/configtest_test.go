@@ -0,0 +1,88 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configtest_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/adzr/config"
+	"github.com/adzr/config/configtest"
+)
+
+type testConf struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestPrefix(t *testing.T) {
+	prefixes := make(map[string]bool)
+
+	for i := 0; i < 3; i++ {
+		prefix := configtest.Prefix(t)
+		if prefixes[prefix] {
+			t.Errorf("expected unique prefixes, but found: %v twice", prefix)
+		}
+		prefixes[prefix] = true
+
+		if _, err := config.Parse(prefix, "", nil, &testConf{}, config.WithEnvironment(nil), config.WithArgs("")); err != nil {
+			t.Errorf("expected valid prefix, but found: %v for %v", err, prefix)
+		}
+	}
+
+	t.Run("1st/sub test", func(t *testing.T) {
+		if _, err := config.Parse(configtest.Prefix(t), "", nil, &testConf{}, config.WithEnvironment(nil), config.WithArgs("")); err != nil {
+			t.Errorf("expected valid prefix, but found: %v", err)
+		}
+	})
+}
+
+func TestEnv(t *testing.T) {
+	os.Setenv("CONFIGTEST_LEAKED", "leaked")
+	defer os.Unsetenv("CONFIGTEST_LEAKED")
+
+	for i := 0; i < 8; i++ {
+		i := i
+
+		t.Run(fmt.Sprintf("parallel %v", i), func(t *testing.T) {
+			t.Parallel()
+
+			env := configtest.NewEnv(t)
+			env.Set("CONFIG", `{"id": ${ID}, "name": "${NAME}-${env:CONFIGTEST_HOST:-none}${env:CONFIGTEST_LEAKED:-}"}`)
+			env.Set("ID", fmt.Sprint(i))
+			env.Set("NAME", fmt.Sprintf("name%v", i))
+			env.SetRaw("CONFIGTEST_HOST", "host")
+
+			conf := &testConf{}
+
+			if _, err := config.Parse(env.Prefix(), "", nil, conf, env.Options()...); err != nil {
+				t.Fatal(err)
+			}
+
+			if expected := (testConf{ID: i, Name: fmt.Sprintf("name%v-host", i)}); *conf != expected {
+				t.Errorf("expected configuration: %+v, but found: %+v", expected, *conf)
+			}
+
+			conf = &testConf{}
+
+			if _, err := config.Parse(env.Prefix(), "", nil, conf, env.Options("-config", `{"id": 42}`)...); err != nil || conf.ID != 42 {
+				t.Errorf("expected configuration of the arguments, but found: (%+v, %v)", *conf, err)
+			}
+		})
+	}
+}
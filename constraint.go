@@ -0,0 +1,171 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// checkConstraints fails listing every value of the configuration structure conf violating the constraints
+// of the tags of its field, along with the dotted JSON path of the field, in the order they are declared:
+//
+//	min:"<bound>"  numbers, including durations e.g. min:"1s", must not be less than bound, and the lengths
+//	               of strings, slices and maps must not be less than it.
+//	max:"<bound>"  the same, the other way round.
+//	len:"<length>" the lengths of strings, slices and maps must be exactly length.
+//	oneof:"a,b,c"  strings must be one of the comma separated values, e.g. oneof:"debug,info,warn,error",
+//	               unless empty, use `required:"true"` to require them.
+//
+// Lengths of strings are counted in characters. The fields of nested structures that are not set, i.e. nil
+// pointers, are not checked.
+func checkConstraints(conf interface{}) error {
+	v := reflect.ValueOf(conf)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	var violations []string
+
+	if err := constrainStruct(v.Elem(), "", &violations); err != nil {
+		return err
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("invalid configuration: %v", strings.Join(violations, ", "))
+	}
+
+	return nil
+}
+
+// constrainStruct adds the violations of the constraints of the fields of the structure v, located at path,
+// to violations. It fails on invalid constraints.
+func constrainStruct(v reflect.Value, path string, violations *[]string) error {
+	return walkFields(v, path, nil, func(field reflect.Value, f reflect.StructField, path string) (bool, error) {
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				return false, nil
+			}
+			field = field.Elem()
+		}
+
+		return true, constrainField(field, f.Tag, path, violations)
+	})
+}
+
+// constrainField adds the violations of the constraints of tag by v, the value of the field located at path,
+// to violations.
+func constrainField(v reflect.Value, tag reflect.StructTag, path string, violations *[]string) error {
+	if values, found := tag.Lookup("oneof"); found {
+		if v.Kind() != reflect.String {
+			return fmt.Errorf("invalid constraint [oneof] of field [%v], its type [%v] is not a string", path, v.Type())
+		}
+
+		allowed := strings.Split(values, ",")
+		for i := range allowed {
+			allowed[i] = strings.TrimSpace(allowed[i])
+		}
+
+		if v.Len() > 0 && !slices.Contains(allowed, v.String()) {
+			*violations = append(*violations, fmt.Sprintf("[%v] %q is not one of %v", path, v.String(), strings.Join(allowed, ", ")))
+		}
+	}
+
+	for _, constraint := range []string{"min", "max", "len"} {
+		bound, found := tag.Lookup(constraint)
+		if !found {
+			continue
+		}
+
+		var violation string
+
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			if constraint == "len" {
+				return fmt.Errorf("invalid constraint [len] of field [%v], numbers have no length", path)
+			}
+
+			// bounds are parsed the way the values of environment variables are, e.g. 1m30s for durations.
+			b := reflect.New(v.Type()).Elem()
+			if err := setFromString(b, bound); err != nil {
+				return fmt.Errorf("invalid constraint [%v] of field [%v]: %v", constraint, path, err)
+			}
+
+			if c := compareNumbers(v, b); constraint == "min" && c < 0 {
+				violation = fmt.Sprintf("%v is less than the minimum %v", v.Interface(), b.Interface())
+			} else if constraint == "max" && c > 0 {
+				violation = fmt.Sprintf("%v is greater than the maximum %v", v.Interface(), b.Interface())
+			}
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+			n, err := strconv.Atoi(bound)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid constraint [%v] of field [%v], a length is expected", constraint, path)
+			}
+
+			length, value := v.Len(), fmt.Sprint(v.Interface())
+			if v.Kind() == reflect.String {
+				length, value = utf8.RuneCountInString(v.String()), strconv.Quote(v.String())
+			}
+
+			switch {
+			case constraint == "min" && length < n:
+				violation = fmt.Sprintf("%v is shorter than the minimum length %v", value, n)
+			case constraint == "max" && length > n:
+				violation = fmt.Sprintf("%v is longer than the maximum length %v", value, n)
+			case constraint == "len" && length != n:
+				violation = fmt.Sprintf("%v is not of length %v", value, n)
+			}
+		default:
+			return fmt.Errorf("invalid constraint [%v] of field [%v], its type [%v] cannot be constrained", constraint, path, v.Type())
+		}
+
+		if len(violation) > 0 {
+			*violations = append(*violations, fmt.Sprintf("[%v] %v", path, violation))
+		}
+	}
+
+	return nil
+}
+
+// compareNumbers returns -1, 0 or 1 when the number a is less than, equal to or greater than the number b
+// of the same type.
+func compareNumbers(a, b reflect.Value) int {
+	var less, greater bool
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		less, greater = a.Int() < b.Int(), a.Int() > b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		less, greater = a.Uint() < b.Uint(), a.Uint() > b.Uint()
+	default:
+		less, greater = a.Float() < b.Float(), a.Float() > b.Float()
+	}
+
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type constrainedConf struct {
+	Port    int           `json:"port" min:"1" max:"65535"`
+	Timeout time.Duration `json:"timeout" min:"1s"`
+	Ratio   *float64      `json:"ratio" max:"1"`
+	Name    string        `json:"name" min:"1" max:"8"`
+	Code    string        `json:"code" len:"2"`
+	Level   string        `json:"level" oneof:"debug, info,warn,error"`
+	Tags    []string      `json:"tags" max:"2"`
+	Servers []struct {
+		Weight uint `json:"weight" max:"10"`
+	} `json:"servers"`
+}
+
+func TestCliConstraints(t *testing.T) {
+	cases := []struct {
+		config string
+		err    string
+	}{
+		{`{"port": 80, "timeout": 5000000000, "name": "app", "code": "eu", "servers": [{"weight": 10}]}`, ""},
+		{`{"port": 0, "timeout": 5000000000, "name": "app", "code": "eu"}`, `invalid configuration: [port] 0 is less than the minimum 1`},
+		{`{"port": 70000, "timeout": 10000000, "ratio": 1.5, "name": "application", "code": "é", "tags": ["a", "b", "c"], "servers": [{}, {"weight": 11}]}`,
+			`invalid configuration: [port] 70000 is greater than the maximum 65535, [timeout] 10ms is less than the minimum 1s, ` +
+				`[ratio] 1.5 is greater than the maximum 1, [name] "application" is longer than the maximum length 8, ` +
+				`[code] "é" is not of length 2, [tags] [a b c] is longer than the maximum length 2, [servers[1].weight] 11 is greater than the maximum 10`},
+		{`{"port": 80, "timeout": 5000000000, "name": "app", "code": "eu", "level": "info"}`, ""},
+		{`{"port": 80, "timeout": 5000000000, "name": "app", "code": "eu", "level": "trace"}`, `invalid configuration: [level] "trace" is not one of debug, info, warn, error`},
+		{`{"port": 80, "timeout": 5000000000, "name": "", "code": "eu"}`, `invalid configuration: [name] "" is shorter than the minimum length 1`},
+	}
+
+	for _, c := range cases {
+		_, err := Parse("CONSTRAINT", "", nil, &constrainedConf{}, WithEnvironment(nil), WithArgs("", "-config", c.config))
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || err.Error() != c.err)) {
+			t.Errorf("expected error: %v, but found: %v", c.err, err)
+		}
+	}
+
+	invalid := []struct {
+		conf interface{}
+		err  string
+	}{
+		{&struct {
+			Port int `json:"port" min:"one"`
+		}{}, "invalid constraint [min] of field [port]"},
+		{&struct {
+			Port int `json:"port" len:"1"`
+		}{}, "invalid constraint [len] of field [port], numbers have no length"},
+		{&struct {
+			Name string `json:"name" max:"-1"`
+		}{}, "invalid constraint [max] of field [name], a length is expected"},
+		{&struct {
+			Debug bool `json:"debug" min:"1"`
+		}{}, "invalid constraint [min] of field [debug], its type [bool] cannot be constrained"},
+		{&struct {
+			Level int `json:"level" oneof:"1,2"`
+		}{}, "invalid constraint [oneof] of field [level], its type [int] is not a string"},
+	}
+
+	for _, c := range invalid {
+		_, err := Parse("CONSTRAINT", "", nil, c.conf, WithEnvironment(nil), WithArgs("", "-config", `{}`))
+
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error: %v, but found: %v", c.err, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// convert translates the configuration document of the file in into the format of the file out, selected by
+// their extensions, and writes it there, so that deployments can migrate from a format to another. The
+// document must decode into the configuration structure, which is its schema, without options unknown to it
+// unless WithForwardCompatibility is set, and its placeholders are kept as written, never resolved, so that
+// no secret is ever written. Documents are read in any supported format but only written in JSON or the
+// formats registered by RegisterCodec, and the conversion is not lossless: documents are translated through
+// JSON, so the comments of JSONC, YAML or TOML documents are dropped, as well as the anchors of YAML ones.
+func convert(o *options, in, out string) (string, error) {
+	outFormat := formatOf(out)
+	codec, registered := codecOf(outFormat)
+
+	if !strings.EqualFold(outFormat, "json") && !registered {
+		names := append([]string{"json"}, codecNames()...)
+		return "", fmt.Errorf("unsupported format of [%v] to convert into, supported formats are: %v", out, strings.Join(names, ", "))
+	}
+
+	data, err := os.ReadFile(in)
+	if err != nil {
+		return "", fmt.Errorf("failed to read [%v] to convert: %v", in, err)
+	}
+
+	inFormat := formatOf(in)
+	if len(inFormat) == 0 {
+		inFormat = detectFormat(data)
+	}
+
+	doc, err := toJSON(o, inFormat, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to read [%v] to convert: %v", in, err)
+	}
+
+	// the document must be of the configuration structure, options unknown to it are most likely typos,
+	// unless they are of newer versions of the application, which are kept.
+	if o.confType != nil {
+		if err = reportUnknown(o, doc); err != nil {
+			return "", fmt.Errorf("failed to convert [%v]: %v", in, err)
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(doc))
+		if !o.forwardCompatible {
+			dec.DisallowUnknownFields()
+		}
+
+		if err = dec.Decode(reflect.New(o.confType).Interface()); err != nil {
+			return "", fmt.Errorf("failed to convert [%v], it does not match the configuration structure: %v", in, err)
+		}
+	}
+
+	if registered {
+		data, err = codec.Marshal(doc)
+	} else {
+		var b bytes.Buffer
+		err = json.Indent(&b, doc, "", "  ")
+		data = append(b.Bytes(), '\n')
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to convert [%v] into [%v]: %v", in, out, err)
+	}
+
+	if err = os.WriteFile(out, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write [%v]: %v", out, err)
+	}
+
+	return fmt.Sprintf("Converted %v into %v\n", in, out), nil
+}
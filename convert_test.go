@@ -0,0 +1,87 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCliConvert(t *testing.T) {
+	RegisterCodec("kv", kvCodec{})
+	t.Cleanup(func() {
+		codecs.Lock()
+		delete(codecs.byName, "kv")
+		codecs.Unlock()
+	})
+
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"conf.json":    "{\n  // the name\n  \"name\": \"${NAME}\", \"host\": \"h\",\n}",
+		"conf.star":    `config = {"name": "star"}`,
+		"conf.kv":      "name = kv\nhost = ${HOST}\n",
+		"unknown.json": `{"nmae": "typo"}`,
+	}
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		in, out  string
+		opts     []Option
+		expected string
+		err      string
+	}{
+		{"conf.json", "out.kv", nil, "host=h\nname=${NAME}\n", ""},
+		{"conf.kv", "out.json", nil, "{\n  \"host\": \"${HOST}\",\n  \"name\": \"kv\"\n}\n", ""},
+		{"conf.star", "out.json", []Option{WithStarlark()}, "{\n  \"name\": \"star\"\n}\n", ""},
+		{"unknown.json", "out.kv", nil, "", "it does not match the configuration structure: json: unknown field \"nmae\""},
+		{"conf.json", "out.star", nil, "", "unsupported format of [" + filepath.Join(dir, "out.star") + "] to convert into, supported formats are: json, kv"},
+		{"missing.json", "out.kv", nil, "", "failed to read [" + filepath.Join(dir, "missing.json") + "] to convert"},
+	}
+
+	for _, c := range cases {
+		conf := &struct {
+			Name string `json:"name"`
+			Host string `json:"host"`
+		}{}
+
+		in, out := filepath.Join(dir, c.in), filepath.Join(dir, c.out)
+		os.Remove(out)
+
+		msg, err := Parse("CONV", "", nil, conf, append(c.opts, WithEnvironment(nil), WithArgs("", "-convert", in, out))...)
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) {
+			t.Errorf("expected error: %v, but found: %v", c.err, err)
+			continue
+		}
+
+		if data, _ := os.ReadFile(out); string(data) != c.expected || (err == nil && !strings.Contains(msg, out)) {
+			t.Errorf("expected converted document: %q, but found: (%q, %q)", c.expected, data, msg)
+		}
+	}
+
+	if _, err := Parse("CONV", "", nil, &testConf{}, WithEnvironment(nil), WithArgs("", "-convert", "in.json")); err == nil || !strings.Contains(err.Error(), "requires the files to convert from and into") {
+		t.Errorf("expected the missing arguments to fail, but found: %v", err)
+	}
+}
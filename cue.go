@@ -0,0 +1,58 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	cueerrors "cuelang.org/go/cue/errors"
+)
+
+// evalCUE evaluates doc as a CUE document, which is a superset of JSON, unified with
+// the optional schema and returns the resulting concrete value as a JSON document.
+func evalCUE(doc []byte, schema string) ([]byte, error) {
+	ctx := cuecontext.New()
+
+	val := ctx.CompileBytes(doc, cue.Filename("config"))
+	if err := val.Err(); err != nil {
+		return nil, cueError("invalid configuration", err)
+	}
+
+	if len(strings.TrimSpace(schema)) > 0 {
+		sch := ctx.CompileString(schema, cue.Filename("schema"))
+		if err := sch.Err(); err != nil {
+			return nil, cueError("invalid configuration schema", err)
+		}
+
+		val = sch.Unify(val)
+	}
+
+	if err := val.Validate(cue.Concrete(true)); err != nil {
+		return nil, cueError("configuration violates schema", err)
+	}
+
+	return val.MarshalJSON()
+}
+
+// cueError wraps err with all the details CUE reports about it, e.g. the positions
+// and the conflicting values of every violated constraint.
+func cueError(msg string, err error) error {
+	return fmt.Errorf("%v: %v", msg, strings.TrimSpace(cueerrors.Details(err, nil)))
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSchema = `
+#Config: {
+	id:     int & >0
+	name:   string | *"anonymous"
+	online: bool
+}
+#Config
+`
+
+func TestCliCUE(t *testing.T) {
+	cases := []struct {
+		args     []string
+		expected testConf
+		err      string
+	}{
+		{[]string{"", "-config", `{"id":1,"online":true}`}, testConf{ID: 1, Name: "anonymous", Online: true}, ""},
+		{[]string{"", "-config-format", "cue", "-config", "id: 2\nname: \"Bob\"\nonline: false"}, testConf{ID: 2, Name: "Bob"}, ""},
+		{[]string{"", "-config", `{"id":0,"online":true}`}, testConf{}, "configuration violates schema: "},
+		{[]string{"", "-config", `{"id":1,"online":true,"port":80}`}, testConf{}, "configuration violates schema: "},
+		{[]string{"", "-config-format", "cue", "-config", "id: "}, testConf{}, "invalid configuration: "},
+		{[]string{"", "-config-format", "yml", "-config", "id: 1"}, testConf{}, "unsupported configuration format [yml], supported formats are: "},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		res, err := withMockedArgs(&input{args: c.args}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf, WithCUESchema(testSchema))
+		})
+
+		if len(c.err) > 0 {
+			if err == nil || !strings.HasPrefix(err.Error(), c.err) {
+				t.Errorf("expected error: %v, but found: %v", c.err, err)
+			}
+			continue
+		}
+
+		if res != "" || err != nil || *conf != c.expected {
+			t.Errorf("expected output: (\"\", nil, %+v), but found: (%v, %v, %+v)", c.expected, res, err, *conf)
+		}
+	}
+}
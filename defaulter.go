@@ -0,0 +1,47 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+)
+
+// Defaulter is implemented by the configuration structure, or any of the values it holds, setting its own
+// default values programmatically, e.g. the ones derived from others or from the environment of the host.
+type Defaulter interface {
+	SetDefaults()
+}
+
+// defaulterType is the type of the values setting their own defaults.
+var defaulterType = reflect.TypeOf((*Defaulter)(nil)).Elem()
+
+// callDefaulters calls the SetDefaults methods of the values of the configuration structure conf implementing
+// Defaulter, before any source is applied so that the sources override them, the nested values before the
+// ones holding them so that the latter may override the former. The methods promoted from embedded
+// structures are called for both, they are expected to be idempotent.
+func callDefaulters(conf interface{}) {
+	v := reflect.ValueOf(conf)
+	if !v.IsValid() {
+		return
+	}
+
+	walkValues(v, "", make(map[uintptr]bool), func(v reflect.Value, _ string) {
+		if defaulter, ok := implementer(v, defaulterType); ok {
+			defaulter.(Defaulter).SetDefaults()
+		}
+	})
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+type DefaulterPool struct {
+	Size int `json:"size" default:"4"`
+	Max  int `json:"max"`
+}
+
+func (p *DefaulterPool) SetDefaults() {
+	if p.Max == 0 {
+		p.Max = p.Size * 2
+	}
+}
+
+type defaulterConf struct {
+	DefaulterPool
+	Name   string                   `json:"name"`
+	Cache  *DefaulterPool           `json:"cache"`
+	Shards map[string]DefaulterPool `json:"shards"`
+}
+
+func (c *defaulterConf) SetDefaults() {
+	if len(c.Name) == 0 {
+		c.Name = "pool"
+	}
+
+	// nested values are already defaulted.
+	if c.Cache != nil {
+		c.Cache.Max++
+	}
+}
+
+func TestCliDefaulters(t *testing.T) {
+	cases := []struct {
+		conf     defaulterConf
+		config   string
+		expected defaulterConf
+	}{
+		{defaulterConf{}, `{}`, defaulterConf{DefaulterPool: DefaulterPool{4, 8}, Name: "pool", Cache: &DefaulterPool{4, 9}}},
+		{defaulterConf{Cache: &DefaulterPool{Size: 1}}, `{"name": "x", "max": 3}`, defaulterConf{DefaulterPool: DefaulterPool{4, 3}, Name: "x", Cache: &DefaulterPool{1, 3}}},
+	}
+
+	for _, c := range cases {
+		conf := c.conf
+
+		_, err := Parse("DFL", "", nil, &conf, WithEnvironment(nil), WithArgs("", "-config", c.config))
+
+		if err != nil || !reflect.DeepEqual(conf, c.expected) {
+			t.Errorf("expected output: (%+v, <nil>), but found: (%+v, %v)", c.expected, conf, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+)
+
+// mergeEmbeddedDefault merges the JSON document doc over the default configuration embedded in the binary.
+func mergeEmbeddedDefault(o *options, doc []byte, getEnv func(key, defVal string) string) ([]byte, error) {
+	data, err := embeddedDefault(o, getEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	var defaults, val interface{}
+
+	if err = json.Unmarshal(data, &defaults); err != nil {
+		return nil, fmt.Errorf("invalid embedded default configuration: %v", err)
+	}
+
+	if err = json.Unmarshal(doc, &val); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(deepMerge(defaults, val))
+}
+
+// embeddedDefault reads the default configuration embedded in the binary and translates it into JSON,
+// it is interpreted the same way as any other configuration, placeholders included.
+func embeddedDefault(o *options, getEnv func(key, defVal string) string) ([]byte, error) {
+	data, err := fs.ReadFile(o.defaultFS, o.defaultPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded default configuration: %v", err)
+	}
+
+	if data, err = interpret(o, formatOf(o.defaultPath), string(data), getEnv); err != nil {
+		return nil, fmt.Errorf("invalid embedded default configuration: %v", err)
+	}
+
+	return data, nil
+}
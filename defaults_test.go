@@ -0,0 +1,64 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCliEmbeddedDefault(t *testing.T) {
+	t.Setenv("TEST_DEFAULT_NAME", "Sybil")
+
+	fsys := fstest.MapFS{
+		"defaults.json": {Data: []byte(`{
+			// shipped with the binary.
+			"id": 26,
+			"name": "${DEFAULT_NAME}",
+			"online": true,
+		}`)},
+		"defaults.cue":  {Data: []byte("id: 27\nname: \"Trent\"")},
+		"invalid.json":  {Data: []byte(`{"id": `)},
+		"defaults.conf": {Data: []byte(`{"id": 28}`)},
+	}
+
+	cases := []struct {
+		path, config string
+		expected     testConf
+		err          string
+	}{
+		{"defaults.json", `{}`, testConf{ID: 26, Name: "Sybil", Online: true}, ""},
+		{"defaults.json", `{"name": "Victor", "online": false}`, testConf{ID: 26, Name: "Victor"}, ""},
+		{"defaults.cue", `{"online": true}`, testConf{ID: 27, Name: "Trent", Online: true}, ""},
+		{"defaults.conf", `{}`, testConf{ID: 28}, ""},
+		{"invalid.json", `{}`, testConf{}, "invalid embedded default configuration"},
+		{"missing.json", `{}`, testConf{}, "failed to read embedded default configuration"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config", c.config}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf, WithEmbeddedDefault(fsys, c.path))
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// applyDefaultTags sets the fields of the configuration structure conf tagged with `default:"<value>"` that
+// hold zero values to their default values, parsed the way the values of environment variables are, e.g.
+// 1m30s for durations or a,b for slices, so that applications need not populate conf by hand. Nested
+// structures are walked as well, including the ones pointed to, which are only allocated if any of their
+// fields is set. The elements of slices are left to applyElementDefaults, since the ones of the documents
+// replace them when decoded.
+func applyDefaultTags(conf interface{}) error {
+	v := reflect.ValueOf(conf)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	_, err := defaultStruct(v.Elem(), "", false, nil)
+
+	return err
+}
+
+// applyElementDefaults sets the fields of the structures of the elements of the slices and arrays of the
+// configuration structure conf tagged with `default:"<value>"` that hold zero values to their default
+// values once decoded, as applyDefaultTags does for the other fields before.
+func applyElementDefaults(conf interface{}) error {
+	v := reflect.ValueOf(conf)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	_, err := defaultStruct(v.Elem(), "", true, nil)
+
+	return err
+}
+
+// defaultStruct sets the fields of the structure v to their default values, where path is the dotted JSON
+// path of v in the configuration structure, and reports whether any field was set. Only the fields of the
+// elements of slices and arrays are set when elements is true, and only the other ones otherwise. The types
+// of the structures holding v are recorded in parents, see walkFields.
+func defaultStruct(v reflect.Value, path string, elements bool, parents map[reflect.Type]bool) (bool, error) {
+	var set bool
+
+	if parents == nil {
+		parents = make(map[reflect.Type]bool)
+	}
+
+	err := walkFields(v, path, parents, func(field reflect.Value, f reflect.StructField, path string) (bool, error) {
+		if val, found := f.Tag.Lookup("default"); found {
+			if elements || !field.IsZero() {
+				return false, nil
+			}
+
+			if err := setFromString(field, val); err != nil {
+				return false, fmt.Errorf("invalid default value [%v] of field [%v]: %v", val, path, err)
+			}
+
+			set = true
+
+			return false, nil
+		}
+
+		switch {
+		case isNestedStruct(field.Type()):
+			if field.Kind() != reflect.Ptr || !field.IsNil() {
+				return true, nil
+			}
+
+			// recursive structures are not allocated again, e.g. Next in type node struct{ Next *node }.
+			if elements || !field.CanSet() || parents[field.Type().Elem()] {
+				return false, nil
+			}
+
+			// structures pointed to are only allocated if any of their fields is set.
+			target := reflect.New(field.Type().Elem())
+
+			nested, err := defaultStruct(target.Elem(), path, false, parents)
+			if nested {
+				field.Set(target)
+				set = true
+			}
+
+			return false, err
+		case elements && isStructList(field.Type()):
+			// elements get the defaults of their own fields, then the walk sets the ones of their own elements.
+			for i := 0; i < field.Len(); i++ {
+				elem := field.Index(i)
+				if elem.Kind() == reflect.Ptr {
+					if elem.IsNil() {
+						continue
+					}
+					elem = elem.Elem()
+				}
+
+				nested, err := defaultStruct(elem, fmt.Sprintf("%v[%v]", path, i), false, parents)
+				if err != nil {
+					return false, err
+				}
+
+				set = set || nested
+			}
+
+			return true, nil
+		}
+
+		return false, nil
+	})
+
+	return set, err
+}
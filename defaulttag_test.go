@@ -0,0 +1,125 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type defaultServer struct {
+	Host string `json:"host" default:"localhost"`
+	Port int    `json:"port" default:"8080"`
+}
+
+type defaultConf struct {
+	Name     string         `json:"name" default:"app"`
+	Timeout  time.Duration  `json:"timeout" default:"1m30s"`
+	Tags     []string       `json:"tags" default:"a, b"`
+	Enabled  *bool          `json:"enabled" default:"true"`
+	Server   defaultServer  `json:"server"`
+	Backup   *defaultServer `json:"backup"`
+	Replicas []defaultServer
+	Plain    *struct {
+		Value string `json:"value"`
+	} `json:"plain"`
+}
+
+func TestCliDefaultTags(t *testing.T) {
+	enabled, disabled := true, false
+
+	cases := []struct {
+		conf     defaultConf
+		config   string
+		expected defaultConf
+	}{
+		{defaultConf{}, `{}`, defaultConf{
+			Name: "app", Timeout: 90 * time.Second, Tags: []string{"a", "b"}, Enabled: &enabled,
+			Server: defaultServer{"localhost", 8080}, Backup: &defaultServer{"localhost", 8080},
+		}},
+		{defaultConf{Name: "preset", Enabled: &disabled, Replicas: []defaultServer{{Host: "r1"}}}, `{"timeout": 5000000000, "server": {"port": 80}, "tags": ["x"]}`, defaultConf{
+			Name: "preset", Timeout: 5 * time.Second, Tags: []string{"x"}, Enabled: &disabled,
+			Server: defaultServer{"localhost", 80}, Backup: &defaultServer{"localhost", 8080}, Replicas: []defaultServer{{"r1", 8080}},
+		}},
+		{defaultConf{Replicas: []defaultServer{{Host: "r1"}}}, `{"Replicas": [{"host": "r2"}, {"port": 81}]}`, defaultConf{
+			Name: "app", Timeout: 90 * time.Second, Tags: []string{"a", "b"}, Enabled: &enabled,
+			Server: defaultServer{"localhost", 8080}, Backup: &defaultServer{"localhost", 8080}, Replicas: []defaultServer{{"r2", 8080}, {"localhost", 81}},
+		}},
+	}
+
+	for _, c := range cases {
+		conf := c.conf
+
+		_, err := Parse("DEF", "", nil, &conf, WithEnvironment(nil), WithArgs("", "-config", c.config))
+
+		if err != nil || !reflect.DeepEqual(conf, c.expected) {
+			t.Errorf("expected output: (%+v, <nil>), but found: (%+v, %v)", c.expected, conf, err)
+		}
+	}
+
+	invalid := []struct {
+		conf   interface{}
+		config string
+		err    string
+	}{
+		{&struct {
+			Server struct {
+				Port int `json:"port" default:"http"`
+			} `json:"server"`
+		}{}, `{}`, "invalid default value [http] of field [server.port]"},
+		{&struct {
+			Servers []struct {
+				Port int `json:"port" default:"http"`
+			} `json:"servers"`
+		}{}, `{"servers": [{}]}`, "invalid default value [http] of field [servers[0].port]"},
+	}
+
+	for _, c := range invalid {
+		_, err := Parse("DEF", "", nil, c.conf, WithEnvironment(nil), WithArgs("", "-config", c.config))
+
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error: %v, but found: %v", c.err, err)
+		}
+	}
+}
+
+type defaultNode struct {
+	Name     string         `json:"name" default:"node"`
+	Next     *defaultNode   `json:"next"`
+	Children []*defaultNode `json:"children"`
+}
+
+func TestDefaultTagsRecursive(t *testing.T) {
+	conf := &defaultNode{Next: &defaultNode{Name: "second"}, Children: []*defaultNode{{}}}
+
+	if err := applyDefaultTags(conf); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := applyElementDefaults(conf); err != nil {
+		t.Fatal(err)
+	}
+
+	// nil pointers to the recursive structure are left nil.
+	expected := &defaultNode{Name: "node", Next: &defaultNode{Name: "second"}, Children: []*defaultNode{{Name: "node"}}}
+
+	if !reflect.DeepEqual(conf, expected) {
+		t.Errorf("expected output: %+v, but found: %+v", expected, conf)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// checkDeprecated warns about the options of the JSON document doc of the fields of the configuration
+// structure tagged with `deprecated:"<message>"`, e.g. `deprecated:"use server.port instead"`, reporting
+// each of them to the hook set by WithDeprecationHook if any, so that deployments migrate before they are
+// removed. It fails listing them instead when WithStrictDeprecations is set.
+func checkDeprecated(o *options, doc []byte) error {
+	if o.confType == nil {
+		return nil
+	}
+
+	fields := taggedFields(o.confType, nil, "deprecated", func(value string) bool { return len(value) > 0 }, make(map[reflect.Type]bool))
+	if len(fields) == 0 {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+
+	var root map[string]interface{}
+	if err := dec.Decode(&root); err != nil {
+		// documents that are not objects are left to fail decoding.
+		return nil
+	}
+
+	var deprecated []string
+
+	for _, field := range fields {
+		path := strings.Join(field.path, ".")
+		found := false
+
+		matchKeys(root, field.path, func(map[string]interface{}, string) {
+			found = true
+		})
+
+		if !found {
+			continue
+		}
+
+		if o.deprecationHook != nil {
+			o.deprecationHook(path, field.value)
+		}
+
+		if o.strictDeprecations {
+			deprecated = append(deprecated, fmt.Sprintf("[%v] %v", path, field.value))
+		} else {
+			o.logger.Printf("WARNING: option [%v] is deprecated: %v", path, field.value)
+		}
+	}
+
+	if len(deprecated) > 0 {
+		return fmt.Errorf("deprecated options: %v", strings.Join(deprecated, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type deprecationConf struct {
+	Server struct {
+		Port   int    `json:"port"`
+		Listen string `json:"listen" deprecated:"use server.port instead"`
+	} `json:"server"`
+	Legacy *struct {
+		Mode string `json:"mode"`
+	} `json:"legacy" deprecated:"remove it, it has no effect"`
+}
+
+func TestCliDeprecated(t *testing.T) {
+	cases := []struct {
+		config string
+		opts   []Option
+		logs   string
+		hooked []string
+		err    string
+	}{
+		{`{"server": {"port": 80}}`, nil, "", nil, ""},
+		{`{"server": {"LISTEN": ":80"}, "legacy": {"mode": "x"}}`, nil,
+			"WARNING: option [server.listen] is deprecated: use server.port instead\nWARNING: option [legacy] is deprecated: remove it, it has no effect\n",
+			[]string{"server.listen: use server.port instead", "legacy: remove it, it has no effect"}, ""},
+		{`{"server": {"listen": ":80"}, "legacy": null}`, []Option{WithStrictDeprecations()}, "",
+			[]string{"server.listen: use server.port instead", "legacy: remove it, it has no effect"},
+			"deprecated options: [server.listen] use server.port instead, [legacy] remove it, it has no effect"},
+	}
+
+	for _, c := range cases {
+		var (
+			logs   bytes.Buffer
+			hooked []string
+		)
+
+		hook := func(path, message string) {
+			hooked = append(hooked, path+": "+message)
+		}
+
+		_, err := Parse("DEP", "", nil, &deprecationConf{}, append(c.opts, WithDeprecationHook(hook), WithLogger(log.New(&logs, "", 0)),
+			WithEnvironment(nil), WithArgs("", "-config", c.config))...)
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) ||
+			logs.String() != c.logs || !reflect.DeepEqual(hooked, c.hooked) {
+			t.Errorf("expected output: (%q, %v, %v), but found: (%q, %v, %v)", c.logs, c.hooked, c.err, logs.String(), hooked, err)
+		}
+	}
+}
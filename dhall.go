@@ -0,0 +1,27 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "context"
+
+// evalDhall evaluates doc as a Dhall expression into a JSON document using the dhall-to-json
+// executable, which has to be available in $PATH. Imports are resolved relative to the working
+// directory, and are subject to the usual Dhall import safety rules i.e. integrity checks
+// and the same-origin policy of remote imports.
+func evalDhall(ctx context.Context, doc []byte) ([]byte, error) {
+	return runCommand(ctx, "dhall-to-json", doc, nil, "--compact")
+}
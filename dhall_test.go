@@ -0,0 +1,71 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// withFakeCommand installs an executable shell script with the specified name and body in a
+// temporary directory that takes precedence in $PATH for the rest of the test.
+func withFakeCommand(t *testing.T, name, script string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not supported on windows")
+	}
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestCliDhall(t *testing.T) {
+	// the fake evaluator echoes its input back when it is already JSON, and fails otherwise.
+	withFakeCommand(t, "dhall-to-json", `input=$(cat)
+case "$input" in
+  "{"*) echo "$input" ;;
+  *) echo "Error: Invalid input" >&2; exit 1 ;;
+esac
+`)
+
+	cases := []struct {
+		args     []string
+		expected testConf
+		err      string
+	}{
+		{[]string{"", "-config-format", "dhall", "-config", `{"id":7,"online":true}`}, testConf{ID: 7, Online: true}, ""},
+		{[]string{"", "-config-format", "dhall", "-config", `let id = 7 in { id }`}, testConf{}, "dhall-to-json failed: exit status 1: Error: Invalid input"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: c.args}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && err.Error() != c.err) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
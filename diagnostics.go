@@ -0,0 +1,212 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	// severityError and severityWarning are the severities of diagnostics, as defined by the
+	// Language Server Protocol.
+	severityError   = 1
+	severityWarning = 2
+)
+
+// stdin is where the documents to diagnose are read from, tests replace it.
+var stdin io.Reader = os.Stdin
+
+// diagnostic is a problem found in a configuration document, in the shape of the diagnostics of the
+// Language Server Protocol, so that editor plugins can pass them through.
+type diagnostic struct {
+	Range    diagnosticRange `json:"range"`
+	Severity int             `json:"severity"`
+	Source   string          `json:"source"`
+	Message  string          `json:"message"`
+}
+
+// diagnosticRange is the range of a document a diagnostic is about, from start included to end excluded.
+type diagnosticRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+// position is a zero based position in a document, where the character is counted in UTF-16 code units.
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// expandedRange is a range of an expanded document that replaced a placeholder of the original one.
+type expandedRange struct {
+	start, end, origStart, origEnd int
+}
+
+// diagnose reads a configuration document of the specified format, json if empty, from the standard
+// input, and returns its diagnostics as a JSON array: syntax errors, values that do not decode into the
+// configuration structure, and lint findings as warnings.
+// Placeholders of environment variables are expanded, while placeholders of schemes are taken as the
+// strings they are, so that no secret store is ever queried, and the ranges of the diagnostics are of
+// the document as written.
+func diagnose(o *options, format string, getEnv func(string, string) string) (string, error) {
+	if len(format) == 0 {
+		format = "json"
+	}
+
+	src, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the configuration document from the standard input: %v", err)
+	}
+
+	doc := string(src)
+	isJSON := strings.EqualFold(format, "json")
+	if isJSON {
+		doc = string(stripJSONC(src))
+	}
+
+	diags := []diagnostic{}
+	report := func(severity int, start, end int, msg string) {
+		diags = append(diags, diagnostic{
+			Range:    diagnosticRange{Start: positionOf(string(src), start), End: positionOf(string(src), end)},
+			Severity: severity,
+			Source:   "config",
+			Message:  msg,
+		})
+	}
+
+	// placeholders are expanded one by one, to map the offsets of the expanded document back.
+	var expanded strings.Builder
+	var ranges []expandedRange
+
+	last := 0
+	for _, loc := range placeHolderRegex.FindAllStringIndex(doc, -1) {
+		expanded.WriteString(doc[last:loc[0]])
+
+		val, err := expandEnvPlaceholders(o, doc[loc[0]:loc[1]], getEnv)
+		if err != nil {
+			report(severityError, loc[0], loc[1], err.Error())
+			val = doc[loc[0]:loc[1]]
+		}
+
+		start := expanded.Len()
+		expanded.WriteString(val)
+		ranges = append(ranges, expandedRange{start, expanded.Len(), loc[0], loc[1]})
+		last = loc[1]
+	}
+	expanded.WriteString(doc[last:])
+
+	// offsets of translated documents do not match the ones of the original documents, while offsets
+	// within expanded placeholders are mapped to the bounds of the placeholders.
+	original := func(off int64, end bool) int {
+		if !isJSON {
+			return 0
+		}
+
+		delta := 0
+		for _, r := range ranges {
+			if int(off) < r.start || (end && int(off) == r.start) {
+				break
+			}
+			if int(off) < r.end || (end && int(off) == r.end) {
+				if end {
+					return r.origEnd
+				}
+				return r.origStart
+			}
+			delta = r.origEnd - r.end
+		}
+
+		return int(off) + delta
+	}
+
+	data, err := toJSON(o, format, []byte(expanded.String()))
+	if err != nil {
+		report(severityError, 0, 0, err.Error())
+		return marshalDiagnostics(diags)
+	}
+
+	if isJSON {
+		for _, finding := range lintDocument(data) {
+			report(severityWarning, original(finding.start, false), original(finding.end, true), finding.msg)
+		}
+	}
+
+	var target interface{} = new(interface{})
+	if o.confType != nil {
+		target = reflect.New(o.confType).Interface()
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	if err = json.Unmarshal(data, target); errors.As(err, &syntaxErr) {
+		report(severityError, original(syntaxErr.Offset-1, false), original(syntaxErr.Offset, true), err.Error())
+	} else if errors.As(err, &typeErr) {
+		report(severityError, original(valueStart(data, typeErr.Offset), false), original(typeErr.Offset, true), err.Error())
+	} else if err != nil {
+		report(severityError, 0, 0, err.Error())
+	}
+
+	return marshalDiagnostics(diags)
+}
+
+// marshalDiagnostics encodes diags as a JSON array, followed by a new line.
+func marshalDiagnostics(diags []diagnostic) (string, error) {
+	data, err := json.Marshal(diags)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data) + "\n", nil
+}
+
+// valueStart returns the offset of the start of the JSON value of doc ending at end.
+func valueStart(doc []byte, end int64) int64 {
+	start := end
+	for start > 0 && !strings.ContainsRune(":,[", rune(doc[start-1])) {
+		start--
+	}
+
+	for start < end && strings.ContainsRune(" \t\r\n", rune(doc[start])) {
+		start++
+	}
+
+	return start
+}
+
+// positionOf returns the position of the byte at offset off of doc.
+func positionOf(doc string, off int) position {
+	if off > len(doc) {
+		off = len(doc)
+	}
+
+	line := strings.Count(doc[:off], "\n")
+	char := 0
+
+	for _, r := range doc[strings.LastIndex(doc[:off], "\n")+1 : off] {
+		char += utf16.RuneLen(r)
+	}
+
+	return position{Line: line, Character: char}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCliDiagnostics(t *testing.T) {
+	t.Setenv("DIAG_ID", "7")
+	t.Setenv("DIAG_LONG", "1234567")
+
+	pos := func(line, char int) position {
+		return position{Line: line, Character: char}
+	}
+
+	cases := []struct {
+		args     []string
+		doc      string
+		expected []diagnostic
+	}{
+		{nil, `{"id": ${ID}, "name": "${azkv:vault/name}"}`, []diagnostic{}},
+		{nil, "{\n  // the identifier\n  \"id\": 1,\n  \"id\": 2,\n}", []diagnostic{
+			{diagnosticRange{pos(3, 2), pos(3, 6)}, severityWarning, "config", "duplicate key [id]"},
+		}},
+		{nil, "{\"id\": ${LONG}, \"name\": \"é\", \"url\": \"x\"\n  \"online\": true}", []diagnostic{
+			{diagnosticRange{pos(0, 36), pos(0, 39)}, severityWarning, "config", "invalid URL of key [url]"},
+			{diagnosticRange{pos(1, 2), pos(1, 3)}, severityError, "config", "invalid character '\"' after object key:value pair"},
+		}},
+		{nil, "{\"name\": \"é\", \"id\": \"${LONG}\"}", []diagnostic{
+			{diagnosticRange{pos(0, 20), pos(0, 29)}, severityError, "config", "json: cannot unmarshal string into Go struct field testConf.id of type int"},
+		}},
+		{nil, "{\"id\": ${MISSING:?is required},\n\"url\": \"\"}", []diagnostic{
+			{diagnosticRange{pos(0, 7), pos(0, 30)}, severityError, "config", "$DIAG_MISSING is not set: is required"},
+			{diagnosticRange{pos(0, 7), pos(0, 30)}, severityError, "config", "invalid character '$' looking for beginning of value"},
+		}},
+		{[]string{"-config-format", "cue"}, "id: \"one\"", []diagnostic{
+			{diagnosticRange{pos(0, 0), pos(0, 0)}, severityError, "config", "json: cannot unmarshal string into Go struct field testConf.id of type int"},
+		}},
+	}
+
+	defer func(r interface{}) { stdin = r.(*strings.Reader) }(strings.NewReader(""))
+
+	for _, c := range cases {
+		stdin = strings.NewReader(c.doc)
+
+		out, err := withMockedArgs(&input{args: append([]string{"", "-diagnostics"}, c.args...)}, func(in *input) (string, error) {
+			return Parse("DIAG", "", nil, &testConf{})
+		})
+
+		var actual []diagnostic
+		if err == nil {
+			err = json.Unmarshal([]byte(out), &actual)
+		}
+
+		if err != nil || !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("expected diagnostics of %q: %+v, but found: (%+v, %v)", c.doc, c.expected, actual, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// dotEnvKeyRegex matches the names of the variables of .env files.
+var dotEnvKeyRegex = regexp.MustCompile(`\A[A-Za-z_][A-Za-z0-9_.]*\z`)
+
+// loadDotEnv reads the variables of the .env files set by WithDotEnv, in order, such that the variables
+// of a file override the ones of the files before it. Files that do not exist are skipped.
+func loadDotEnv(o *options) error {
+	for _, path := range o.dotEnvFiles {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to read [%v]: %v", path, err)
+		}
+
+		if err = parseDotEnv(data, o.dotEnv); err != nil {
+			return fmt.Errorf("invalid .env file [%v]: %v", path, err)
+		}
+	}
+
+	return nil
+}
+
+// parseDotEnv adds the variables defined by the .env document data to vars. Lines are of the form
+// KEY=value, optionally preceded by export, values may be single quoted to be taken literally, or double
+// quoted to support the \n, \t, \" and \\ escapes, and lines or unquoted values may end with a comment
+// starting with #.
+func parseDotEnv(data []byte, vars map[string]string) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, val, found := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if key = strings.TrimSpace(key); !found || !dotEnvKeyRegex.MatchString(key) {
+			return fmt.Errorf("line %v: a KEY=value definition is expected", n)
+		}
+
+		val = strings.TrimSpace(val)
+
+		switch {
+		case strings.HasPrefix(val, "'"):
+			end := strings.Index(val[1:], "'")
+			if end < 0 {
+				return fmt.Errorf("line %v: unterminated single quoted value", n)
+			}
+			val = val[1 : end+1]
+		case strings.HasPrefix(val, `"`):
+			var b strings.Builder
+			closed := false
+
+			for i := 1; i < len(val) && !closed; i++ {
+				switch c := val[i]; {
+				case c == '"':
+					closed = true
+				case c == '\\' && i+1 < len(val):
+					i++
+					switch val[i] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					default:
+						b.WriteByte(val[i])
+					}
+				default:
+					b.WriteByte(c)
+				}
+			}
+
+			if !closed {
+				return fmt.Errorf("line %v: unterminated double quoted value", n)
+			}
+			val = b.String()
+		default:
+			if i := strings.Index(val, " #"); i >= 0 {
+				val = strings.TrimSpace(val[:i])
+			}
+		}
+
+		vars[key] = val
+	}
+
+	return scanner.Err()
+}
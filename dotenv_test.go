@@ -0,0 +1,102 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCliDotEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	if err := os.WriteFile(".env", []byte("# local development\nexport DOT_CONFIG={\"id\": ${ID}, \"name\": \"${NAME}\"}\nDOT_ID=5\nDOT_NAME='Mia' # the name\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile("override.env", []byte("DOT_NAME=\"Noah # Jr\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile("invalid.env", []byte("DOT_NAME=Mia\nnot a definition\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		env      map[string]string
+		opts     []Option
+		expected testConf
+		err      string
+	}{
+		{nil, nil, testConf{}, ""},
+		{nil, []Option{WithDotEnv()}, testConf{ID: 5, Name: "Mia"}, ""},
+		{nil, []Option{WithDotEnv(".env", "override.env", "missing.env")}, testConf{ID: 5, Name: "Noah # Jr"}, ""},
+		{map[string]string{"DOT_ID": "6"}, []Option{WithDotEnv()}, testConf{ID: 6, Name: "Mia"}, ""},
+		{nil, []Option{WithDotEnv("invalid.env")}, testConf{}, "invalid .env file [invalid.env]: line 2: a KEY=value definition is expected"},
+		{nil, []Option{WithDotEnv(filepath.Join(dir))}, testConf{}, "failed to read [" + dir + "]"},
+	}
+
+	for _, c := range cases {
+		for key, val := range c.env {
+			t.Setenv(key, val)
+		}
+
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{""}}, func(in *input) (string, error) {
+			return Parse("DOT", "", nil, conf, c.opts...)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+
+		for key := range c.env {
+			os.Unsetenv(key)
+		}
+	}
+
+	if _, found := os.LookupEnv("DOT_ID"); found {
+		t.Error("expected the environment of the process to be unchanged")
+	}
+}
+
+func TestParseDotEnv(t *testing.T) {
+	cases := []struct {
+		doc      string
+		expected map[string]string
+		err      string
+	}{
+		{"A=1\n\n  B = two words # comment\nC=\"x\\ny\\t\\\\\" # comment\nD='$${E} # kept'\nF=\nG=a#b", map[string]string{"A": "1", "B": "two words", "C": "x\ny\t\\", "D": "$${E} # kept", "F": "", "G": "a#b"}, ""},
+		{"A='unterminated", map[string]string{}, "line 1: unterminated single quoted value"},
+		{"A=\"unterminated\\\"", map[string]string{}, "line 1: unterminated double quoted value"},
+		{"1A=x", map[string]string{}, "line 1: a KEY=value definition is expected"},
+	}
+
+	for _, c := range cases {
+		vars := make(map[string]string)
+		err := parseDotEnv([]byte(c.doc), vars)
+
+		if (err == nil && len(c.err) > 0) || (err != nil && err.Error() != c.err) || !reflect.DeepEqual(vars, c.expected) {
+			t.Errorf("expected output: (%v, %v), but found: (%v, %v)", c.expected, c.err, vars, err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// DriftedOption is an option of the configuration the application runs with whose value differs from the
+// one of the source of truth.
+type DriftedOption struct {
+	// Path is the dotted JSON path of the option, e.g. database.host.
+	Path string
+
+	// Origin is the source of the declared value of the option, or empty if it is not declared.
+	Origin Origin
+}
+
+// Drift loads the configuration again from its sources, the way Parse does given the same command line
+// arguments and environment, into declared, and compares it to running, the configuration the application
+// runs with. It returns the options whose values differ, e.g. database.host, in the order their fields are
+// declared, along with the sources their declared values come from, so that instances whose configuration
+// drifted from the source of truth, e.g. patched by hand or loaded before the source changed, can be flagged.
+// Applications typically call it periodically, so the configuration is loaded without side effects: no usage
+// report, receipt, deprecation, emergency or validation hook, and exec placeholders are disabled.
+// The running and declared parameters must be pointers to structures of the same type, declared holding
+// the same defaults running held before being parsed.
+func Drift(envVarPrefix string, running, declared interface{}, opts ...Option) ([]DriftedOption, error) {
+	if t := reflect.TypeOf(running); t == nil || t.Kind() != reflect.Ptr || t != reflect.TypeOf(declared) {
+		return nil, fmt.Errorf("the running and declared configurations must be pointers of the same type, found [%T] and [%T]", running, declared)
+	}
+
+	var provenance Provenance
+
+	opts = append(opts[:len(opts):len(opts)], func(o *options) {
+		o.usageReport, o.receiptSink, o.receiptKey = nil, "", nil
+		o.deprecationHook, o.emergencyHook, o.validationHooks = nil, nil, nil
+		o.execPlaceholders = false
+		o.provenance = &provenance
+	})
+
+	out, err := Parse(envVarPrefix, "", nil, declared, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the declared configuration: %v", err)
+	}
+
+	if len(out) > 0 {
+		return nil, errors.New("failed to load the declared configuration: the command line arguments do not load any")
+	}
+
+	var (
+		leaves = make([]map[string]interface{}, 2)
+		docs   = make([][]byte, 2)
+	)
+
+	for i, conf := range []interface{}{running, declared} {
+		data, err := json.Marshal(conf)
+		if err != nil {
+			return nil, err
+		}
+
+		var val interface{}
+		if err = json.Unmarshal(data, &val); err != nil {
+			return nil, err
+		}
+
+		leaves[i], docs[i] = make(map[string]interface{}), data
+		flatten("", val, leaves[i])
+	}
+
+	var paths []string
+
+	for path, val := range leaves[0] {
+		if other, found := leaves[1][path]; !found || !jsonEqual(val, other) {
+			paths = append(paths, path)
+		}
+	}
+
+	// the declared options are the ones the sources were recorded for.
+	for path := range provenance {
+		if _, found := leaves[0][path]; !found {
+			paths = append(paths, path)
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	// options are listed in the order their fields are declared.
+	sortPaths(paths, leafOrder(docs...))
+
+	drifted := make([]DriftedOption, len(paths))
+	for i, path := range paths {
+		drifted[i] = DriftedOption{Path: path, Origin: provenance[path]}
+	}
+
+	return drifted, nil
+}
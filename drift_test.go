@@ -0,0 +1,83 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDrift(t *testing.T) {
+	cases := []struct {
+		args     []string
+		running  interface{}
+		declared interface{}
+		expected []DriftedOption
+		err      string
+	}{
+		{[]string{"", "-config", `{"id": 1, "name": "Liam"}`}, &testConf{ID: 1, Name: "Liam"}, &testConf{}, nil, ""},
+		{[]string{"", "-config", `{"id": 1, "name": "Liam"}`}, &testConf{ID: 2, Name: "Liam", Online: true}, &testConf{}, []DriftedOption{{"id", OriginDocument}, {"online", OriginDefault}}, ""},
+		{[]string{"", "-config", `{"database": {"host": "db"}}`}, &usageConf{Port: 8080}, &usageConf{Port: 8080}, []DriftedOption{{"database.host", OriginDocument}}, ""},
+		{[]string{"", "-config", `{"id": "one"}`}, &testConf{}, &testConf{}, nil, "failed to load the declared configuration: json: cannot unmarshal string"},
+		{[]string{"", "-config", `{"id": 1, "name": "${exec:echo Liam}"}`}, &testConf{}, &testConf{}, nil, "requires commands to be enabled by WithExecPlaceholders"},
+		{[]string{"", "-version"}, &testConf{}, &testConf{}, nil, "failed to load the declared configuration: the command line arguments do not load any"},
+		{[]string{""}, &testConf{}, &usageConf{}, nil, "the running and declared configurations must be pointers of the same type, found [*config.testConf] and [*config.usageConf]"},
+	}
+
+	var (
+		report bytes.Buffer
+		called []string
+	)
+
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	receipt := filepath.Join(t.TempDir(), "receipt.json")
+
+	// the declared configuration is loaded without any side effect.
+	opts := []Option{
+		WithUsageReport(&report),
+		WithReceipt(receipt, key),
+		WithExecPlaceholders(),
+		WithEmergencyHook(func(time.Time, []string) { called = append(called, "emergency") }),
+		WithDeprecationHook(func(string, string) { called = append(called, "deprecation") }),
+		WithValidationHook(func(interface{}, Provenance) error {
+			called = append(called, "validation")
+			return nil
+		}),
+	}
+
+	for _, c := range cases {
+		drifted, err := withMockedArgs(&input{args: c.args}, func(in *input) (string, error) {
+			drifted, err := Drift("TEST", c.running, c.declared, opts...)
+			return fmt.Sprint(drifted), err
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || (err == nil && drifted != fmt.Sprint(c.expected)) {
+			t.Errorf("expected output: (%v, %v), but found: (%v, %v)", c.expected, c.err, drifted, err)
+		}
+	}
+
+	if _, err := os.Stat(receipt); report.Len() > 0 || len(called) > 0 || !os.IsNotExist(err) {
+		t.Errorf("expected no usage report, receipt or hook called, but found: (%q, %v, %v)", report.String(), err, called)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// emergency is the break-glass configuration of $<envVarPrefix>_EMERGENCY_CONFIG.
+type emergency struct {
+	// Expires is the time the break-glass configuration stops being in effect at.
+	Expires time.Time `json:"expires"`
+
+	// Config holds the options overriding the configuration.
+	Config json.RawMessage `json:"config"`
+}
+
+// applyEmergency decodes the options of the break-glass configuration of $<envVarPrefix>_EMERGENCY_CONFIG
+// if any into conf once every other source is, so that they outrank them all, letting the configuration be
+// patched during incidents without redeploying. The document must hold its expiry timestamp and the overriding options, e.g.
+// {"expires": "2024-01-02T15:04:05Z", "config": {"replicas": 10}}, and it is ignored once expired.
+// Its use is logged loudly and reported to the hook of WithEmergencyHook, if any.
+func applyEmergency(o *options, getEnv func(key, defVal string) string, conf interface{}) error {
+	name := o.envVarPrefix + "EMERGENCY_CONFIG"
+
+	val, found := lookupPrefixedEnv(o, "EMERGENCY_CONFIG")
+	if !found || len(strings.TrimSpace(val)) == 0 {
+		return nil
+	}
+
+	doc, err := expandPlaceholders(o, string(stripJSONC([]byte(val))), getEnv)
+	if err != nil {
+		return fmt.Errorf("invalid emergency configuration of $%v: %v", name, err)
+	}
+
+	var e emergency
+	if err = json.Unmarshal([]byte(doc), &e); err != nil {
+		return fmt.Errorf("invalid emergency configuration of $%v: %v", name, err)
+	}
+
+	if e.Expires.IsZero() {
+		return fmt.Errorf("emergency configuration of $%v requires an expiry timestamp, e.g. {\"expires\": \"%v\", \"config\": {...}}", name, time.Now().UTC().Add(time.Hour).Format(time.RFC3339))
+	}
+
+	if trimmed := bytes.TrimSpace(e.Config); len(trimmed) == 0 || trimmed[0] != '{' {
+		return fmt.Errorf("emergency configuration of $%v requires the overriding options as an object, e.g. {\"expires\": \"%v\", \"config\": {...}}", name, e.Expires.Format(time.RFC3339))
+	}
+
+	if time.Now().After(e.Expires) {
+		o.logger.Printf("WARNING: ignoring the emergency configuration of $%v, which expired at %v", name, e.Expires.Format(time.RFC3339))
+		return nil
+	}
+
+	if err = json.Unmarshal(e.Config, conf); err != nil {
+		return fmt.Errorf("invalid emergency configuration of $%v: %v", name, err)
+	}
+
+	order := leafOrder(e.Config)
+
+	paths := make([]string, 0, len(order))
+	for path := range order {
+		paths = append(paths, path)
+	}
+
+	sortPaths(paths, order)
+
+	o.logger.Printf("WARNING: EMERGENCY CONFIGURATION of $%v IS IN EFFECT until %v, overriding: %v", name, e.Expires.Format(time.RFC3339), strings.Join(paths, ", "))
+
+	if o.emergencyHook != nil {
+		o.emergencyHook(e.Expires, paths)
+	}
+
+	return nil
+}
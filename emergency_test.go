@@ -0,0 +1,87 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type emergencyConf struct {
+	Replicas int `json:"replicas" env:"REPLICAS"`
+	Server   struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	} `json:"server"`
+}
+
+func TestCliEmergencyConfig(t *testing.T) {
+	future, past := time.Now().Add(time.Hour).UTC().Format(time.RFC3339), time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	base := emergencyConf{Replicas: 3}
+	base.Server.Host, base.Server.Port = "a", 80
+
+	patched := base
+	patched.Replicas, patched.Server.Port = 10, 8080
+
+	cases := []struct {
+		env      map[string]string
+		expected emergencyConf
+		logs     string
+		paths    []string
+		err      string
+	}{
+		{nil, base, "", nil, ""},
+		{map[string]string{"EMG_EMERGENCY_CONFIG": `{"expires": "` + future + `", "config": {"server": {"port": ${PORT}}, "replicas": 10}}`, "EMG_PORT": "8080", "EMG_REPLICAS": "5"}, patched,
+			"WARNING: EMERGENCY CONFIGURATION of $EMG_EMERGENCY_CONFIG IS IN EFFECT until " + future + ", overriding: server.port, replicas", []string{"server.port", "replicas"}, ""},
+		{map[string]string{"EMG_EMERGENCY_CONFIG": `{"expires": "` + past + `", "config": {"replicas": 10}}`}, base,
+			"WARNING: ignoring the emergency configuration of $EMG_EMERGENCY_CONFIG, which expired at " + past, nil, ""},
+		{map[string]string{"EMG_EMERGENCY_CONFIG": `{"config": {"replicas": 10}}`}, emergencyConf{}, "", nil, "emergency configuration of $EMG_EMERGENCY_CONFIG requires an expiry timestamp"},
+		{map[string]string{"EMG_EMERGENCY_CONFIG": `{"expires": "` + future + `", "config": 10}`}, emergencyConf{}, "", nil, "emergency configuration of $EMG_EMERGENCY_CONFIG requires the overriding options as an object"},
+		{map[string]string{"EMG_EMERGENCY_CONFIG": `{"expires": "tomorrow"}`}, emergencyConf{}, "", nil, "invalid emergency configuration of $EMG_EMERGENCY_CONFIG: parsing time"},
+		{map[string]string{"EMG_EMERGENCY_CONFIG": `{"expires": "` + future + `", "config": {"replicas": "ten"}}`}, emergencyConf{}, "", nil, "invalid emergency configuration of $EMG_EMERGENCY_CONFIG: json: cannot unmarshal string"},
+	}
+
+	for _, c := range cases {
+		var (
+			logs  bytes.Buffer
+			paths []string
+		)
+
+		conf := &emergencyConf{}
+
+		hook := func(expires time.Time, p []string) {
+			paths = p
+		}
+
+		_, err := Parse("EMG", "", nil, conf, WithEnvironment(c.env), WithEmergencyHook(hook), WithLogger(log.New(&logs, "", 0)),
+			WithArgs("", "-config", `{"replicas": 3, "server": {"host": "a", "port": 80}}`))
+
+		if err != nil {
+			*conf = emergencyConf{}
+		}
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected ||
+			strings.TrimSuffix(logs.String(), "\n") != c.logs || !reflect.DeepEqual(paths, c.paths) {
+			t.Errorf("expected output: (%+v, %v, %v, %v), but found: (%+v, %v, %v, %v)", c.expected, c.logs, c.paths, c.err, *conf, logs.String(), paths, err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// lookupEnv returns the value of the environment variable of the specified name, and whether it is
+// defined, in the environment or else in the .env files loaded by WithDotEnv. When WithCaseInsensitiveEnv
+// is set, a variable whose name only differs by case matches as well, unless one matches exactly.
+func lookupEnv(o *options, name string) (string, bool) {
+	if val, found := lookupProcessEnv(o, name); found {
+		return val, true
+	}
+
+	if val, found := o.dotEnv[name]; found {
+		return val, true
+	}
+
+	if !o.caseInsensitiveEnv {
+		return "", false
+	}
+
+	for _, kv := range environ(o) {
+		if key, val, _ := strings.Cut(kv, "="); strings.EqualFold(key, name) {
+			return val, true
+		}
+	}
+
+	for key, val := range o.dotEnv {
+		if strings.EqualFold(key, name) {
+			return val, true
+		}
+	}
+
+	return "", false
+}
+
+// lookupPrefixedEnv returns the value of the environment variable named after the environment variable
+// prefix followed by key, and whether it is defined. Variables named after the legacy prefixes set by
+// WithLegacyPrefixes are looked up in order when it is not, warning once about their deprecation.
+func lookupPrefixedEnv(o *options, key string) (string, bool) {
+	if val, found := lookupEnv(o, o.envVarPrefix+key); found {
+		return val, true
+	}
+
+	for _, prefix := range o.legacyPrefixes {
+		if val, found := lookupEnv(o, prefix+key); found {
+			if !o.legacyWarned[prefix+key] {
+				o.legacyWarned[prefix+key] = true
+				o.logger.Printf("WARNING: $%v%v is deprecated, use $%v%v instead", prefix, key, o.envVarPrefix, key)
+			}
+
+			return val, true
+		}
+	}
+
+	return "", false
+}
+
+// lookupEnvFile returns the content of the file $<envVarPrefix><key>_FILE points at, and whether that
+// variable is defined, following the convention of Docker secrets where values are read from files rather
+// than held by the environment. A single trailing line break is trimmed, as left by editors and echo.
+func lookupEnvFile(o *options, key string) (string, bool, error) {
+	file, found := lookupPrefixedEnv(o, key+"_FILE")
+	if !found {
+		return "", false, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", true, fmt.Errorf("failed to read the file [%v] of $%v%v_FILE: %v", file, o.envVarPrefix, key, err)
+	}
+
+	return trimLineBreak(string(data)), true, nil
+}
+
+// trimLineBreak trims a single trailing line break off s, as left by editors and echo at the end of files.
+func trimLineBreak(s string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(s, "\n"), "\r")
+}
+
+// envValue returns the value of the environment variable named after the environment variable prefix
+// followed by key, or an empty string if undefined.
+func envValue(o *options, key string) string {
+	val, _ := lookupPrefixedEnv(o, key)
+	return val
+}
+
+// prefixedEnv returns a function returning the value of the environment variable named after the
+// environment variable prefix followed by key, or defVal if undefined.
+func prefixedEnv(o *options) func(key, defVal string) string {
+	return func(key, defVal string) string {
+		if val, found := lookupPrefixedEnv(o, key); found {
+			return val
+		}
+
+		return defVal
+	}
+}
+
+// platformEnv returns the value of the environment variable of the specified name regardless of the
+// environment variable prefix, e.g. the ones set by the platform, or an empty string if undefined.
+func platformEnv(o *options, name string) string {
+	val, _ := lookupEnv(o, name)
+	return val
+}
+
+// lookupProcessEnv returns the value of the environment variable of the specified name, and whether it
+// is defined, in the environment of the process, or in the one set by WithLookupEnv or WithEnvironment
+// instead.
+func lookupProcessEnv(o *options, name string) (string, bool) {
+	if o.lookupEnv != nil {
+		return o.lookupEnv(name)
+	}
+
+	if o.environment == nil {
+		return os.LookupEnv(name)
+	}
+
+	val, found := o.environment[name]
+
+	return val, found
+}
+
+// environ returns the variables of the environment of the process, or of the one set by WithEnvironment
+// instead sorted by name, in the form "key=value". The variables of the function set by WithLookupEnv
+// cannot be enumerated, so there are none.
+func environ(o *options) []string {
+	if o.lookupEnv != nil {
+		return nil
+	}
+
+	if o.environment == nil {
+		return os.Environ()
+	}
+
+	vars := make([]string, 0, len(o.environment))
+	for key, val := range o.environment {
+		vars = append(vars, key+"="+val)
+	}
+
+	sort.Strings(vars)
+
+	return vars
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCliCaseInsensitiveEnv(t *testing.T) {
+	cases := []struct {
+		prefix   string
+		env      map[string]string
+		opts     []Option
+		expected testConf
+		err      string
+	}{
+		{"CI", map[string]string{"Ci_Name": "Karl", "ci_config": `{"id": 3, "name": "${NAME}"}`}, nil, testConf{}, ""},
+		{"CI", map[string]string{"Ci_Name": "Karl", "ci_config": `{"id": 3, "name": "${NAME}"}`}, []Option{WithCaseInsensitiveEnv()}, testConf{ID: 3, Name: "Karl"}, ""},
+		{"ci", map[string]string{"CI_NAME": "Karl", "ci_Name": "Other", "CI_CONFIG": `{"id": 3, "name": "${NAME}"}`}, []Option{WithCaseInsensitiveEnv()}, testConf{ID: 3, Name: "Karl"}, ""},
+		{"ci", map[string]string{"CI_CONFIG": `{"id": 4}`}, nil, testConf{ID: 4}, ""},
+		{"ci-", nil, nil, testConf{}, "environment variable prefix [ci-] must start with a letter then letters or underscores"},
+	}
+
+	for _, c := range cases {
+		for key, val := range c.env {
+			t.Setenv(key, val)
+		}
+
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{""}}, func(in *input) (string, error) {
+			return Parse(c.prefix, "", nil, conf, c.opts...)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+
+		for key := range c.env {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestCliLegacyPrefixes(t *testing.T) {
+	cases := []struct {
+		env      map[string]string
+		prefixes []string
+		expected testConf
+		logs     string
+		err      string
+	}{
+		{map[string]string{"NEWAPP_CONFIG": `{"id": 1, "name": "${NAME}"}`, "NEWAPP_NAME": "new", "OLDAPP_NAME": "old"}, []string{"oldapp"}, testConf{ID: 1, Name: "new"}, "", ""},
+		{map[string]string{"OLDAPP_CONFIG": `{"id": 2, "name": "${NAME}-${NAME}"}`, "OLDAPP_NAME": "old", "OLDERAPP_NAME": "older"}, []string{"OLDAPP", "OLDERAPP"}, testConf{ID: 2, Name: "old-old"},
+			"WARNING: $OLDAPP_CONFIG is deprecated, use $NEWAPP_CONFIG instead\nWARNING: $OLDAPP_NAME is deprecated, use $NEWAPP_NAME instead\n", ""},
+		{map[string]string{"NEWAPP_CONFIG": `{"id": 3, "name": "${NAME}"}`, "OLDERAPP_NAME": "older"}, []string{"OLDAPP", "OLDERAPP"}, testConf{ID: 3, Name: "older"},
+			"WARNING: $OLDERAPP_NAME is deprecated, use $NEWAPP_NAME instead\n", ""},
+		{map[string]string{"OLDAPP_CONFIG": `{"id": 4}`}, nil, testConf{}, "", ""},
+		{nil, []string{"old-app"}, testConf{}, "", "invalid legacy prefix: environment variable prefix [old-app] must start with a letter"},
+	}
+
+	for _, c := range cases {
+		var logs bytes.Buffer
+
+		conf := &testConf{}
+
+		_, err := Parse("NEWAPP", "", nil, conf, WithLegacyPrefixes(c.prefixes...), WithEnvironment(c.env), WithArgs(""), WithLogger(log.New(&logs, "", 0)))
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected || logs.String() != c.logs {
+			t.Errorf("expected output: (%+v, %q, %v), but found: (%+v, %q, %v)", c.expected, c.logs, c.err, *conf, logs.String(), err)
+		}
+	}
+}
+
+func TestCliLookupEnv(t *testing.T) {
+	t.Setenv("LK_NAME", "leaked")
+
+	env := map[string]string{"LK_CONFIG": `{"id": ${ID}, "name": "${NAME:-none} ${env:LK_HOST}"}`, "LK_ID": "7", "LK_HOST": "h", "lk_name": "lower"}
+
+	var looked []string
+
+	lookup := func(name string) (string, bool) {
+		looked = append(looked, name)
+		val, found := env[name]
+		return val, found
+	}
+
+	conf := &testConf{}
+
+	_, err := Parse("LK", "", nil, conf, WithLookupEnv(lookup), WithEnvironment(map[string]string{"LK_ID": "8"}), WithCaseInsensitiveEnv(), WithArgs(""))
+
+	if expected := (testConf{ID: 7, Name: "none h"}); err != nil || *conf != expected {
+		t.Errorf("expected output: (%+v, <nil>), but found: (%+v, %v)", expected, *conf, err)
+	}
+
+	if len(looked) == 0 || looked[0] != "LK_CONFIG" {
+		t.Errorf("expected the variables to be looked up by the function, but found: %v", looked)
+	}
+}
+
+func TestCliEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "password")
+
+	if err := os.WriteFile(secret, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	type secretConf struct {
+		Password string `json:"password" env:"DB_PASSWORD"`
+		Token    string `json:"token"`
+	}
+
+	cases := []struct {
+		env      map[string]string
+		opts     []Option
+		expected secretConf
+		err      string
+	}{
+		{map[string]string{"EF_DB_PASSWORD_FILE": secret}, nil, secretConf{Password: "s3cr3t"}, ""},
+		{map[string]string{"EF_DB_PASSWORD_FILE": secret, "EF_DB_PASSWORD": "inline"}, nil, secretConf{Password: "inline"}, ""},
+		{map[string]string{"EF_CONFIG": `{"token": "${TOKEN}"}`, "EF_TOKEN_FILE": secret}, []Option{WithStrictPlaceholders()}, secretConf{Token: "s3cr3t"}, ""},
+		{map[string]string{"EF_CONFIG": `{"token": "${TOKEN:-none}"}`, "EF_TOKEN_FILE": secret, "EF_TOKEN": ""}, nil, secretConf{Token: "none"}, ""},
+		{map[string]string{"EF_CONFIG": `{"token": "${TOKEN}"}`, "EF_TOKEN_FILE": filepath.Join(dir, "missing")}, nil, secretConf{}, "failed to read the file"},
+		{map[string]string{"EF_DB_PASSWORD_FILE": filepath.Join(dir, "missing")}, nil, secretConf{}, "of $EF_DB_PASSWORD_FILE"},
+	}
+
+	for _, c := range cases {
+		conf := &secretConf{}
+
+		_, err := Parse("EF", "", nil, conf, append(c.opts, WithEnvironment(c.env), WithArgs(""))...)
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || (len(c.err) == 0 && *conf != c.expected) {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	// textUnmarshalerType is the type of the values parsing themselves from text.
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	// durationType is the type of time.Duration, parsed from strings such as 1m30s.
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// bindEnv sets the fields of the configuration structure conf tagged with `env:"<name>"` to the values
+// of $<envVarPrefix>_<name>, or else to the content of the file $<envVarPrefix>_<name>_FILE points at, when
+// defined, overriding the values of the configuration documents. When
+// automatic binding is enabled, every other field is bound as well to the variable named after its path,
+// see WithAutoEnv. Nested structures are walked as well, including the ones pointed to, which are only
+// allocated if any of their fields is set.
+func bindEnv(o *options, conf interface{}) error {
+	v := reflect.ValueOf(conf)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	_, err := bindEnvStruct(o, v.Elem(), "", "", make(map[reflect.Type]bool))
+
+	return err
+}
+
+// bindEnvStruct sets the bound fields of the structure v, where path is the dotted JSON path of v in the
+// configuration structure and envPath is the one used to name the variables bound automatically, and reports whether
+// any field was set. The types of the structures holding v are recorded in parents, see walkFields.
+func bindEnvStruct(o *options, v reflect.Value, path, envPath string, parents map[reflect.Type]bool) (bool, error) {
+	var set bool
+
+	err := walkFields(v, path, parents, func(field reflect.Value, f reflect.StructField, name string) (bool, error) {
+		key, auto := f.Tag.Get("env"), false
+		if key == "-" {
+			return false, nil
+		}
+
+		// embedded structures share the path of the structure embedding them, as in JSON.
+		nestedEnvPath := envPath + envName(f) + "_"
+		if name == path {
+			nestedEnvPath = envPath
+		}
+
+		if len(key) == 0 && o.autoEnv && !isNestedStruct(field.Type()) {
+			key, auto = envPath+envName(f), true
+		}
+
+		// alpha fields are only bound when alpha options are enabled.
+		alpha := f.Tag.Get("stability") == "alpha" && !o.alpha
+
+		if len(key) > 0 {
+			val, found := lookupPrefixedEnv(o, key)
+			if !found {
+				// secrets may be read from the file of $<envVarPrefix>_<name>_FILE instead.
+				var err error
+				if val, found, err = lookupEnvFile(o, key); err != nil || !found {
+					return false, err
+				}
+			}
+
+			if alpha {
+				o.logger.Printf("WARNING: ignoring alpha option [%v] set by $%v%v, enable alpha options with -enable-alpha-config or $%vALPHA=1", name, o.envVarPrefix, key, o.envVarPrefix)
+				return false, nil
+			}
+
+			if err := setFromString(field, val); err != nil {
+				return false, fmt.Errorf("invalid value of $%v%v for field [%v]: %v", o.envVarPrefix, key, name, err)
+			}
+
+			set = true
+
+			return false, nil
+		}
+
+		if !isNestedStruct(field.Type()) || auto || alpha {
+			return false, nil
+		}
+
+		if field.Kind() != reflect.Ptr {
+			nested, err := bindEnvStruct(o, field, name, nestedEnvPath, parents)
+			set = set || nested
+
+			return false, err
+		}
+
+		if !field.IsNil() {
+			nested, err := bindEnvStruct(o, field.Elem(), name, nestedEnvPath, parents)
+			set = set || nested
+
+			return false, err
+		}
+
+		// recursive structures are not allocated again, e.g. Next in type node struct{ Next *node }.
+		if !field.CanSet() || parents[field.Type().Elem()] {
+			return false, nil
+		}
+
+		// structures pointed to are only allocated if any of their fields is set.
+		target := reflect.New(field.Type().Elem())
+
+		nested, err := bindEnvStruct(o, target.Elem(), name, nestedEnvPath, parents)
+		if nested {
+			field.Set(target)
+			set = true
+		}
+
+		return false, err
+	})
+
+	return set, err
+}
+
+// envBinding binds the environment variable of key, relative to the environment variable prefix, to the
+// field of the configuration structure located by its dotted JSON path.
+type envBinding struct {
+	key, path string
+}
+
+// envBindings returns the bindings of the environment variables to the fields of the structure t, the way
+// bindEnvStruct binds them, where path is the JSON path of t and envPath is the one used to name the
+// variables bound automatically. The types of the structures holding t are recorded in parents, see walkType.
+func envBindings(o *options, t reflect.Type, path, envPath string, parents map[reflect.Type]bool) []envBinding {
+	var bindings []envBinding
+
+	_ = walkType(t, path, parents, func(_ reflect.Value, f reflect.StructField, name string) (bool, error) {
+		key, auto := f.Tag.Get("env"), false
+		if key == "-" {
+			return false, nil
+		}
+
+		nestedEnvPath := envPath + envName(f) + "_"
+		if name == path {
+			nestedEnvPath = envPath
+		}
+
+		if len(key) == 0 && o.autoEnv && !isNestedStruct(f.Type) {
+			key, auto = envPath+envName(f), true
+		}
+
+		if len(key) > 0 {
+			bindings = append(bindings, envBinding{key: key, path: name})
+		} else if isNestedStruct(f.Type) && !auto {
+			bindings = append(bindings, envBindings(o, f.Type, name, nestedEnvPath, parents)...)
+		}
+
+		return false, nil
+	})
+
+	return bindings
+}
+
+// isNestedStruct reports whether t is a structure, or a pointer to one, whose fields are bound
+// individually rather than as a whole.
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// envName derives the name of the environment variable of field f, relative to the structure it
+// belongs to, from its JSON name if any, otherwise its name, in upper snake case e.g. MaxConns
+// is named MAX_CONNS.
+func envName(f reflect.StructField) string {
+	name := f.Name
+	if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); len(tag) > 0 && tag != "-" {
+		name = tag
+	}
+
+	var b strings.Builder
+
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+			(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+			b.WriteRune('_')
+		}
+
+		if r == '-' || r == '.' {
+			r = '_'
+		}
+
+		b.WriteRune(unicode.ToUpper(r))
+	}
+
+	return b.String()
+}
+
+// setFromString parses s into v according to its type, values implementing encoding.TextUnmarshaler
+// parse themselves, durations are parsed by time.ParseDuration, slices are comma separated, and other
+// composite values are parsed as JSON.
+func setFromString(v reflect.Value, s string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Ptr:
+		target := reflect.New(v.Type().Elem())
+		if err := setFromString(target.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(target)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 && !strings.HasPrefix(strings.TrimSpace(s), "[") {
+			items := strings.Split(s, ",")
+			slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+
+			for i, item := range items {
+				if err := setFromString(slice.Index(i), strings.TrimSpace(item)); err != nil {
+					return err
+				}
+			}
+
+			v.Set(slice)
+
+			return nil
+		}
+		fallthrough
+	default:
+		target := reflect.New(v.Type())
+		if err := json.Unmarshal([]byte(s), target.Interface()); err != nil {
+			return err
+		}
+		v.Set(target.Elem())
+	}
+
+	return nil
+}
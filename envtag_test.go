@@ -0,0 +1,214 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type envTagConf struct {
+	Port    int           `json:"port" env:"PORT"`
+	Debug   bool          `json:"debug" env:"DEBUG"`
+	Timeout time.Duration `json:"timeout" env:"TIMEOUT"`
+	Hosts   []string      `json:"hosts" env:"HOSTS"`
+	Bind    net.IP        `json:"bind" env:"BIND"`
+	Labels  map[string]string
+	Server  struct {
+		Name string `json:"name" env:"SERVER_NAME"`
+	} `json:"server"`
+	TLS *struct {
+		Cert string `env:"TLS_CERT"`
+	}
+	Ignored string `env:"-"`
+	secret  string `env:"SECRET"`
+}
+
+func TestCliEnvTag(t *testing.T) {
+	web := envTagConf{Port: 80}
+	web.Server.Name = "web"
+
+	cases := []struct {
+		env      map[string]string
+		expected envTagConf
+		err      string
+	}{
+		{nil, web, ""},
+		{map[string]string{
+			"TEST_PORT":        "8080",
+			"TEST_DEBUG":       "true",
+			"TEST_TIMEOUT":     "1m30s",
+			"TEST_HOSTS":       "a, b",
+			"TEST_BIND":        "::1",
+			"TEST_SERVER_NAME": "api",
+			"TEST_TLS_CERT":    "/etc/tls.crt",
+			"TEST_SECRET":      "s3cr3t",
+			"TEST_IGNORED":     "x",
+		}, envTagConf{
+			Port:    8080,
+			Debug:   true,
+			Timeout: 90 * time.Second,
+			Hosts:   []string{"a", "b"},
+			Bind:    net.ParseIP("::1"),
+			Server: struct {
+				Name string `json:"name" env:"SERVER_NAME"`
+			}{Name: "api"},
+			TLS: &struct {
+				Cert string `env:"TLS_CERT"`
+			}{Cert: "/etc/tls.crt"},
+		}, ""},
+		{map[string]string{"TEST_PORT": "http"}, web, `invalid value of $TEST_PORT for field [port]: strconv.ParseInt: parsing "http": invalid syntax`},
+		{map[string]string{"TEST_TIMEOUT": "soon"}, web, `invalid value of $TEST_TIMEOUT for field [timeout]`},
+	}
+
+	for _, c := range cases {
+		for key, val := range c.env {
+			t.Setenv(key, val)
+		}
+
+		conf := &envTagConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config", `{"port": 80, "server": {"name": "web"}}`}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || !reflect.DeepEqual(*conf, c.expected) {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+
+		for key := range c.env {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+type autoEnvConf struct {
+	Name     string `json:"name"`
+	MaxConns int
+	Database struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	} `json:"database"`
+	Cache *struct {
+		TTL time.Duration `json:"ttl"`
+	} `json:"cache"`
+	Tagged   string `env:"CUSTOM"`
+	Hidden   string `json:"-"`
+	Excluded string `env:"-"`
+}
+
+func TestCliAutoEnv(t *testing.T) {
+	cases := []struct {
+		env      map[string]string
+		expected autoEnvConf
+		err      string
+	}{
+		{nil, autoEnvConf{Name: "app"}, ""},
+		{map[string]string{
+			"AUTO_NAME":          "api",
+			"AUTO_MAX_CONNS":     "10",
+			"AUTO_DATABASE_HOST": "db",
+			"AUTO_DATABASE_PORT": "5432",
+			"AUTO_CACHE_TTL":     "1m",
+			"AUTO_CUSTOM":        "custom",
+			"AUTO_TAGGED":        "x",
+			"AUTO_HIDDEN":        "x",
+			"AUTO_EXCLUDED":      "x",
+		}, autoEnvConf{
+			Name:     "api",
+			MaxConns: 10,
+			Database: struct {
+				Host string `json:"host"`
+				Port int    `json:"port"`
+			}{Host: "db", Port: 5432},
+			Cache: &struct {
+				TTL time.Duration `json:"ttl"`
+			}{TTL: time.Minute},
+			Tagged: "custom",
+		}, ""},
+		{map[string]string{"AUTO_DATABASE_PORT": "db"}, autoEnvConf{Name: "app"}, `invalid value of $AUTO_DATABASE_PORT for field [database.port]`},
+	}
+
+	for _, c := range cases {
+		for key, val := range c.env {
+			t.Setenv(key, val)
+		}
+
+		conf := &autoEnvConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config", `{"name": "app"}`}}, func(in *input) (string, error) {
+			return Parse("AUTO", "", nil, conf, WithAutoEnv())
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || !reflect.DeepEqual(*conf, c.expected) {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+
+		for key := range c.env {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	cases := []struct {
+		field    reflect.StructField
+		expected string
+	}{
+		{reflect.StructField{Name: "Host"}, "HOST"},
+		{reflect.StructField{Name: "MaxConns"}, "MAX_CONNS"},
+		{reflect.StructField{Name: "TLSConfig"}, "TLS_CONFIG"},
+		{reflect.StructField{Name: "Port2Use"}, "PORT2_USE"},
+		{reflect.StructField{Name: "Host", Tag: `json:"server-host,omitempty"`}, "SERVER_HOST"},
+		{reflect.StructField{Name: "Host", Tag: `json:",omitempty"`}, "HOST"},
+	}
+
+	for _, c := range cases {
+		if actual := envName(c.field); actual != c.expected {
+			t.Errorf("expected env name of [%v] to be [%v], but found [%v]", c.field.Name, c.expected, actual)
+		}
+	}
+}
+
+type envNode struct {
+	Name string   `json:"name"`
+	Next *envNode `json:"next"`
+}
+
+func TestEnvRecursive(t *testing.T) {
+	o := newOptions([]Option{WithAutoEnv(), WithEnvironment(map[string]string{"NODE_NAME": "first", "NODE_NEXT_NAME": "second"})})
+	o.envVarPrefix = "NODE_"
+
+	conf := &envNode{}
+	if err := bindEnv(o, conf); err != nil {
+		t.Fatal(err)
+	}
+
+	// the recursive structure Next points to is not walked again, it is left nil.
+	if expected := (&envNode{Name: "first"}); !reflect.DeepEqual(conf, expected) {
+		t.Errorf("expected output: %+v, but found: %+v", expected, conf)
+	}
+
+	bindings := envBindings(o, reflect.TypeOf(conf), "", "", make(map[reflect.Type]bool))
+	if expected := []envBinding{{"NAME", "name"}}; !reflect.DeepEqual(bindings, expected) {
+		t.Errorf("expected bindings: %+v, but found: %+v", expected, bindings)
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// errorFormats maps the names of the supported error formats to functions rendering the messages of
+// an error, along with the path of the configuration file it is about if any.
+var errorFormats = map[string]func(file string, msgs []string) string{
+	"text": func(_ string, msgs []string) string {
+		return strings.Join(msgs, "\n")
+	},
+	"json": func(file string, msgs []string) string {
+		type entry struct {
+			Message string `json:"message"`
+			File    string `json:"file,omitempty"`
+		}
+
+		entries := make([]entry, len(msgs))
+		for i, msg := range msgs {
+			entries[i] = entry{Message: msg, File: file}
+		}
+
+		data, _ := json.Marshal(entries)
+
+		return string(data)
+	},
+	"github": func(file string, msgs []string) string {
+		props := "title=Invalid configuration"
+		if len(file) > 0 {
+			props = "file=" + escapeGitHubProperty(file) + "," + props
+		}
+
+		lines := make([]string, len(msgs))
+		for i, msg := range msgs {
+			lines[i] = fmt.Sprintf("::error %v::%v", props, escapeGitHubData(msg))
+		}
+
+		return strings.Join(lines, "\n")
+	},
+	"sarif": func(file string, msgs []string) string {
+		type location struct {
+			PhysicalLocation struct {
+				ArtifactLocation struct {
+					URI string `json:"uri"`
+				} `json:"artifactLocation"`
+			} `json:"physicalLocation"`
+		}
+
+		type result struct {
+			RuleID  string `json:"ruleId"`
+			Level   string `json:"level"`
+			Message struct {
+				Text string `json:"text"`
+			} `json:"message"`
+			Locations []location `json:"locations,omitempty"`
+		}
+
+		results := make([]result, len(msgs))
+		for i, msg := range msgs {
+			results[i] = result{RuleID: sarifRuleID, Level: "error"}
+			results[i].Message.Text = msg
+
+			if len(file) > 0 {
+				loc := location{}
+				loc.PhysicalLocation.ArtifactLocation.URI = file
+				results[i].Locations = []location{loc}
+			}
+		}
+
+		sarif := map[string]interface{}{
+			"version": "2.1.0",
+			"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+			"runs": []interface{}{map[string]interface{}{
+				"tool": map[string]interface{}{"driver": map[string]interface{}{
+					"name":           "config",
+					"informationUri": "https://github.com/adzr/config",
+					"rules": []interface{}{map[string]interface{}{
+						"id":               sarifRuleID,
+						"shortDescription": map[string]string{"text": "The configuration is invalid"},
+					}},
+				}},
+				"results": results,
+			}},
+		}
+
+		data, _ := json.Marshal(sarif)
+
+		return string(data)
+	},
+}
+
+// sarifRuleID is the identifier of the SARIF rule configuration errors are reported under.
+const sarifRuleID = "invalid-configuration"
+
+// formatError renders err in the specified error format, so that CI systems can show configuration
+// errors inline, e.g. as GitHub Actions annotations or SARIF logs for code scanning dashboards. Errors grouping several errors are rendered as
+// one entry per line of their message, and file is the path of the configuration file if any.
+func formatError(format, file string, err error) error {
+	render, found := errorFormats[strings.ToLower(format)]
+	if !found || strings.EqualFold(format, "text") {
+		return err
+	}
+
+	var msgs []string
+	for _, msg := range strings.Split(err.Error(), "\n") {
+		if msg = strings.TrimSpace(msg); len(msg) > 0 {
+			msgs = append(msgs, msg)
+		}
+	}
+
+	return errors.New(render(file, msgs))
+}
+
+// errorFile returns the path of the configuration file loaded from uris, if they are a single file: URI.
+func errorFile(uris []string) string {
+	if len(uris) != 1 {
+		return ""
+	}
+
+	u, err := parseURI(uris[0])
+	if err != nil || !strings.EqualFold(u.Scheme, "file") {
+		return ""
+	}
+
+	if len(u.Opaque) > 0 {
+		return u.Opaque
+	}
+
+	return u.Path
+}
+
+// errorFormatNames returns the sorted names of the supported error formats.
+func errorFormatNames() []string {
+	names := make([]string, 0, len(errorFormats))
+
+	for name := range errorFormats {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// escapeGitHubData escapes the message of a GitHub Actions workflow command.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes a property value of a GitHub Actions workflow command.
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
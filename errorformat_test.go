@@ -0,0 +1,67 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCliErrorFormats(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "api.json")
+	if err := os.WriteFile(file, []byte(`{"id": "one"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"", "-config", `{"id": "one"}`}, "json: cannot unmarshal string into Go struct field testConf.id of type int"},
+		{[]string{"", "-errors", "json", "-config", `{"id": "one"}`}, `[{"message":"json: cannot unmarshal string into Go struct field testConf.id of type int"}]`},
+		{[]string{"", "-errors", "json", "-config-uri", "file://" + file}, `[{"message":"json: cannot unmarshal string into Go struct field testConf.id of type int","file":"` + file + `"}]`},
+		{[]string{"", "-errors", "github", "-config-uri", "file://" + file}, "::error file=" + file + ",title=Invalid configuration::json: cannot unmarshal string into Go struct field testConf.id of type int"},
+		{[]string{"", "-errors", "github", "-config-uri", "file:///missing.json file:///other.json"}, "::error title=Invalid configuration::failed to fetch configuration from [file:///missing.json]: open /missing.json: no such file or directory"},
+		{[]string{"", "-errors", "github", "-config", `{"name": "100%"`}, "::error title=Invalid configuration::unexpected end of JSON input"},
+		{[]string{"", "-errors", "sarif", "-config-uri", "file://" + file}, `{"$schema":"https://json.schemastore.org/sarif-2.1.0.json","runs":[{"results":[{"ruleId":"invalid-configuration","level":"error",` +
+			`"message":{"text":"json: cannot unmarshal string into Go struct field testConf.id of type int"},"locations":[{"physicalLocation":{"artifactLocation":{"uri":"` + file + `"}}}]}],` +
+			`"tool":{"driver":{"informationUri":"https://github.com/adzr/config","name":"config","rules":[{"id":"invalid-configuration","shortDescription":{"text":"The configuration is invalid"}}]}}}],"version":"2.1.0"}`},
+		{[]string{"", "-errors", "xml"}, "unsupported error format [xml], supported formats are: github, json, sarif, text"},
+	}
+
+	for _, c := range cases {
+		_, err := withMockedArgs(&input{args: c.args}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, &testConf{})
+		})
+
+		if err == nil || err.Error() != c.err {
+			t.Errorf("expected error: %v, but found: %v", c.err, err)
+		}
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	err := formatError("github", "conf/a,b.json", errors.New("first: 100%\nsecond\n"))
+	expected := "::error file=conf/a%2Cb.json,title=Invalid configuration::first: 100%25\n" +
+		"::error file=conf/a%2Cb.json,title=Invalid configuration::second"
+
+	if err.Error() != expected {
+		t.Errorf("expected error: %v, but found: %v", expected, err)
+	}
+}
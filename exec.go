@@ -0,0 +1,96 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// commandOutput returns the standard output of the command referenced by the placeholder, without its
+// trailing new lines, e.g. ${exec:op read op://vault/db/password} for secrets of a password manager CLI.
+// The command is split into its executable and arguments as commandArgs does and run without any shell.
+// The output is escaped to be injected into JSON strings. Commands are only run when WithExecPlaceholders
+// is set, since documents of untrusted sources would otherwise run commands of their choice.
+func commandOutput(ctx context.Context, o *options, ref string) (string, error) {
+	if !o.execPlaceholders {
+		return "", fmt.Errorf("placeholder [${exec:%v}] requires commands to be enabled by WithExecPlaceholders", ref)
+	}
+
+	args, err := commandArgs(ref)
+	if err != nil {
+		return "", fmt.Errorf("placeholder [${exec:%v}] is invalid: %v", ref, err)
+	}
+
+	if len(args) == 0 {
+		return "", fmt.Errorf("placeholder [${exec:%v}] has no command", ref)
+	}
+
+	out, err := runCommand(ctx, args[0], nil, nil, args[1:]...)
+	if err != nil {
+		return "", fmt.Errorf("failed to run the command of placeholder [${exec:%v}]: %v", ref, err)
+	}
+
+	escaped := quote(strings.TrimRight(string(out), "\r\n"))
+
+	return escaped[1 : len(escaped)-1], nil
+}
+
+// commandArgs splits command into its executable and arguments by white spaces, except for the ones enclosed
+// in single or double quotes as in shells, e.g. op read 'op://vault/db/my password', single quotes needing
+// no escaping within JSON strings. Unlike in shells, backslashes are kept as they are.
+func commandArgs(command string) ([]string, error) {
+	var (
+		args  []string
+		b     strings.Builder
+		open  rune
+		inArg bool
+	)
+
+	for _, c := range command {
+		switch {
+		case open != 0 && c == open:
+			open = 0
+		case open != 0:
+			b.WriteRune(c)
+		case c == '\'' || c == '"':
+			open, inArg = c, true
+		case unicode.IsSpace(c):
+			if inArg {
+				args = append(args, b.String())
+				b.Reset()
+				inArg = false
+			}
+		default:
+			b.WriteRune(c)
+			inArg = true
+		}
+	}
+
+	if open != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+
+	if inArg {
+		args = append(args, b.String())
+	}
+
+	return args, nil
+}
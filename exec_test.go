@@ -0,0 +1,51 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCliExecPlaceholder(t *testing.T) {
+	cases := []struct {
+		config   string
+		opts     []Option
+		expected testConf
+		err      string
+	}{
+		{`{"id": 1, "name": "${exec:printf s3cr3t\n\n}"}`, []Option{WithExecPlaceholders()}, testConf{ID: 1, Name: "s3cr3t"}, ""},
+		{`{"id": ${exec:echo 7}, "name": "${exec:echo two words}"}`, []Option{WithExecPlaceholders()}, testConf{ID: 7, Name: "two words"}, ""},
+		{`{"id": 1, "name": "${exec:printf '%s|%s' 'two  words' ''}"}`, []Option{WithExecPlaceholders()}, testConf{ID: 1, Name: "two  words|"}, ""},
+		{`{"id": 1, "name": "${exec:printf '\042q\042\134\nx'}"}`, []Option{WithExecPlaceholders()}, testConf{ID: 1, Name: "\"q\"\\\nx"}, ""},
+		{`{"id": 1, "name": "${exec:echo 'x}"}`, []Option{WithExecPlaceholders()}, testConf{}, "placeholder [${exec:echo 'x}] is invalid: unterminated quote"},
+		{`{"id": 1, "name": "$${exec:echo x}"}`, []Option{WithExecPlaceholders()}, testConf{ID: 1, Name: "${exec:echo x}"}, ""},
+		{`{"id": 1, "name": "${exec:echo x}"}`, nil, testConf{}, "placeholder [${exec:echo x}] requires commands to be enabled by WithExecPlaceholders"},
+		{`{"id": 1, "name": "${exec:false}"}`, []Option{WithExecPlaceholders()}, testConf{}, "failed to run the command of placeholder [${exec:false}]: false failed"},
+		{`{"id": 1, "name": "${exec:  }"}`, []Option{WithExecPlaceholders()}, testConf{}, "placeholder [${exec:  }] has no command"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := Parse("EXEC", "", nil, conf, append(c.opts, WithArgs("", "-config", c.config))...)
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// endpointCooldown is how long a failed endpoint is tried after the healthy ones.
+	endpointCooldown = time.Minute
+
+	// regionLookupTimeout bounds the time spent asking the instance metadata services for the region.
+	regionLookupTimeout = time.Second
+)
+
+// failedEndpoints records the last time every endpoint failed.
+var failedEndpoints = struct {
+	sync.Mutex
+	at map[string]time.Time
+}{at: make(map[string]time.Time)}
+
+// probedRegion caches the region answered by the instance metadata services, which are only probed once
+// per process.
+var probedRegion = struct {
+	sync.Mutex
+	probed bool
+	region string
+}{}
+
+// fetchFailover fetches the configuration document from the first of the alternative endpoints uris that
+// succeeds, and returns it along with its format as reported by fetchWithRetry. The endpoints are tried in
+// order, except that the ones of the local region come first, while the ones that failed within the last
+// minute come last.
+// The local region is read from $<envVarPrefix>_REGION, otherwise from the instance metadata services
+// of AWS, Google Cloud or Azure, and an endpoint is of the region if its host contains the region name,
+// e.g. https://config.eu-west-1.example.com/api.json is of the eu-west-1 region.
+func fetchFailover(o *options, uris []string) ([]byte, string, error) {
+	region := strings.ToLower(envValue(o, "REGION"))
+	if len(region) == 0 {
+		region = localRegion(o)
+	}
+
+	failedEndpoints.Lock()
+	failed := make(map[string]bool, len(uris))
+	for _, uri := range uris {
+		failed[uri] = time.Since(failedEndpoints.at[uri]) < endpointCooldown
+	}
+	failedEndpoints.Unlock()
+
+	local := func(uri string) bool {
+		u, err := parseURI(uri)
+		return err == nil && len(region) > 0 && strings.Contains(strings.ToLower(u.Host), region)
+	}
+
+	ordered := append([]string(nil), uris...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if failed[ordered[i]] != failed[ordered[j]] {
+			return !failed[ordered[i]]
+		}
+		return local(ordered[i]) && !local(ordered[j])
+	})
+
+	var errs []error
+
+	for _, uri := range ordered {
+		doc, format, err := fetchWithRetry(o, uri)
+
+		failedEndpoints.Lock()
+		if err == nil {
+			delete(failedEndpoints.at, uri)
+		} else {
+			failedEndpoints.at[uri] = time.Now()
+		}
+		failedEndpoints.Unlock()
+
+		if err == nil {
+			return doc, format, nil
+		}
+
+		if o.ctx.Err() != nil {
+			return nil, "", err
+		}
+
+		errs = append(errs, err)
+	}
+
+	return nil, "", fmt.Errorf("all configuration endpoints failed: %v", errors.Join(errs...))
+}
+
+// localRegion asks the instance metadata services of the supported clouds for the region the
+// process runs in, and returns the first answer, or an empty string if none answers in time. The
+// answer is cached, unless the lookup was interrupted by the context of Parse.
+func localRegion(o *options) string {
+	probedRegion.Lock()
+	defer probedRegion.Unlock()
+
+	if !probedRegion.probed {
+		probedRegion.region = lookupRegion(o)
+		probedRegion.probed = o.ctx.Err() == nil
+	}
+
+	return probedRegion.region
+}
+
+// lookupRegion probes the instance metadata services concurrently, as described by localRegion.
+func lookupRegion(o *options) string {
+	ctx, cancel := context.WithTimeout(o.ctx, regionLookupTimeout)
+	defer cancel()
+
+	lookups := []func(ctx context.Context, o *options) (string, error){awsRegion, googleRegion, azureRegion}
+	regions := make(chan string, len(lookups))
+
+	for _, lookup := range lookups {
+		go func() {
+			region, err := lookup(ctx, o)
+			if err != nil {
+				region = ""
+			}
+			regions <- strings.ToLower(strings.TrimSpace(region))
+		}()
+	}
+
+	for range lookups {
+		select {
+		case region := <-regions:
+			if len(region) > 0 {
+				return region
+			}
+		case <-ctx.Done():
+			return ""
+		}
+	}
+
+	return ""
+}
+
+// awsRegion reads the region from the EC2 instance metadata service, using IMDSv2.
+func awsRegion(ctx context.Context, o *options) (string, error) {
+	endpoint := platformEnv(o, "AWS_EC2_METADATA_SERVICE_ENDPOINT")
+	if len(endpoint) == 0 {
+		endpoint = "http://169.254.169.254"
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+
+	client := metadataClient(o, req.URL.Hostname())
+
+	token, err := doRequest(client, req)
+	if err != nil {
+		return "", err
+	}
+
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/latest/meta-data/placement/region", nil); err != nil {
+		return "", err
+	}
+
+	req.Header.Set("X-aws-ec2-metadata-token", string(token))
+
+	region, err := doRequest(client, req)
+
+	return string(region), err
+}
+
+// googleRegion reads the zone from the Google Cloud metadata server and returns its region.
+func googleRegion(ctx context.Context, o *options) (string, error) {
+	host := platformEnv(o, "GCE_METADATA_HOST")
+	if len(host) == 0 {
+		host = "metadata.google.internal"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+host+"/computeMetadata/v1/instance/zone", nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	zone, err := doRequest(metadataClient(o, req.URL.Hostname()), req)
+	if err != nil {
+		return "", err
+	}
+
+	// zones are of the form projects/<project>/zones/<region>-<zone>.
+	region := string(zone[strings.LastIndex(string(zone), "/")+1:])
+	if i := strings.LastIndex(region, "-"); i > 0 {
+		region = region[:i]
+	}
+
+	return region, nil
+}
+
+// azureRegion reads the location from the Azure instance metadata service.
+func azureRegion(ctx context.Context, o *options) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		"http://169.254.169.254/metadata/instance/compute/location?api-version=2021-02-01&format=text", nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Metadata", "true")
+
+	region, err := doRequest(metadataClient(o, req.URL.Hostname()), req)
+
+	return string(region), err
+}
+
+// metadataClient returns the client requesting the instance metadata service at host. Link-local
+// addresses and the well known metadata hosts are only reachable from the instance, they are requested
+// directly rather than through the proxies of the environment, with a copy of the client of the sources
+// whose transport has no proxy. Clients of other transports than http.Transport are used as they are.
+func metadataClient(o *options, host string) *http.Client {
+	ip := net.ParseIP(host)
+	if (ip == nil || !ip.IsLinkLocalUnicast()) && host != "metadata.google.internal" {
+		return o.httpClient
+	}
+
+	rt := o.httpClient.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	transport, ok := rt.(*http.Transport)
+	if !ok {
+		return o.httpClient
+	}
+
+	transport = transport.Clone()
+	transport.Proxy = nil
+	transport.DisableKeepAlives = true
+
+	client := *o.httpClient
+	client.Transport = transport
+
+	return &client
+}
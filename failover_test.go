@@ -0,0 +1,122 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCliFailover(t *testing.T) {
+	var down map[string]bool
+	var probes int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "metadata.test" {
+			probes++
+			if r.URL.Path != "/computeMetadata/v1/instance/zone" || r.Header.Get("Metadata-Flavor") != "Google" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte("projects/42/zones/us-east1-b"))
+			return
+		}
+
+		// the AWS and Azure metadata services are not available.
+		if !strings.HasPrefix(r.Host, "config.") {
+			http.NotFound(w, r)
+			return
+		}
+
+		region := strings.TrimSuffix(strings.TrimPrefix(r.Host, "config."), ".example.com")
+
+		if down[region] {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte(`{"id": 29, "name": "` + region + `"}`))
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: rewriteTransport(srv.URL)}
+
+	t.Setenv("GCE_METADATA_HOST", "metadata.test")
+	t.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", "http://aws.test")
+
+	uri := "https://config.europe-west1.example.com/api.json|https://config.us-east1.example.com/api.json"
+
+	cases := []struct {
+		region   string
+		down     []string
+		expected testConf
+		err      string
+	}{
+		// the region is read from the metadata server.
+		{"", nil, testConf{ID: 29, Name: "us-east1"}, ""},
+		{"europe-west1", nil, testConf{ID: 29, Name: "europe-west1"}, ""},
+		{"europe-west1", []string{"europe-west1", "us-east1"}, testConf{}, "all configuration endpoints failed"},
+		{"europe-west1", []string{"europe-west1"}, testConf{ID: 29, Name: "us-east1"}, ""},
+		// endpoints that failed recently are tried last.
+		{"europe-west1", nil, testConf{ID: 29, Name: "us-east1"}, ""},
+		// the region read from the metadata server is cached.
+		{"", []string{"europe-west1"}, testConf{ID: 29, Name: "us-east1"}, ""},
+	}
+
+	failedEndpoints.at = make(map[string]time.Time)
+	probedRegion.probed = false
+
+	for _, c := range cases {
+		t.Setenv("TEST_REGION", c.region)
+
+		down = map[string]bool{}
+		for _, region := range c.down {
+			down[region] = true
+		}
+
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf, func(o *options) { o.httpClient = client })
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+
+	if probes != 1 {
+		t.Errorf("expected the metadata server to be probed once, but it was probed %v times", probes)
+	}
+
+	proxied := &http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}}
+	o := &options{httpClient: proxied}
+
+	for host, direct := range map[string]bool{"169.254.169.254": true, "fe80::1": true, "metadata.google.internal": true, "metadata.test": false} {
+		if c := metadataClient(o, host); (c != proxied) != direct || (direct && c.Transport.(*http.Transport).Proxy != nil) {
+			t.Errorf("expected the metadata service at [%v] to be requested directly: %v", host, direct)
+		}
+	}
+
+	// clients of other transports are used as they are.
+	if o.httpClient = client; metadataClient(o, "169.254.169.254") != client {
+		t.Error("expected the metadata service to be requested by the client of the sources")
+	}
+}
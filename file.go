@@ -0,0 +1,54 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// fetchFile reads a local file, located by a file:///<absolute path> or a file:<relative path> URI.
+func fetchFile(ctx context.Context, o *options, u *url.URL) ([]byte, error) {
+	name := u.Path
+	if len(u.Opaque) > 0 {
+		name = u.Opaque
+	}
+
+	if len(name) == 0 || len(u.Host) > 0 {
+		return nil, errors.New("URI must be in the form file:///<absolute path> or file:<relative path>")
+	}
+
+	return os.ReadFile(name)
+}
+
+// fileContent returns the content of the file referenced by its path, e.g. ${file:/run/secrets/db_password}
+// for secrets mounted as files by Docker or Kubernetes, without its trailing line break if any, as for the
+// files of $<envVarPrefix>_<name>_FILE. The content is escaped to be injected into JSON strings, e.g. a
+// multi-line PEM key.
+func fileContent(ctx context.Context, o *options, ref string) (string, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the file of placeholder [${file:%v}]: %v", ref, err)
+	}
+
+	escaped := quote(trimLineBreak(string(data)))
+
+	return escaped[1 : len(escaped)-1], nil
+}
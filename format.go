@@ -0,0 +1,146 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// formats maps the names of the supported configuration formats to functions
+// translating a document of that format into a strict JSON document.
+var formats = map[string]func(o *options, doc []byte) ([]byte, error){
+	"json": func(o *options, doc []byte) ([]byte, error) {
+		return stripJSONC(doc), nil
+	},
+	"cue": func(o *options, doc []byte) ([]byte, error) {
+		return evalCUE(doc, o.cueSchema)
+	},
+	"dhall": func(o *options, doc []byte) ([]byte, error) {
+		return evalDhall(o.ctx, doc)
+	},
+	"jsonnet": func(o *options, doc []byte) ([]byte, error) {
+		return evalJsonnet(o, doc)
+	},
+	"star": evalStarlark,
+}
+
+// toJSON translates doc from the specified format into a strict JSON document.
+func toJSON(o *options, format string, doc []byte) ([]byte, error) {
+	translate, found := formats[strings.ToLower(format)]
+
+	if codec, registered := codecOf(format); !found && registered {
+		return codec.Unmarshal(doc)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("unsupported configuration format [%v], supported formats are: %v", format, strings.Join(formatNames(), ", "))
+	}
+
+	return translate(o, doc)
+}
+
+// interpret translates doc written in the specified format, detected if empty, into a strict JSON document.
+// Hand-edited JSON documents may carry comments and trailing commas, these are stripped first so that
+// placeholders inside comments are never resolved. Then the placeholders e.g. ${PASSWORD}, which
+// translates into "I want to inject the value of the environment variable APP_PREFIX_PASSWORD here",
+// are replaced by their values, and they may as well refer to secrets in a store e.g. ${azkv:vault/secret}
+// for a secret in an Azure Key Vault.
+func interpret(o *options, format, doc string, getEnv func(key, defVal string) string) ([]byte, error) {
+	if len(format) == 0 {
+		format = detectFormat([]byte(doc))
+	}
+
+	if strings.EqualFold(format, "json") {
+		doc = scopePlaceholders(o, string(stripJSONC([]byte(doc))))
+	}
+
+	// environment variables must not shadow each other silently.
+	if err := checkEnvCollisions(o, format, doc); err != nil {
+		return nil, err
+	}
+
+	// malformed documents fail fast when pre-validation is enabled, before any expensive resolution.
+	if o.preValidate && o.confType != nil && schemePlaceHolderRegex.MatchString(doc) {
+		done := track(o, "validate")
+		err := preValidate(o, format, doc, getEnv)
+		done()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	done := track(o, "resolve")
+	expanded, err := expandPlaceholders(o, doc, getEnv)
+	done()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer track(o, "decode")()
+
+	data, err := toJSON(o, format, []byte(strings.TrimSpace(expanded)))
+	if err == nil {
+		lint(o, data)
+	}
+
+	return data, err
+}
+
+// formatNames returns the sorted names of the supported configuration formats.
+func formatNames() []string {
+	names := codecNames()
+
+	for name := range formats {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// runCommand runs the named executable with the specified arguments feeding it with stdin,
+// and returns whatever it writes to its standard output. The command inherits the environment
+// of the current process in addition to env. Failures are reported along with whatever the
+// command writes to its standard error.
+func runCommand(ctx context.Context, name string, stdin []byte, env []string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); len(msg) > 0 {
+			return nil, fmt.Errorf("%v failed: %v: %v", name, err, msg)
+		}
+
+		return nil, fmt.Errorf("%v failed: %v", name, err)
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// gcsReadScope is the OAuth2 scope required to read objects from Google Cloud Storage.
+	gcsReadScope = "https://www.googleapis.com/auth/devstorage.read_only"
+
+	// googleTokenURL is the default OAuth2 token endpoint of Google.
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+)
+
+// googleCredentials is the content of a Google application default credentials file,
+// either of a service account or of an authorized user.
+type googleCredentials struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// fetchGCS downloads the object located by a gs://<bucket>/<object> URI from Google Cloud Storage,
+// authenticated with the application default credentials. Setting $STORAGE_EMULATOR_HOST directs
+// the requests to an unauthenticated storage emulator instead.
+func fetchGCS(ctx context.Context, o *options, u *url.URL) ([]byte, error) {
+	bucket, object := u.Host, strings.TrimPrefix(u.Path, "/")
+
+	if len(bucket) == 0 || len(object) == 0 {
+		return nil, errors.New("URI must be in the form gs://<bucket>/<object>")
+	}
+
+	var (
+		endpoint = "https://storage.googleapis.com"
+		token    string
+		err      error
+	)
+
+	if host := platformEnv(o, "STORAGE_EMULATOR_HOST"); len(host) > 0 {
+		endpoint = host
+		if !strings.Contains(host, "://") {
+			endpoint = "http://" + host
+		}
+	} else if token, err = googleToken(ctx, o, gcsReadScope); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%v/storage/v1/b/%v/o/%v?alt=media",
+		strings.TrimSuffix(endpoint, "/"), url.PathEscape(bucket), url.PathEscape(object)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return doRequest(o.httpClient, req)
+}
+
+// googleToken obtains an OAuth2 access token for the specified scope using the application default
+// credentials, that is the credentials file pointed to by $GOOGLE_APPLICATION_CREDENTIALS, the one
+// created by "gcloud auth application-default login", or finally the metadata server.
+func googleToken(ctx context.Context, o *options, scope string) (string, error) {
+	file := platformEnv(o, "GOOGLE_APPLICATION_CREDENTIALS")
+
+	if len(file) == 0 {
+		if dir, err := os.UserConfigDir(); err == nil {
+			if f := filepath.Join(dir, "gcloud", "application_default_credentials.json"); fileExists(f) {
+				file = f
+			}
+		}
+	}
+
+	if len(file) == 0 {
+		return googleMetadataToken(ctx, o)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Google credentials: %v", err)
+	}
+
+	creds := &googleCredentials{}
+	if err = json.Unmarshal(data, creds); err != nil {
+		return "", fmt.Errorf("failed to parse Google credentials [%v]: %v", file, err)
+	}
+
+	form := url.Values{}
+
+	switch creds.Type {
+	case "service_account":
+		assertion, err := googleJWT(creds, scope)
+		if err != nil {
+			return "", err
+		}
+
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		form.Set("assertion", assertion)
+	case "authorized_user":
+		form.Set("grant_type", "refresh_token")
+		form.Set("client_id", creds.ClientID)
+		form.Set("client_secret", creds.ClientSecret)
+		form.Set("refresh_token", creds.RefreshToken)
+	default:
+		return "", fmt.Errorf("unsupported Google credentials type [%v]", creds.Type)
+	}
+
+	tokenURL := creds.TokenURI
+	if len(tokenURL) == 0 {
+		tokenURL = googleTokenURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return accessToken(o, req)
+}
+
+// googleMetadataToken obtains an access token of the default service account from the
+// metadata server available on GCE, GKE and Cloud Run.
+func googleMetadataToken(ctx context.Context, o *options) (string, error) {
+	host := platformEnv(o, "GCE_METADATA_HOST")
+	if len(host) == 0 {
+		host = "metadata.google.internal"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		"http://"+host+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	return accessToken(o, req)
+}
+
+// googleJWT creates a signed JWT assertion for the service account credentials requesting the scope.
+func googleJWT(creds *googleCredentials, scope string) (string, error) {
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return "", errors.New("invalid Google service account private key")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return "", fmt.Errorf("invalid Google service account private key: %v", err)
+		}
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("private key of the Google service account must be an RSA key")
+	}
+
+	aud := creds.TokenURI
+	if len(aud) == 0 {
+		aud = googleTokenURL
+	}
+
+	now := time.Now()
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   creds.ClientEmail,
+		"scope": scope,
+		"aud":   aud,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// accessToken sends req to an OAuth2 token endpoint and returns the access token of the response. The
+// instance metadata services are requested directly, see metadataClient.
+func accessToken(o *options, req *http.Request) (string, error) {
+	body, err := doRequest(metadataClient(o, req.URL.Hostname()), req)
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain access token: %v", err)
+	}
+
+	res := &struct {
+		AccessToken string `json:"access_token"`
+	}{}
+
+	if err = json.Unmarshal(body, res); err != nil || len(res.AccessToken) == 0 {
+		return "", errors.New("failed to obtain access token: token endpoint returned no access token")
+	}
+
+	return res.AccessToken, nil
+}
+
+// fileExists reports whether a regular file exists at the specified path.
+func fileExists(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.Mode().IsRegular()
+}
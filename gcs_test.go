@@ -0,0 +1,150 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCliGCS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || len(r.FormValue("assertion")) == 0 {
+			http.Error(w, "invalid grant", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"access_token":"secret-token"}`))
+	})
+	mux.HandleFunc("/storage/v1/b/bucket/o/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.EscapedPath() != "/storage/v1/b/bucket/o/app%2Fconf.json" || r.URL.Query().Get("alt") != "media" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"id":3,"name":"${NAME}","online":true}`))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	creds, _ := json.Marshal(&googleCredentials{
+		Type:        "service_account",
+		ClientEmail: "config@project.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    srv.URL + "/token",
+	})
+
+	file := filepath.Join(t.TempDir(), "credentials.json")
+	if err = os.WriteFile(file, creds, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", file)
+	t.Setenv("TEST_NAME", "Alice")
+
+	// the storage endpoint is swapped for the test server through the transport.
+	client := &http.Client{Transport: rewriteTransport(srv.URL)}
+
+	cases := []struct {
+		uri      string
+		expected testConf
+		err      string
+	}{
+		{"gs://bucket/app/conf.json", testConf{ID: 3, Name: "Alice", Online: true}, ""},
+		{"gs://bucket/missing.json", testConf{}, "failed to fetch configuration from [gs://bucket/missing.json]: unexpected response status [404 Not Found]: 404 page not found"},
+		{"gs://bucket", testConf{}, "failed to fetch configuration from [gs://bucket]: URI must be in the form gs://<bucket>/<object>"},
+		{"s3://bucket/conf.json", testConf{}, "unsupported configuration URI scheme [s3], supported schemes are: azappconfig, azkv, configmap, file, git, gs, http, https, nats, oci, redis, rediss, secret, spring, sql, txt, zk"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf, func(o *options) { o.httpClient = client })
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && err.Error() != c.err) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
+
+// rewriteTransport returns a transport sending every request to the server at base.
+func rewriteTransport(base string) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		u, _ := r.URL.Parse(base)
+		r.URL.Scheme, r.URL.Host = u.Scheme, u.Host
+		return http.DefaultTransport.RoundTrip(r)
+	})
+}
+
+// roundTripperFunc is an http.RoundTripper implemented by a function.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(r).
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestAccessTokenMetadata(t *testing.T) {
+	var proxied []string
+
+	o := &options{httpClient: &http.Client{Transport: &http.Transport{Proxy: func(r *http.Request) (*url.URL, error) {
+		proxied = append(proxied, r.URL.Host)
+		return nil, errors.New("proxied")
+	}}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	for _, uri := range []string{"http://169.254.169.254:9/metadata/identity/oauth2/token", "http://metadata.google.internal:9/token", "http://example.test/token"} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		accessToken(o, req)
+	}
+
+	// the instance metadata services are never requested through proxies.
+	if len(proxied) != 1 || proxied[0] != "example.test" {
+		t.Errorf("expected only [example.test] to be requested through proxies, but found: %v", proxied)
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// systemCABundles are the usual paths of the PEM bundles of the CA certificates trusted by the system.
+var systemCABundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",                // Debian, Ubuntu, Gentoo, Arch
+	"/etc/pki/tls/certs/ca-bundle.crt",                  // Fedora, RHEL 6
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem", // CentOS, RHEL 7
+	"/etc/ssl/ca-bundle.pem",                            // OpenSUSE
+	"/etc/ssl/cert.pem",                                 // Alpine, macOS, BSDs
+}
+
+// fetchGit reads a file from a git repository located by a git+<transport>://<repository>//<path>[?ref=<ref>]
+// URI, e.g. git+https://github.com/org/configs.git//services/api.json?ref=v1.2.0, where the ref is
+// a branch, a tag or a commit hash and defaults to the HEAD of the repository. Only the requested ref
+// is fetched, without history, using the git executable which has to be available in $PATH, so the
+// usual git credentials helpers and SSH agents apply.
+func fetchGit(ctx context.Context, o *options, u *url.URL) ([]byte, error) {
+	i := strings.Index(u.Path, "//")
+	if !strings.HasPrefix(u.Scheme, "git+") || i < 0 || i+2 == len(u.Path) {
+		return nil, errors.New("URI must be in the form git+<transport>://<repository>//<path>[?ref=<ref>]")
+	}
+
+	repo, file := *u, filepath.FromSlash(u.Path[i+2:])
+	repo.Scheme, repo.Path, repo.RawPath, repo.RawQuery = strings.TrimPrefix(u.Scheme, "git+"), u.Path[:i], "", ""
+
+	ref := u.Query().Get("ref")
+	if len(ref) == 0 {
+		ref = "HEAD"
+	}
+
+	// the repository and the ref must not be taken for options of git, e.g. --upload-pack=<command>.
+	for _, arg := range []string{repo.String(), ref} {
+		if strings.HasPrefix(arg, "-") {
+			return nil, fmt.Errorf("invalid git repository or ref [%v], it must not start with -", arg)
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "config-git-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+
+	if len(o.caFile) > 0 {
+		bundle, err := gitCABundle(o)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(bundle)
+
+		env = append(env, "GIT_SSL_CAINFO="+bundle)
+	}
+
+	if o.tlsConfig != nil && o.tlsConfig.InsecureSkipVerify {
+		env = append(env, "GIT_SSL_NO_VERIFY=true")
+	}
+
+	fetch := []string{"fetch", "--quiet", "--depth", "1"}
+
+	switch o.network {
+	case "tcp4":
+		fetch = append(fetch, "--ipv4")
+	case "tcp6":
+		fetch = append(fetch, "--ipv6")
+	}
+
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		append(fetch, "--end-of-options", repo.String(), ref),
+		{"checkout", "--quiet", "FETCH_HEAD"},
+	} {
+		if _, err = runCommand(ctx, "git", nil, env, append([]string{"-C", dir}, args...)...); err != nil {
+			return nil, err
+		}
+	}
+
+	if rel, err := filepath.Rel(dir, filepath.Join(dir, file)); err != nil || strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("path [%v] is outside of the repository", u.Path[i+2:])
+	}
+
+	return os.ReadFile(filepath.Join(dir, file))
+}
+
+// gitCABundle writes a temporary PEM bundle of the CA certificates of the CA file of o along with the ones
+// trusted by git otherwise, read from $GIT_SSL_CAINFO, $SSL_CERT_FILE or the bundle of the system, since
+// the CA file of git replaces them, and returns its path.
+func gitCABundle(o *options) (string, error) {
+	custom, err := os.ReadFile(o.caFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read CA certificates: %v", err)
+	}
+
+	var system []byte
+
+	for _, name := range append([]string{platformEnv(o, "GIT_SSL_CAINFO"), platformEnv(o, "SSL_CERT_FILE")}, systemCABundles...) {
+		if len(name) > 0 {
+			if system, err = os.ReadFile(name); err == nil {
+				break
+			}
+		}
+	}
+
+	f, err := os.CreateTemp("", "config-git-ca-*.pem")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = f.Write(append(append(system, '\n'), custom...))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
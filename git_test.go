@@ -0,0 +1,110 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCliGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	repo := t.TempDir()
+
+	git := func(args ...string) {
+		env := []string{"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com"}
+		if _, err := runCommand(context.Background(), "git", nil, env, append([]string{"-C", repo}, args...)...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write := func(name, content string) {
+		os.MkdirAll(filepath.Join(repo, filepath.Dir(name)), 0755)
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "--quiet")
+	write("services/api.json", `{"id":15,"name":"v1"}`)
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "v1")
+	git("tag", "v1")
+	write("services/api.json", `{"id":16,"name":"v2","online":true}`)
+	git("commit", "--quiet", "-am", "v2")
+
+	base := "git+file://" + filepath.ToSlash(repo)
+
+	cases := []struct {
+		uri      string
+		expected testConf
+		err      string
+	}{
+		{base + "//services/api.json", testConf{ID: 16, Name: "v2", Online: true}, ""},
+		{base + "//services/api.json?ref=v1", testConf{ID: 15, Name: "v1"}, ""},
+		{base + "//services/../../etc/passwd", testConf{}, "path [services/../../etc/passwd] is outside of the repository"},
+		{base + "//services/missing.json", testConf{}, "no such file or directory"},
+		{base + "//services/api.json?ref=missing", testConf{}, "git failed: exit status"},
+		{base + "//services/api.json?ref=--upload-pack=touch%20" + filepath.ToSlash(filepath.Join(repo, "pwned")), testConf{}, "invalid git repository or ref [--upload-pack=touch "},
+		{base, testConf{}, "URI must be in the form git+<transport>://<repository>//<path>[?ref=<ref>]"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(repo, "pwned")); err == nil {
+		t.Error("expected the ref not to be taken for an option of git")
+	}
+}
+
+func TestGitCABundle(t *testing.T) {
+	dir := t.TempDir()
+
+	system, custom := filepath.Join(dir, "system.pem"), filepath.Join(dir, "custom.pem")
+	os.WriteFile(system, []byte("SYSTEM CERTIFICATES"), 0600)
+	os.WriteFile(custom, []byte("CUSTOM CERTIFICATES\n"), 0600)
+
+	o := newOptions([]Option{WithEnvironment(map[string]string{"SSL_CERT_FILE": system})})
+	o.caFile = custom
+
+	bundle, err := gitCABundle(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(bundle)
+
+	// the CA file is trusted in addition to the CA certificates of the system.
+	if data, _ := os.ReadFile(bundle); string(data) != "SYSTEM CERTIFICATES\nCUSTOM CERTIFICATES\n" {
+		t.Errorf("expected the bundle to hold the system and custom CA certificates, but found: %q", data)
+	}
+}
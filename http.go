@@ -0,0 +1,162 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// formatMediaTypes maps the supported configuration formats to the media types negotiated with HTTP servers.
+var formatMediaTypes = map[string]string{
+	"cue":     "application/cue",
+	"dhall":   "application/dhall",
+	"json":    "application/json",
+	"jsonnet": "application/jsonnet",
+	"star":    "application/starlark",
+}
+
+// httpSource downloads the configuration document located by an http:// or https:// URI.
+type httpSource struct {
+	o      *options
+	u      *url.URL
+	format string
+}
+
+// newHTTPSource creates the source of the document located by u.
+func newHTTPSource(o *options, u *url.URL) (Source, error) {
+	return &httpSource{o: o, u: u}, nil
+}
+
+// Name returns the URI of the document without its credentials.
+func (s *httpSource) Name() string {
+	return s.u.Redacted()
+}
+
+// Format returns the format of the document matching the Content-Type of the response, if any.
+func (s *httpSource) Format() string {
+	return s.format
+}
+
+// Load downloads the document, authenticated over mTLS with the SVID of the workload when SPIFFE
+// is enabled, and with a bearer token obtained with the OAuth2 client credentials flow when configured,
+// which requires https.
+// The representation is negotiated with the server, which is told the supported formats, preferring
+// JSON, and may compress the response with gzip or zstd.
+func (s *httpSource) Load(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", acceptHeader(s.o))
+	req.Header.Set("Accept-Encoding", "zstd, gzip")
+
+	token, err := oauthClientToken(ctx, s.o, s.u)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s.o.httpClient
+	if s.o.spiffeClient != nil {
+		client = s.o.spiffeClient
+	}
+
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+		client = httpsOnly(client)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := decodeBody(res)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected response status [%v]: %v", res.Status, strings.TrimSpace(string(body)))
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(res.Header.Get("Content-Type")); err == nil {
+		for format, t := range formatMediaTypes {
+			if mediaType == t {
+				s.format = format
+			}
+		}
+
+		if strings.HasSuffix(mediaType, "+json") {
+			s.format = "json"
+		}
+	}
+
+	return body, nil
+}
+
+// acceptHeader returns the value of the Accept header listing the media types of the enabled formats.
+func acceptHeader(o *options) string {
+	types := make([]string, 0, len(formatMediaTypes))
+
+	for format, t := range formatMediaTypes {
+		if format != "json" && (format != "star" || o.starlark) {
+			types = append(types, t+";q=0.9")
+		}
+	}
+
+	sort.Strings(types)
+
+	return strings.Join(append(append([]string{formatMediaTypes["json"]}, types...), "*/*;q=0.1"), ", ")
+}
+
+// decodeBody reads the body of res, decompressing it according to its Content-Encoding.
+func decodeBody(res *http.Response) ([]byte, error) {
+	switch encoding := strings.ToLower(strings.TrimSpace(res.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return io.ReadAll(res.Body)
+	case "gzip":
+		r, err := gzip.NewReader(res.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip response: %v", err)
+		}
+		defer r.Close()
+
+		return io.ReadAll(r)
+	case "zstd":
+		r, err := zstd.NewReader(res.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd response: %v", err)
+		}
+		defer r.Close()
+
+		return io.ReadAll(r)
+	default:
+		return nil, fmt.Errorf("unsupported response content encoding [%v]", encoding)
+	}
+}
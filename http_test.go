@@ -0,0 +1,270 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCliHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api.cue" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("id: 21\nname: \"${NAME}\""))
+	}))
+	defer srv.Close()
+
+	t.Setenv("TEST_NAME", "Mallory")
+	t.Setenv("SPIFFE_ENDPOINT_SOCKET", "unix:///nonexistent/agent.sock")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	cases := []struct {
+		uri      string
+		opts     []Option
+		expected testConf
+		err      string
+	}{
+		{srv.URL + "/api.cue", nil, testConf{ID: 21, Name: "Mallory"}, ""},
+		{srv.URL + "/missing.json", nil, testConf{}, "unexpected response status [404 Not Found]"},
+		{srv.URL + "/api.cue", []Option{WithSPIFFE("not a SPIFFE ID")}, testConf{}, "invalid SPIFFE ID [not a SPIFFE ID]"},
+		{srv.URL + "/api.cue", []Option{WithContext(ctx), WithSPIFFE()}, testConf{}, "failed to obtain SVID from the SPIFFE Workload API"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf, c.opts...)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
+
+func TestCliHTTPOAuth(t *testing.T) {
+	var issued int
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if id, secret, _ := r.BasicAuth(); id != "api" || secret != "s3cr3t" || r.FormValue("grant_type") != "client_credentials" || !strings.HasPrefix(r.FormValue("scope"), "config.read") {
+				http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+				return
+			}
+			issued++
+			w.Write([]byte(`{"access_token":"token-` + r.URL.Query().Get("ttl") + `","expires_in":` + r.URL.Query().Get("ttl") + `}`))
+		case "/api.json":
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token-") {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"id":22}`))
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+
+	secretFile := dir + "/secret"
+	if err := os.WriteFile(secretFile, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	caFile := dir + "/ca.pem"
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("OAUTH_CA_FILE", caFile)
+	t.Setenv("OAUTH_OAUTH_CLIENT_ID", "api")
+	t.Setenv("OAUTH_OAUTH_CLIENT_SECRET_FILE", secretFile)
+
+	cases := []struct {
+		ttl    string
+		scopes string
+		issued int
+	}{
+		// long lived tokens are reused, while tokens about to expire are refreshed.
+		{"3600", "config.read", 1}, {"3600", "config.read", 1}, {"10", "config.read", 2}, {"10", "config.read", 3},
+		// tokens are only reused for the same scopes, regardless of their order.
+		{"3600", "config.read config.write", 4}, {"3600", "config.write  config.read", 4}, {"3600", "config.read", 4},
+	}
+
+	for _, c := range cases {
+		t.Setenv("OAUTH_OAUTH_TOKEN_URL", srv.URL+"/token?ttl="+c.ttl)
+		t.Setenv("OAUTH_OAUTH_SCOPES", c.scopes)
+
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", srv.URL + "/api.json"}}, func(in *input) (string, error) {
+			return Parse("OAUTH", "", nil, conf)
+		})
+
+		if err != nil || conf.ID != 22 || issued != c.issued {
+			t.Errorf("expected output: (22, <nil>, %v tokens issued), but found: (%v, %v, %v tokens issued)", c.issued, conf.ID, err, issued)
+		}
+	}
+
+	t.Setenv("OAUTH_OAUTH_CLIENT_SECRET_FILE", "")
+
+	_, err := withMockedArgs(&input{args: []string{"", "-config-uri", srv.URL + "/api.json"}}, func(in *input) (string, error) {
+		return Parse("OAUTH", "", nil, &testConf{})
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "OAuth2 client credentials are missing") {
+		t.Errorf("expected error: OAuth2 client credentials are missing, but found: %v", err)
+	}
+}
+
+func TestCliHTTPOAuthPlainHTTP(t *testing.T) {
+	var issued, leaked int
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			issued++
+			w.Write([]byte(`{"access_token":"token","expires_in":3600}`))
+		case r.TLS == nil && len(r.Header.Get("Authorization")) > 0:
+			leaked++
+		default:
+			w.Write([]byte(`{"id":23}`))
+		}
+	}
+
+	plain := httptest.NewServer(http.HandlerFunc(handler))
+	defer plain.Close()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, plain.URL+"/api.json", http.StatusFound)
+			return
+		}
+		handler(w, r)
+	}))
+	defer srv.Close()
+
+	caFile := t.TempDir() + "/ca.pem"
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PLAIN_CA_FILE", caFile)
+	t.Setenv("PLAIN_OAUTH_CLIENT_ID", "api")
+	t.Setenv("PLAIN_OAUTH_CLIENT_SECRET", "s3cr3t")
+
+	cases := []struct {
+		tokenURL, uri string
+		issued        int
+		err           string
+	}{
+		{srv.URL + "/token", plain.URL + "/api.json", 0, "refusing to send OAuth2 access token to [" + plain.URL + "/api.json], https is required"},
+		{plain.URL + "/token", srv.URL + "/api.json", 0, "invalid OAuth2 token URL [" + plain.URL + "/token], https is required"},
+		{srv.URL + "/token", srv.URL + "/redirect", 1, "refusing to follow redirect to [" + plain.URL + "/api.json] with OAuth2 credentials, https is required"},
+		{srv.URL + "/token", srv.URL + "/api.json", 1, ""},
+	}
+
+	for _, c := range cases {
+		t.Setenv("PLAIN_OAUTH_TOKEN_URL", c.tokenURL)
+
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("PLAIN", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || issued != c.issued || leaked > 0 {
+			t.Errorf("expected output: (%v, %v tokens issued), but found: (%v, %v tokens issued, %v leaked)", c.err, c.issued, err, issued, leaked)
+		}
+	}
+}
+
+func TestCliHTTPNegotiation(t *testing.T) {
+	const accept = "application/json, application/cue;q=0.9, application/dhall;q=0.9, application/jsonnet;q=0.9, */*;q=0.1"
+
+	var gzipped, zstded bytes.Buffer
+
+	gw := gzip.NewWriter(&gzipped)
+	gw.Write([]byte(`{"id": 32, "name": "gzip"}`))
+	gw.Close()
+
+	zw, _ := zstd.NewWriter(&zstded)
+	zw.Write([]byte(`{"id": 33, "name": "zstd"}`))
+	zw.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != accept || r.Header.Get("Accept-Encoding") != "zstd, gzip" {
+			http.Error(w, "not acceptable: "+r.Header.Get("Accept"), http.StatusNotAcceptable)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipped.Bytes())
+		case "/zstd":
+			w.Header().Set("Content-Encoding", "zstd")
+			w.Write(zstded.Bytes())
+		case "/cue":
+			w.Header().Set("Content-Type", "application/cue; charset=utf-8")
+			w.Write([]byte("id: 34\nname: \"cue\""))
+		case "/br":
+			w.Header().Set("Content-Encoding", "br")
+			w.Write([]byte("compressed"))
+		}
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		uri      string
+		expected testConf
+		err      string
+	}{
+		{srv.URL + "/gzip", testConf{ID: 32, Name: "gzip"}, ""},
+		{srv.URL + "/zstd", testConf{ID: 33, Name: "zstd"}, ""},
+		{srv.URL + "/cue", testConf{ID: 34, Name: "cue"}, ""},
+		{srv.URL + "/br", testConf{}, "unsupported response content encoding [br]"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
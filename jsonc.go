@@ -0,0 +1,91 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+// stripJSONC turns a JSON document that may contain line comments (// ...),
+// block comments (/* ... */) and trailing commas into a strict JSON document
+// that encoding/json accepts.
+//
+// Comments and trailing commas are replaced by spaces rather than removed,
+// so that offsets reported by the JSON decoder still point at the right
+// place of the original document. Content inside string literals is never touched.
+func stripJSONC(src []byte) []byte {
+	out := make([]byte, len(src))
+	copy(out, src)
+
+	// first pass blanks out the comments.
+	for i, inString := 0, false; i < len(out); i++ {
+		switch c := out[i]; {
+		case inString:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			out[i], out[i+1] = ' ', ' '
+			for i += 2; i < len(out); i++ {
+				if out[i] == '*' && i+1 < len(out) && out[i+1] == '/' {
+					out[i], out[i+1] = ' ', ' '
+					i++
+					break
+				}
+
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+			}
+		}
+	}
+
+	// second pass blanks out commas that are only followed by whitespaces
+	// and then a closing brace or bracket.
+	for i, inString := 0, false; i < len(out); i++ {
+		switch c := out[i]; {
+		case inString:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == ',':
+			j := i + 1
+			for j < len(out) && isJSONSpace(out[j]) {
+				j++
+			}
+
+			if j < len(out) && (out[j] == '}' || out[j] == ']') {
+				out[i] = ' '
+			}
+		}
+	}
+
+	return out
+}
+
+// isJSONSpace reports whether c is one of the insignificant whitespaces of JSON.
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
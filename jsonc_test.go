@@ -0,0 +1,72 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStripJSONC(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected string
+	}{
+		{`{"id":1}`, `{"id":1}`},
+		{"{\"id\":1 // the id\n}", "{\"id\":1          \n}"},
+		{`{"id":1 /* the id */}`, `{"id":1             }`},
+		{"{/* multi\nline */\"id\":1}", "{        \n       \"id\":1}"},
+		{`{"id":1,}`, `{"id":1 }`},
+		{`{"ids":[1,2, ],}`, `{"ids":[1,2  ] }`},
+		{`{"url":"http://host/*x*/",}`, `{"url":"http://host/*x*/" }`},
+		{`{"name":"a,}\"//b"}`, `{"name":"a,}\"//b"}`},
+	}
+
+	for _, c := range cases {
+		if res := string(stripJSONC([]byte(c.in))); res != c.expected {
+			t.Errorf("expected output: %q, but found: %q", c.expected, res)
+		}
+	}
+}
+
+func TestCliConfigJSONC(t *testing.T) {
+	c := &testConf{}
+
+	i := &input{
+		prefix: "TEST",
+		conf:   c,
+		args: []string{"", "-config", `{
+			// the identifier.
+			"id": 1,
+			/* "name": "${NAME}", */
+			"online": true,
+		}`},
+	}
+
+	res, err := withMockedArgs(i, func(in *input) (string, error) {
+		return Parse(in.prefix, in.description, in.info, in.conf)
+	})
+
+	if res != "" || err != nil {
+		t.Errorf("expected output: (\"\", nil), but found: (%v, %v)", res, err)
+	}
+
+	if c.ID != 1 || c.Name != "" || !c.Online {
+		j, _ := json.Marshal(c)
+		t.Errorf("expected output: %v, but found: %v", `{"id":1,"name":"","online":true}`, string(j))
+	}
+}
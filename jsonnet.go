@@ -0,0 +1,76 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// evalJsonnet evaluates doc as a Jsonnet entrypoint into a JSON document using the jsonnet
+// executable, which has to be available in $PATH. Every environment variable carrying the
+// application prefix is made available as an external variable named without the prefix,
+// e.g. $<envVarPrefix>_REGION is read with std.extVar("REGION"). Values are passed through
+// private files rather than the arguments of the evaluator to keep secrets out of process listings,
+// and rather than its environment to keep them from overriding its variables, e.g. $<envVarPrefix>_PATH.
+func evalJsonnet(o *options, doc []byte) ([]byte, error) {
+	args := []string{"-"}
+
+	for _, dir := range o.jsonnetPaths {
+		args = append(args, "--jpath", dir)
+	}
+
+	if len(o.envVarPrefix) > 0 {
+		dir, err := os.MkdirTemp("", "config-jsonnet-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		// variables of .env files are seen as well, unless the environment defines them.
+		vars := environ(o)
+		for key, val := range o.dotEnv {
+			if _, found := lookupProcessEnv(o, key); !found {
+				vars = append(vars, key+"="+val)
+			}
+		}
+
+		for i, kv := range vars {
+			key, val, _ := strings.Cut(kv, "=")
+
+			prefixed := strings.HasPrefix(key, o.envVarPrefix)
+			if o.caseInsensitiveEnv {
+				prefixed = strings.HasPrefix(strings.ToUpper(key), o.envVarPrefix)
+			}
+
+			if !prefixed || len(key) == len(o.envVarPrefix) {
+				continue
+			}
+
+			file := filepath.Join(dir, strconv.Itoa(i))
+			if err = os.WriteFile(file, []byte(val), 0600); err != nil {
+				return nil, err
+			}
+
+			args = append(args, "--ext-str-file", key[len(o.envVarPrefix):]+"="+file)
+		}
+	}
+
+	return runCommand(o.ctx, "jsonnet", doc, nil, args...)
+}
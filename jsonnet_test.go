@@ -0,0 +1,48 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "testing"
+
+func TestCliJsonnet(t *testing.T) {
+	// the fake evaluator only succeeds when called with the expected arguments and environment.
+	withFakeCommand(t, "jsonnet", `cat >/dev/null
+if [ -n "$NAME" ]; then echo "unexpected environment" >&2; exit 1; fi
+case "$1 $2 $3 $4 $6 ${5%%=*} ${7%%=*}" in
+  "- --jpath lib --ext-str-file --ext-str-file NAME PATH"|"- --jpath lib --ext-str-file --ext-str-file PATH NAME") ;;
+  *) echo "unexpected arguments: $*" >&2; exit 1 ;;
+esac
+for arg in "$5" "$7"; do
+  case "$arg" in NAME=*) name=$(cat "${arg#NAME=}") ;; esac
+done
+printf '{"id":8,"name":"%s"}' "$name"
+`)
+
+	t.Setenv("JSONNET_NAME", "Erin")
+	// the variables of the evaluator, e.g. $PATH, are not overridden.
+	t.Setenv("JSONNET_PATH", "/nonexistent")
+
+	conf := &testConf{}
+
+	_, err := withMockedArgs(&input{args: []string{"", "-config-format", "jsonnet", "-config", `{id: 8, name: std.extVar("NAME")}`}}, func(in *input) (string, error) {
+		return Parse("JSONNET", "", nil, conf, WithJsonnetImportPaths("lib"))
+	})
+
+	if err != nil || *conf != (testConf{ID: 8, Name: "Erin"}) {
+		t.Errorf("expected output: ({ID:8 Name:Erin Online:false}, <nil>), but found: (%+v, %v)", *conf, err)
+	}
+}
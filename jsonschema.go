@@ -0,0 +1,117 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// validateJSONSchema checks the JSON document doc against the JSON schema set by WithJSONSchema or
+// WithJSONSchemaFile if any, and fails listing every violation along with the dotted JSON path of the
+// value it is about, e.g. [server.port] minimum: got 0, want 1.
+func validateJSONSchema(o *options, doc []byte) error {
+	schema := []byte(o.jsonSchema)
+
+	if len(o.jsonSchemaFile) > 0 {
+		var err error
+		if schema, err = os.ReadFile(o.jsonSchemaFile); err != nil {
+			return fmt.Errorf("failed to read the JSON schema [%v]: %v", o.jsonSchemaFile, err)
+		}
+	}
+
+	if len(schema) == 0 {
+		return nil
+	}
+
+	defer track(o, "validate")()
+
+	parsed, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return fmt.Errorf("invalid JSON schema: %v", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err = compiler.AddResource("schema.json", parsed); err != nil {
+		return fmt.Errorf("invalid JSON schema: %v", err)
+	}
+
+	compiled, err := compiler.Compile("schema.json")
+	if err != nil {
+		return fmt.Errorf("invalid JSON schema: %v", err)
+	}
+
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(doc))
+	if err != nil {
+		return err
+	}
+
+	var invalid *jsonschema.ValidationError
+	if err = compiled.Validate(instance); !errors.As(err, &invalid) {
+		return err
+	}
+
+	var violations []string
+
+	for _, unit := range invalid.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+
+		violations = append(violations, fmt.Sprintf("[%v] %v", pointerPath(unit.InstanceLocation), unit.Error))
+	}
+
+	// the violations are found in no particular order.
+	sort.Strings(violations)
+
+	return fmt.Errorf("the configuration does not satisfy the JSON schema: %v", strings.Join(violations, ", "))
+}
+
+// pointerPath returns the dotted JSON path located by the JSON pointer ptr, e.g. servers[0].port for
+// /servers/0/port, where numeric tokens are taken for the indexes of arrays.
+func pointerPath(ptr string) string {
+	var b strings.Builder
+
+	for _, token := range strings.Split(strings.TrimPrefix(ptr, "/"), "/") {
+		if len(token) == 0 {
+			continue
+		}
+
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+
+		switch {
+		case strings.Trim(token, "0123456789") == "":
+			b.WriteString("[" + token + "]")
+		case b.Len() > 0:
+			b.WriteString("." + token)
+		default:
+			b.WriteString(token)
+		}
+	}
+
+	if b.Len() == 0 {
+		return "."
+	}
+
+	return b.String()
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testJSONSchema = `{
+  "type": "object",
+  "properties": {
+    "id": {"type": "integer", "minimum": 1},
+    "name": {"type": "string", "pattern": "^[a-z]+$"},
+    "tags": {"type": "array", "items": {"type": "string"}}
+  },
+  "required": ["id"]
+}`
+
+func TestCliJSONSchema(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "schema.json")
+
+	if err := os.WriteFile(file, []byte(testJSONSchema), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		config string
+		opts   []Option
+		err    string
+	}{
+		{`{"id": 1, "name": "${NAME}"}`, []Option{WithJSONSchema(testJSONSchema)}, ""},
+		{`{"id": 0, "name": "Bob"}`, []Option{WithJSONSchema(testJSONSchema)}, "the configuration does not satisfy the JSON schema: [id] minimum: got 0, want 1, [name] '"},
+		{`{"name": "app", "tags": ["a", 1]}`, []Option{WithJSONSchemaFile(file)}, "[.] missing property 'id', [tags[1]] got number, want string"},
+		{`{"id": 1}`, []Option{WithJSONSchemaFile(filepath.Join(dir, "missing.json"))}, "failed to read the JSON schema"},
+		{`{"id": 1}`, []Option{WithJSONSchema(`{"type": 1}`)}, "invalid JSON schema"},
+		{`{"id": 1}`, []Option{WithJSONSchema(`{`)}, "invalid JSON schema"},
+	}
+
+	for _, c := range cases {
+		_, err := Parse("JS", "", nil, &testConf{}, append(c.opts, WithEnvironment(map[string]string{"JS_NAME": "app"}), WithArgs("", "-config", c.config))...)
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) {
+			t.Errorf("expected error: %v, but found: %v", c.err, err)
+		}
+	}
+
+	if expected := "servers[0].a/b"; pointerPath("/servers/0/a~1b") != expected {
+		t.Errorf("expected path: %v, but found: %v", expected, pointerPath("/servers/0/a~1b"))
+	}
+}
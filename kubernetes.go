@@ -0,0 +1,187 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// serviceAccountDir is the directory where Kubernetes mounts the credentials of the pod service account.
+var serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// fetchConfigMap reads a ConfigMap through the Kubernetes API of the cluster the process runs in,
+// located by a configmap://[<namespace>]/<name>[/<key>] URI. The namespace defaults to the one
+// of the pod, and if a key is specified its value is the configuration document, otherwise the
+// whole data of the ConfigMap is returned as a JSON object.
+func fetchConfigMap(ctx context.Context, o *options, u *url.URL) ([]byte, error) {
+	namespace, name, key, err := kubeObjectRef(u)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := kubeGet(ctx, o, fmt.Sprintf("/api/v1/namespaces/%v/configmaps/%v", url.PathEscape(namespace), url.PathEscape(name)))
+	if err != nil {
+		return nil, err
+	}
+
+	cm := &struct {
+		Data map[string]string `json:"data"`
+	}{}
+
+	if err = json.Unmarshal(body, cm); err != nil {
+		return nil, fmt.Errorf("invalid ConfigMap: %v", err)
+	}
+
+	if len(key) == 0 {
+		return json.Marshal(cm.Data)
+	}
+
+	val, found := cm.Data[key]
+	if !found {
+		return nil, fmt.Errorf("ConfigMap [%v/%v] has no key [%v]", namespace, name, key)
+	}
+
+	return []byte(val), nil
+}
+
+// fetchSecret reads a Secret through the Kubernetes API of the cluster the process runs in, located
+// by a secret://[<namespace>]/<name>[/<key>] URI. The namespace defaults to the one of the pod, and if
+// a key is specified its decoded value is the configuration document, otherwise the whole decoded data
+// of the Secret is returned as a JSON object to be merged with the rest of the configuration, e.g.
+//
+//	--config-uri "configmap:///app/config.json secret:///app-credentials"
+//
+// Errors never carry the values of the Secret.
+func fetchSecret(ctx context.Context, o *options, u *url.URL) ([]byte, error) {
+	namespace, name, key, err := kubeObjectRef(u)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := kubeGet(ctx, o, fmt.Sprintf("/api/v1/namespaces/%v/secrets/%v", url.PathEscape(namespace), url.PathEscape(name)))
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &struct {
+		Data map[string]string `json:"data"`
+	}{}
+
+	if err = json.Unmarshal(body, secret); err != nil {
+		return nil, errors.New("invalid Secret")
+	}
+
+	data := make(map[string]string, len(secret.Data))
+
+	for k, v := range secret.Data {
+		val, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("Secret [%v/%v] key [%v] is not base64 encoded", namespace, name, k)
+		}
+
+		data[k] = string(val)
+	}
+
+	if len(key) == 0 {
+		return json.Marshal(data)
+	}
+
+	val, found := data[key]
+	if !found {
+		return nil, fmt.Errorf("Secret [%v/%v] has no key [%v]", namespace, name, key)
+	}
+
+	return []byte(val), nil
+}
+
+// kubeObjectRef splits a <scheme>://[<namespace>]/<name>[/<key>] URI into its parts,
+// defaulting the namespace to the one of the pod.
+func kubeObjectRef(u *url.URL) (namespace, name, key string, err error) {
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+
+	if namespace, name = u.Host, parts[0]; len(name) == 0 {
+		return "", "", "", fmt.Errorf("URI must be in the form %v://[<namespace>]/<name>[/<key>]", u.Scheme)
+	}
+
+	if len(parts) > 1 {
+		key = parts[1]
+	}
+
+	if len(namespace) == 0 {
+		data, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+		if err != nil {
+			return "", "", "", fmt.Errorf("failed to determine the namespace of the pod: %v", err)
+		}
+
+		namespace = strings.TrimSpace(string(data))
+	}
+
+	return
+}
+
+// kubeGet requests the specified path from the Kubernetes API server of the cluster the process
+// runs in, authenticated with the token of the pod service account.
+func kubeGet(ctx context.Context, o *options, path string) ([]byte, error) {
+	host, port := platformEnv(o, "KUBERNETES_SERVICE_HOST"), platformEnv(o, "KUBERNETES_SERVICE_PORT")
+
+	if len(host) == 0 || len(port) == 0 {
+		return nil, errors.New("not running inside a Kubernetes cluster, $KUBERNETES_SERVICE_HOST and $KUBERNETES_SERVICE_PORT must be defined")
+	}
+
+	token, err := os.ReadFile(filepath.Join(serviceAccountDir, "token"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %v", err)
+	}
+
+	ca, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account CA certificate: %v", err)
+	}
+
+	// the API server is trusted by the CA of the cluster only, in a pool of its own since the pool of the
+	// shared TLS configuration is shared by the other sources as well.
+	config := newTLSConfig(o, "")
+	config.RootCAs = x509.NewCertPool()
+
+	if !config.RootCAs.AppendCertsFromPEM(ca) {
+		return nil, errors.New("invalid service account CA certificate")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+net.JoinHostPort(host, port)+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+
+	transport := newTransport(o)
+	transport.TLSClientConfig = config
+
+	return doRequest(&http.Client{Transport: transport}, req)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withFakeCluster starts a fake Kubernetes API server serving the specified handler, and points
+// the in-cluster configuration to it for the rest of the test.
+func withFakeCluster(t *testing.T, handler http.HandlerFunc) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer pod-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"token":     []byte("pod-token\n"),
+		"namespace": []byte("default"),
+		"ca.crt":    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}),
+	}
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u, _ := url.Parse(srv.URL)
+	host, port, _ := net.SplitHostPort(u.Host)
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", host)
+	t.Setenv("KUBERNETES_SERVICE_PORT", port)
+
+	dir, serviceAccountDir = serviceAccountDir, dir
+	t.Cleanup(func() { serviceAccountDir = dir })
+}
+
+func TestCliConfigMap(t *testing.T) {
+	withFakeCluster(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/namespaces/default/configmaps/app":
+			w.Write([]byte(`{"data":{"config.json":"{\"id\":9,\"name\":\"Frank\"}","name":"Grace"}}`))
+		case "/api/v1/namespaces/other/configmaps/app":
+			w.Write([]byte(`{"data":{"config.cue":"id: 10\nonline: true"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	cases := []struct {
+		uri      string
+		expected testConf
+		err      string
+	}{
+		{"configmap:///app/config.json", testConf{ID: 9, Name: "Frank"}, ""},
+		{"configmap://other/app/config.cue", testConf{ID: 10, Online: true}, ""},
+		{"configmap:///app", testConf{Name: "Grace"}, ""},
+		{"configmap:///app/missing", testConf{}, "failed to fetch configuration from [configmap:///app/missing]: ConfigMap [default/app] has no key [missing]"},
+		{"configmap:///", testConf{}, "failed to fetch configuration from [configmap:///]: URI must be in the form configmap://[<namespace>]/<name>[/<key>]"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && err.Error() != c.err) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
+
+func TestCliSecret(t *testing.T) {
+	withFakeCluster(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/namespaces/default/configmaps/app":
+			w.Write([]byte(`{"data":{"config.json":"{\"id\":13,\"name\":\"public\",\"online\":true}"}}`))
+		case "/api/v1/namespaces/default/secrets/app":
+			// {"name": "Judy"} and {"id": 14}.
+			w.Write([]byte(`{"data":{"name":"SnVkeQ==","config.json":"eyJpZCI6IDE0fQ=="}}`))
+		case "/api/v1/namespaces/default/secrets/broken":
+			w.Write([]byte(`{"data":{"name":"not base64!"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	cases := []struct {
+		uri      string
+		expected testConf
+		err      string
+	}{
+		{"configmap:///app/config.json secret:///app", testConf{ID: 13, Name: "Judy", Online: true}, ""},
+		{"secret:///app/config.json", testConf{ID: 14}, ""},
+		{"secret:///broken", testConf{}, "failed to fetch configuration from [secret:///broken]: Secret [default/broken] key [name] is not base64 encoded"},
+		{"secret:///app/missing", testConf{}, "failed to fetch configuration from [secret:///app/missing]: Secret [default/app] has no key [missing]"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && err.Error() != c.err) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
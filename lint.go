@@ -0,0 +1,208 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	// lintModes are the supported lint modes, reporting the findings as warnings or failing on them.
+	lintModes = []string{"error", "warn"}
+
+	// requiredSuffixes are the suffixes of the names of the keys that look required, e.g. db_password,
+	// which are reported when their values are empty.
+	requiredSuffixes = []string{"addr", "address", "dsn", "endpoint", "host", "password", "secret", "token", "uri", "url"}
+
+	// urlSuffixes are the suffixes of the names of the keys holding URLs, e.g. apiUrl.
+	urlSuffixes = []string{"endpoint", "uri", "url"}
+)
+
+// lintFinding is a finding of linting a JSON document, located by the offsets of the token it is about.
+type lintFinding struct {
+	msg        string
+	start, end int64
+}
+
+// lint checks the JSON document doc for duplicate keys, empty values of keys that look required and
+// invalid URLs, and records the findings to be reported once the configuration is loaded.
+func lint(o *options, doc []byte) {
+	if len(o.lint) > 0 {
+		o.lintFindings = append(o.lintFindings, lintDocument(doc)...)
+	}
+}
+
+// lintDocument checks the JSON document doc and returns the findings, invalid documents are only
+// checked up to the point they are invalid, since they fail to decode anyway.
+func lintDocument(doc []byte) []lintFinding {
+	l := &linter{doc: doc, dec: json.NewDecoder(bytes.NewReader(doc))}
+	l.dec.UseNumber()
+
+	_ = l.value("")
+
+	return l.findings
+}
+
+// linter checks a JSON document while reading its tokens.
+type linter struct {
+	doc      []byte
+	dec      *json.Decoder
+	findings []lintFinding
+}
+
+// value checks the next value of the document, located at path.
+func (l *linter) value(path string) error {
+	tok, err := l.dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		seen := make(map[string]bool)
+
+		for l.dec.More() {
+			tok, err := l.dec.Token()
+			if err != nil {
+				return err
+			}
+
+			key, _ := tok.(string)
+			keyPath := key
+			if len(path) > 0 {
+				keyPath = path + "." + key
+			}
+
+			if seen[key] {
+				l.report(fmt.Sprintf("duplicate key [%v]", keyPath))
+			}
+			seen[key] = true
+
+			if err = l.value(keyPath); err != nil {
+				return err
+			}
+		}
+
+		_, err = l.dec.Token()
+
+		return err
+	case json.Delim('['):
+		for i := 0; l.dec.More(); i++ {
+			if err = l.value(fmt.Sprintf("%v[%v]", path, i)); err != nil {
+				return err
+			}
+		}
+
+		_, err = l.dec.Token()
+
+		return err
+	}
+
+	if s, ok := tok.(string); ok {
+		l.string(path, s)
+	}
+
+	return nil
+}
+
+// string checks the string value s of the key located at path.
+func (l *linter) string(path, s string) {
+	name := strings.ToLower(path[strings.LastIndex(path, ".")+1:])
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+
+	if len(strings.TrimSpace(s)) == 0 {
+		if hasSuffix(name, requiredSuffixes) {
+			l.report(fmt.Sprintf("empty value of key [%v], which looks required", path))
+		}
+		return
+	}
+
+	if hasSuffix(name, urlSuffixes) {
+		// URLs must be absolute, and the ones of hierarchical schemes e.g. https must have a host. Values are
+		// linted once placeholders are expanded, so they are never reported since they may hold secrets.
+		if u, err := url.Parse(s); err != nil || len(u.Scheme) == 0 || (strings.HasPrefix(u.Scheme, "http") && len(u.Host) == 0) {
+			l.report(fmt.Sprintf("invalid URL of key [%v]", path))
+		}
+	}
+}
+
+// report records a finding about the string token last read.
+func (l *linter) report(msg string) {
+	end := l.dec.InputOffset()
+
+	l.findings = append(l.findings, lintFinding{msg: msg, start: stringStart(l.doc, end), end: end})
+}
+
+// stringStart returns the offset of the opening quote of the string literal of doc ending at end.
+func stringStart(doc []byte, end int64) int64 {
+	for i := end - 2; i >= 0; i-- {
+		if doc[i] != '"' {
+			continue
+		}
+
+		// quotes preceded by an odd number of backslashes are escaped.
+		backslashes := int64(0)
+		for ; i-backslashes > 0 && doc[i-backslashes-1] == '\\'; backslashes++ {
+		}
+
+		if backslashes%2 == 0 {
+			return i
+		}
+	}
+
+	return 0
+}
+
+// hasSuffix reports whether name ends with any of suffixes.
+func hasSuffix(name string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reportLint reports the lint findings according to the lint mode, as warnings or as an error
+// listing every finding on its own line.
+func reportLint(o *options) error {
+	if len(o.lintFindings) == 0 {
+		return nil
+	}
+
+	findings := make([]string, len(o.lintFindings))
+	for i, finding := range o.lintFindings {
+		findings[i] = "lint: " + finding.msg
+	}
+
+	if o.lint == "warn" {
+		for _, finding := range findings {
+			o.logger.Printf("WARNING: %v", finding)
+		}
+		return nil
+	}
+
+	return errors.New(strings.Join(findings, "\n"))
+}
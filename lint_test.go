@@ -0,0 +1,62 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+func TestCliLint(t *testing.T) {
+	cases := []struct {
+		args     []string
+		expected testConf
+		err      string
+		warnings string
+	}{
+		{[]string{"", "-config", `{"id": 1, "id": 2}`}, testConf{ID: 2}, "", ""},
+		{[]string{"", "-lint", "warn", "-config", `{"id": 1, "name": "Judy"}`}, testConf{ID: 1, Name: "Judy"}, "", ""},
+		{[]string{"", "-lint", "warn", "-config", `{"id": 1, "id": 2}`}, testConf{ID: 2}, "", "WARNING: lint: duplicate key [id]\n"},
+		{[]string{"", "-lint", "error", "-config", `{"id": 1, "db": {"host": "", "dsn": " ", "url": "example.com", "api_url": "https://", "list": [{"id": 1, "id": 1}]}, "id": 2}`}, testConf{},
+			"lint: empty value of key [db.host], which looks required\n" +
+				"lint: empty value of key [db.dsn], which looks required\n" +
+				"lint: invalid URL of key [db.url]\n" +
+				"lint: invalid URL of key [db.api_url]\n" +
+				"lint: duplicate key [db.list[0].id]\n" +
+				"lint: duplicate key [id]", ""},
+		{[]string{"", "-lint", "error", "-config", `{"name": "", "endpoint": "https://example.com", "uri": "mailto:a@example.com"}`}, testConf{}, "", ""},
+		{[]string{"", "-lint", "warn", "-config", `{"id": 1, "url": "${URL}"}`}, testConf{ID: 1}, "", "WARNING: lint: invalid URL of key [url]\n"},
+		{[]string{"", "-lint", "always"}, testConf{}, "unsupported lint mode [always], supported modes are: error, warn", ""},
+	}
+
+	// values are never reported, since they may be secrets injected by placeholders.
+	t.Setenv("TEST_URL", "s3cr3t")
+
+	for _, c := range cases {
+		var logs bytes.Buffer
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: c.args}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf, WithLogger(log.New(&logs, "", 0)))
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && err.Error() != c.err) || *conf != c.expected || logs.String() != c.warnings {
+			t.Errorf("expected output: (%+v, %v, %q), but found: (%+v, %v, %q)", c.expected, c.err, c.warnings, *conf, err, logs.String())
+		}
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// EnvVars returns the names of the environment variables the configuration may be read from, the way Parse
+// reads it given the same command line arguments and environment, so that operators can audit which ones
+// to set: the ones read regardless of the configuration, e.g. $<envVarPrefix>_CONFIG, the ones bound to the
+// fields of conf and the ones of the placeholders of the configuration documents, which are fetched but not
+// decoded. The variables specific to the sources of the documents are not listed.
+func EnvVars(envVarPrefix string, conf interface{}, opts ...Option) ([]string, error) {
+	var names []string
+
+	out, err := Parse(envVarPrefix, "", nil, conf, append(opts, func(o *options) {
+		o.envVars = &names
+	})...)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the environment variables: %v", err)
+	}
+
+	if len(out) > 0 {
+		return nil, errors.New("failed to list the environment variables: the command line arguments do not load any configuration")
+	}
+
+	return names, nil
+}
+
+// envVarNames returns the names of the environment variables the configuration may be read from, the ones
+// read regardless of the configuration first, then the ones bound to the fields of the configuration
+// structure, then the ones of the placeholders of docs in order, each once.
+func envVarNames(o *options, docs ...string) []string {
+	var (
+		names []string
+		seen  = make(map[string]bool)
+	)
+
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, key := range envVarKeys {
+		add(o.envVarPrefix + key)
+	}
+
+	if o.confType != nil && o.confType.Kind() == reflect.Struct {
+		for _, binding := range envBindings(o, o.confType, "", "", make(map[reflect.Type]bool)) {
+			add(o.envVarPrefix + binding.key)
+		}
+	}
+
+	for _, doc := range docs {
+		// the placeholders of the namespaced sections of JSON documents are scoped.
+		if stripped := string(stripJSONC([]byte(doc))); scopePlaceholders(o, stripped) != stripped {
+			doc = scopePlaceholders(o, stripped)
+		}
+
+		for _, m := range placeHolderRegex.FindAllStringSubmatch(doc, -1) {
+			switch {
+			case isEscaped(m[0]):
+			case len(m[2]) > 0:
+				add(m[2])
+			default:
+				add(o.envVarPrefix + m[3])
+			}
+		}
+
+		if o.windowsPlaceholders {
+			for _, m := range windowsPlaceHolderRegex.FindAllStringSubmatch(doc, -1) {
+				if len(m[1]) > 0 {
+					add(m[1])
+				}
+			}
+		}
+	}
+
+	return names
+}
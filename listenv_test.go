@@ -0,0 +1,78 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type listEnvConf struct {
+	Name string `json:"name"`
+	Port int    `json:"port" env:"PORT"`
+}
+
+func TestCliListEnv(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "conf.json")
+
+	if err := os.WriteFile(file, []byte(`{"name": "${FILE_NAME} ${env:HOSTNAME}"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	core := func(names ...string) []string {
+		all := []string{}
+		for _, key := range envVarKeys {
+			all = append(all, "LE_"+key)
+		}
+		return append(all, names...)
+	}
+
+	cases := []struct {
+		env      map[string]string
+		args     []string
+		opts     []Option
+		expected []string
+		err      string
+	}{
+		{nil, nil, nil, core("LE_PORT"), ""},
+		{map[string]string{"LE_CONFIG": `{"name": "${ENV_NAME:-x} $${ESCAPED} ${PORT}"}`}, []string{"-config", `{"name": "${NAME}", "x": "%APPDATA%"}`}, []Option{WithWindowsPlaceholders()},
+			core("LE_PORT", "LE_NAME", "APPDATA", "LE_ENV_NAME"), ""},
+		{nil, []string{"-config-uri", "file://" + file}, []Option{WithAutoEnv()}, core("LE_NAME", "LE_PORT", "LE_FILE_NAME", "HOSTNAME"), ""},
+		{nil, []string{"-config-uri", "file://" + filepath.Join(dir, "missing.json")}, nil, nil, "failed to list the environment variables: failed to fetch configuration"},
+		{nil, []string{"-version"}, nil, nil, "the command line arguments do not load any configuration"},
+	}
+
+	for _, c := range cases {
+		conf := &listEnvConf{}
+
+		names, err := EnvVars("LE", conf, append(c.opts, WithEnvironment(c.env), WithArgs(append([]string{""}, c.args...)...))...)
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || !reflect.DeepEqual(names, c.expected) || *conf != (listEnvConf{}) {
+			t.Errorf("expected output: (%v, %v), but found: (%v, %v)", c.expected, c.err, names, err)
+		}
+	}
+
+	out, err := Parse("LE", "", nil, &listEnvConf{}, WithEnvironment(nil), WithArgs("", "-list-env", "-config", `{"name": "${NAME}"}`))
+
+	if expected := strings.Join(core("LE_PORT", "LE_NAME"), "\n") + "\n"; err != nil || out != expected {
+		t.Errorf("expected output: (%v, <nil>), but found: (%v, %v)", expected, out, err)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Migration upgrades the decoded configuration document doc from a version of its schema to the next one,
+// e.g. renaming or moving options, see WithMigration.
+type Migration func(doc map[string]interface{}) error
+
+// migrate upgrades the configuration document doc to the version of its schema set by WithSchemaVersion by
+// applying the migrations of WithMigration in order, before it is checked and decoded, so that long-lived
+// documents keep loading across releases. The version of the document is held by its top level option named
+// by WithSchemaVersion, documents lacking it being of version 1, and is set to the current version once
+// migrated. Documents of versions newer than the current one are rejected.
+func migrate(o *options, doc []byte) ([]byte, error) {
+	if len(o.schemaVersionKey) == 0 {
+		return doc, nil
+	}
+
+	defer track(o, "resolve")()
+
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+
+	var tree map[string]interface{}
+	if err := dec.Decode(&tree); err != nil || tree == nil {
+		return nil, fmt.Errorf("failed to migrate the configuration, a JSON object is expected: %v", err)
+	}
+
+	version := 1
+
+	if val, found := tree[o.schemaVersionKey]; found {
+		n, ok := val.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("invalid configuration version [%v] of option [%v], an integer is expected", val, o.schemaVersionKey)
+		}
+
+		v, err := strconv.Atoi(n.String())
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration version [%v] of option [%v], an integer is expected", val, o.schemaVersionKey)
+		}
+
+		version = v
+	}
+
+	switch {
+	case version > o.schemaVersion:
+		return nil, fmt.Errorf("the configuration version [%v] is newer than the supported one [%v], upgrade the application", version, o.schemaVersion)
+	case version == o.schemaVersion:
+		return doc, nil
+	}
+
+	for v := version; v < o.schemaVersion; v++ {
+		migration, found := o.migrations[v]
+		if !found {
+			return nil, fmt.Errorf("failed to migrate the configuration from version [%v] to [%v]: no migration", v, v+1)
+		}
+
+		if err := migration(tree); err != nil {
+			return nil, fmt.Errorf("failed to migrate the configuration from version [%v] to [%v]: %v", v, v+1, err)
+		}
+	}
+
+	tree[o.schemaVersionKey] = o.schemaVersion
+
+	o.logger.Printf("WARNING: the configuration of version [%v] was migrated to version [%v], update it to skip migrating it when loading", version, o.schemaVersion)
+
+	return json.Marshal(tree)
+}
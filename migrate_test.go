@@ -0,0 +1,106 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+)
+
+type migratedConf struct {
+	Version int `json:"version"`
+	Server  struct {
+		Port int `json:"port"`
+	} `json:"server"`
+	Timeout int `json:"timeout"`
+}
+
+func TestCliMigrations(t *testing.T) {
+	migrations := []Option{
+		WithSchemaVersion("version", 3),
+		// version 2 moved port into server.
+		WithMigration(1, func(doc map[string]interface{}) error {
+			if port, found := doc["port"]; found {
+				doc["server"] = map[string]interface{}{"port": port}
+				delete(doc, "port")
+			}
+			return nil
+		}),
+		// version 3 renamed timeout_ms into timeout.
+		WithMigration(2, func(doc map[string]interface{}) error {
+			if timeout, found := doc["timeout_ms"]; found {
+				doc["timeout"] = timeout
+				delete(doc, "timeout_ms")
+			}
+			return nil
+		}),
+	}
+
+	cases := []struct {
+		config   string
+		opts     []Option
+		expected migratedConf
+		warning  string
+		err      string
+	}{
+		{`{"port": 80, "timeout_ms": 500}`, migrations, migratedConf{3, struct {
+			Port int `json:"port"`
+		}{80}, 500}, "WARNING: the configuration of version [1] was migrated to version [3], update it to skip migrating it when loading\n", ""},
+		{`{"version": 2, "server": {"port": 80}, "timeout_ms": 500}`, migrations, migratedConf{3, struct {
+			Port int `json:"port"`
+		}{80}, 500}, "WARNING: the configuration of version [2] was migrated to version [3], update it to skip migrating it when loading\n", ""},
+		{`{"version": 3, "server": {"port": 80}, "timeout": 500}`, migrations, migratedConf{3, struct {
+			Port int `json:"port"`
+		}{80}, 500}, "", ""},
+		{`{"port": 80}`, nil, migratedConf{}, "", ""},
+		{`{"version": 4}`, migrations, migratedConf{}, "", "the configuration version [4] is newer than the supported one [3], upgrade the application"},
+		{`{"version": "2"}`, migrations, migratedConf{}, "", "invalid configuration version [2] of option [version], an integer is expected"},
+		{`{"version": 1.5}`, migrations, migratedConf{}, "", "invalid configuration version [1.5] of option [version], an integer is expected"},
+		{`{}`, append([]Option(nil), migrations[:2]...), migratedConf{}, "", "failed to migrate the configuration from version [2] to [3]: no migration"},
+		{`{}`, append([]Option{migrations[0], migrations[2]}, WithMigration(1, func(map[string]interface{}) error {
+			return errors.New("unsupported layout")
+		})), migratedConf{}, "", "failed to migrate the configuration from version [1] to [2]: unsupported layout"},
+	}
+
+	for _, c := range cases {
+		var (
+			logs bytes.Buffer
+			conf migratedConf
+		)
+
+		_, err := Parse("MIGRATE", "", nil, &conf, append(c.opts, WithEnvironment(nil), WithArgs("", "-config", c.config), WithLogger(log.New(&logs, "", 0)))...)
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || err.Error() != c.err)) {
+			t.Errorf("expected error: %v, but found: %v", c.err, err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if conf != c.expected {
+			t.Errorf("expected configuration: %+v, but found: %+v", c.expected, conf)
+		}
+
+		if logs.String() != c.warning {
+			t.Errorf("expected warning: %q, but found: %q", c.warning, logs.String())
+		}
+	}
+}
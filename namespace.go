@@ -0,0 +1,128 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// indexRegex matches the indexes of the elements of arrays in dotted JSON paths, e.g. [0] in tags[0].
+var indexRegex = regexp.MustCompile(`\[[0-9]+\]`)
+
+// envNamespace is the sub-prefix of the environment variables of the placeholders of the section of the
+// configuration located by its dotted JSON path.
+type envNamespace struct {
+	path   string
+	prefix string
+}
+
+// envNamespaces returns the namespaces declared by the fields of the structure t tagged with
+// `envprefix:"<name>"`, located at path, where prefix is the sub-prefix of the namespace t belongs to.
+// Namespaces are nested in the ones of the structures they belong to, e.g. DATABASE_PRIMARY_. The types of
+// the structures holding t are recorded in parents, see walkType.
+func envNamespaces(t reflect.Type, path, prefix string, parents map[reflect.Type]bool) []envNamespace {
+	var namespaces []envNamespace
+
+	_ = walkType(t, path, parents, func(_ reflect.Value, f reflect.StructField, path string) (bool, error) {
+		if !isNestedStruct(f.Type) {
+			return false, nil
+		}
+
+		sub := prefix
+		if tag := strings.Trim(f.Tag.Get("envprefix"), "_"); len(tag) > 0 {
+			sub = prefix + tag + "_"
+		}
+
+		if sub != prefix {
+			namespaces = append(namespaces, envNamespace{path, sub})
+		}
+
+		namespaces = append(namespaces, envNamespaces(f.Type, path, sub, parents)...)
+
+		return false, nil
+	})
+
+	return namespaces
+}
+
+// scopePlaceholders prefixes the names of the placeholders of environment variables of the JSON document
+// doc with the sub-prefixes of the namespaces of the sections they are part of, so that e.g. ${HOST} in the
+// database section declared with `envprefix:"DATABASE"` is resolved as $<envVarPrefix>_DATABASE_HOST.
+// Placeholders of raw environment variables, e.g. ${env:HOSTNAME}, are never scoped.
+func scopePlaceholders(o *options, doc string) string {
+	if o.confType == nil {
+		return doc
+	}
+
+	namespaces := envNamespaces(o.confType, "", "", make(map[reflect.Type]bool))
+	if len(namespaces) == 0 {
+		return doc
+	}
+
+	var (
+		b    strings.Builder
+		last int
+	)
+
+	locs, paths := placeholderPaths(doc)
+
+	for i, loc := range locs {
+		if loc[6] < 0 || len(paths[i]) == 0 {
+			continue
+		}
+
+		if prefix := namespaceOf(namespaces, paths[i][0]); len(prefix) > 0 {
+			b.WriteString(doc[last:loc[6]])
+			b.WriteString(prefix)
+			last = loc[6]
+		}
+	}
+
+	b.WriteString(doc[last:])
+
+	return b.String()
+}
+
+// namespaceOf returns the sub-prefix of the innermost namespace the value of the dotted JSON path belongs
+// to, if any. Keys match the names of fields regardless of their case, as when decoding.
+func namespaceOf(namespaces []envNamespace, path string) string {
+	var (
+		keys   = strings.Split(indexRegex.ReplaceAllString(path, ""), ".")
+		prefix string
+		depth  int
+	)
+
+	for _, ns := range namespaces {
+		nsKeys := strings.Split(ns.path, ".")
+		if len(nsKeys) <= depth || len(nsKeys) > len(keys) {
+			continue
+		}
+
+		matches := true
+		for i, key := range nsKeys {
+			matches = matches && strings.EqualFold(key, keys[i])
+		}
+
+		if matches {
+			prefix, depth = ns.prefix, len(nsKeys)
+		}
+	}
+
+	return prefix
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+type namespaceConf struct {
+	Name     string `json:"name"`
+	Database struct {
+		Host    string `json:"host"`
+		Replica *struct {
+			Host string `json:"host"`
+		} `json:"replica" envprefix:"REPLICA"`
+		Tags []string `json:"tags"`
+	} `json:"database" envprefix:"DATABASE"`
+	Cache struct {
+		Host string `json:"host"`
+	} `json:"cache"`
+}
+
+func TestCliEnvNamespaces(t *testing.T) {
+	env := map[string]string{
+		"NS_NAME":                  "app",
+		"NS_HOST":                  "global",
+		"NS_DATABASE_HOST":         "db",
+		"NS_DATABASE_REPLICA_HOST": "replica",
+		"NS_DATABASE_TAG":          "primary",
+		"HOSTNAME":                 "raw",
+	}
+
+	cases := []struct {
+		config   string
+		expected namespaceConf
+	}{
+		{`{"name": "${NAME}", "database": {"host": "${HOST}", "replica": {"host": "${HOST}"}, "tags": ["${TAG}", "$${HOST}"]}, "cache": {"host": "${HOST}"}}`, func() namespaceConf {
+			var c namespaceConf
+			c.Name, c.Database.Host, c.Cache.Host = "app", "db", "global"
+			c.Database.Replica = &struct {
+				Host string `json:"host"`
+			}{"replica"}
+			c.Database.Tags = []string{"primary", "${HOST}"}
+			return c
+		}()},
+		{`{"Database": {"HOST": "${env:HOSTNAME}-${HOST:-x}"}} // ${NAME}`, func() namespaceConf {
+			var c namespaceConf
+			c.Database.Host = "raw-db"
+			return c
+		}()},
+	}
+
+	for _, c := range cases {
+		conf := &namespaceConf{}
+
+		_, err := Parse("NS", "", nil, conf, WithEnvironment(env), WithArgs("", "-config", c.config))
+
+		if err != nil || !reflect.DeepEqual(*conf, c.expected) {
+			t.Errorf("expected output: (%+v, <nil>), but found: (%+v, %v)", c.expected, *conf, err)
+		}
+	}
+
+	names, err := EnvVars("NS", &namespaceConf{}, WithEnvironment(nil), WithArgs("", "-config", `{"database": {"host": "${HOST}"}, "cache": {"host": "${HOST}"}}`))
+
+	if expected := []string{"NS_DATABASE_HOST", "NS_HOST"}; err != nil || !reflect.DeepEqual(names[len(envVarKeys):], expected) {
+		t.Errorf("expected output: (%v, <nil>), but found: (%v, %v)", expected, names, err)
+	}
+}
+
+type namespaceNode struct {
+	Host  string         `json:"host"`
+	Child *namespaceNode `json:"child" envprefix:"CHILD"`
+	Peer  struct {
+		Node *namespaceNode `json:"node"`
+	} `json:"peer" envprefix:"PEER"`
+}
+
+func TestEnvNamespacesRecursive(t *testing.T) {
+	namespaces := envNamespaces(reflect.TypeOf(namespaceNode{}), "", "", make(map[reflect.Type]bool))
+
+	// the fields of recursive structures are not walked again.
+	expected := []envNamespace{{"child", "CHILD_"}, {"peer", "PEER_"}}
+
+	if !reflect.DeepEqual(namespaces, expected) {
+		t.Errorf("expected output: %+v, but found: %+v", expected, namespaces)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// fetchNATS reads the value of a key from a NATS JetStream key/value bucket, located by a
+// nats[+tls]://[<user>:<password>@]<host>[:<port>][,<host>[:<port>]...]/<bucket>/<key> URI, where nats+tls
+// requires TLS. When the URI carries no credentials they are read from the following variables if defined:
+//
+//	$<envVarPrefix>_NATS_USER and $<envVarPrefix>_NATS_PASSWORD  user and password.
+//	$<envVarPrefix>_NATS_TOKEN                                 authentication token.
+//	$<envVarPrefix>_NATS_CREDS                                 path of a credentials file holding a user JWT and NKey seed.
+//	$<envVarPrefix>_NATS_NKEY                                  path of a file holding an NKey seed.
+func fetchNATS(ctx context.Context, o *options, u *url.URL) ([]byte, error) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if len(u.Host) == 0 || len(bucket) == 0 || len(key) == 0 {
+		return nil, errors.New("URI must be in the form nats[+tls]://[<user>:<password>@]<host>[:<port>][,<host>[:<port>]...]/<bucket>/<key>")
+	}
+
+	scheme := "nats"
+	if strings.EqualFold(u.Scheme, "nats+tls") {
+		scheme = "tls"
+	}
+
+	servers := strings.Split(u.Host, ",")
+	for i, server := range servers {
+		servers[i] = scheme + "://" + server
+	}
+
+	getEnv := func(key string) string {
+		return envValue(o, "NATS_"+key)
+	}
+
+	opts := []nats.Option{
+		nats.Name("config"),
+		nats.NoReconnect(),
+		nats.SetCustomDialer(&natsDialer{ctx: ctx, o: o}),
+	}
+
+	if o.tlsConfig != nil {
+		opts = append(opts, nats.Secure(o.tlsConfig.Clone()))
+	}
+
+	if u.User != nil {
+		password, _ := u.User.Password()
+		opts = append(opts, nats.UserInfo(u.User.Username(), password))
+	} else if user := getEnv("USER"); len(user) > 0 {
+		opts = append(opts, nats.UserInfo(user, getEnv("PASSWORD")))
+	}
+
+	if token := getEnv("TOKEN"); len(token) > 0 {
+		opts = append(opts, nats.Token(token))
+	}
+
+	if creds := getEnv("CREDS"); len(creds) > 0 {
+		opts = append(opts, nats.UserCredentials(creds))
+	}
+
+	if seed := getEnv("NKEY"); len(seed) > 0 {
+		opt, err := nats.NkeyOptionFromSeed(seed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NKey seed: %v", err)
+		}
+		opts = append(opts, opt)
+	}
+
+	nc, err := nats.Connect(strings.Join(servers, ","), opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+
+	kv, err := js.KeyValue(ctx, bucket)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrBucketNotFound) {
+			return nil, fmt.Errorf("bucket [%v] does not exist", bucket)
+		}
+		return nil, err
+	}
+
+	entry, err := kv.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return nil, fmt.Errorf("key [%v] does not exist in bucket [%v]", key, bucket)
+		}
+		return nil, err
+	}
+
+	return entry.Value(), nil
+}
+
+// natsDialer connects the NATS client over the preferred IP family, bounded by ctx.
+type natsDialer struct {
+	ctx context.Context
+	o   *options
+}
+
+// Dial connects to address.
+func (d *natsDialer) Dial(_, address string) (net.Conn, error) {
+	return dial(d.ctx, d.o, address)
+}
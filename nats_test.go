@@ -0,0 +1,103 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestCliNATS(t *testing.T) {
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		Username:  "ops",
+		Password:  "s3cr3t",
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go srv.Start()
+	defer srv.Shutdown()
+
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server is not ready")
+	}
+
+	nc, err := nats.Connect(srv.ClientURL(), nats.UserInfo("ops", "s3cr3t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	js, _ := jetstream.New(nc)
+
+	kv, err := js.CreateKeyValue(context.Background(), jetstream.KeyValueConfig{Bucket: "configs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = kv.Put(context.Background(), "api.json", []byte(`{"id": 43, "name": "Carlos"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := strings.TrimPrefix(srv.ClientURL(), "nats://")
+
+	cases := []struct {
+		uri      string
+		env      bool
+		expected testConf
+		err      string
+	}{
+		{"nats://ops:s3cr3t@" + addr + "/configs/api.json", false, testConf{ID: 43, Name: "Carlos"}, ""},
+		{"nats://" + addr + "/configs/api.json", true, testConf{ID: 43, Name: "Carlos"}, ""},
+		{"nats://" + addr + "/configs/api.json", false, testConf{}, "Authorization Violation"},
+		{"nats://ops:s3cr3t@" + addr + "/configs/missing.json", false, testConf{}, "key [missing.json] does not exist in bucket [configs]"},
+		{"nats://ops:s3cr3t@" + addr + "/missing/api.json", false, testConf{}, "bucket [missing] does not exist"},
+		{"nats://" + addr + "/configs", false, testConf{}, "URI must be in the form nats[+tls]://"},
+	}
+
+	for _, c := range cases {
+		if c.env {
+			t.Setenv("TEST_NATS_USER", "ops")
+			t.Setenv("TEST_NATS_PASSWORD", "s3cr3t")
+		} else {
+			t.Setenv("TEST_NATS_USER", "")
+		}
+
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
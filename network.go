@@ -0,0 +1,171 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ipFamilies maps the supported IP family preferences to the networks dialed by the configuration sources.
+var ipFamilies = map[string]string{
+	"":     "tcp",
+	"dual": "tcp",
+	"ipv4": "tcp4",
+	"ipv6": "tcp6",
+}
+
+// configureNetwork sets up the settings shared by every network based configuration source and
+// resolver, given the path of a PEM bundle of CA certificates trusted in addition to the system ones,
+// whether server certificates verification is skipped, which is only ever meant for debugging, and
+// the preferred IP family, one of ipv4, ipv6 or dual which is the default and connects over
+// whichever family is reachable first. HTTP based sources honor $HTTPS_PROXY, $HTTP_PROXY and
+// $NO_PROXY in any case.
+func configureNetwork(o *options, caFile string, skipVerify bool, ipFamily string) error {
+	network, found := ipFamilies[strings.ToLower(ipFamily)]
+	if !found {
+		return fmt.Errorf("unsupported IP family [%v], supported families are: dual, ipv4, ipv6", ipFamily)
+	}
+
+	o.network = network
+
+	if len(caFile) > 0 || skipVerify {
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+
+		if len(caFile) > 0 {
+			pem, err := os.ReadFile(caFile)
+			if err != nil {
+				return fmt.Errorf("failed to read CA certificates: %v", err)
+			}
+
+			if !roots.AppendCertsFromPEM(pem) {
+				return fmt.Errorf("no valid CA certificates found in [%v]", caFile)
+			}
+		}
+
+		if skipVerify {
+			o.logger.Printf("WARNING: TLS certificates verification is DISABLED for every configuration source, " +
+				"connections are open to man-in-the-middle attacks, never use this in production!")
+		}
+
+		o.caFile = caFile
+		o.tlsConfig = &tls.Config{RootCAs: roots, InsecureSkipVerify: skipVerify}
+	}
+
+	if o.httpClient == http.DefaultClient && (o.tlsConfig != nil || o.network != "tcp") {
+		o.httpClient = &http.Client{Transport: newTransport(o)}
+	}
+
+	return nil
+}
+
+// newTransport returns a new HTTP transport honoring the proxy environment variables and the
+// shared network settings of o.
+func newTransport(o *options) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if o.tlsConfig != nil {
+		transport.TLSClientConfig = o.tlsConfig.Clone()
+	}
+
+	transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return dial(ctx, o, addr)
+	}
+
+	return transport
+}
+
+// dial connects to addr, a <host>:<port> address where IPv6 literal hosts are enclosed
+// in square brackets, over the preferred IP family of o.
+func dial(ctx context.Context, o *options, addr string) (net.Conn, error) {
+	network := o.network
+	if len(network) == 0 {
+		network = "tcp"
+	}
+
+	d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+	return d.DialContext(ctx, network, addr)
+}
+
+// newTLSConfig returns a TLS configuration for connecting to serverName based on the shared TLS settings of o.
+func newTLSConfig(o *options, serverName string) *tls.Config {
+	config := &tls.Config{}
+
+	if o.tlsConfig != nil {
+		config = o.tlsConfig.Clone()
+	}
+
+	config.ServerName = serverName
+
+	return config
+}
+
+// parseURI parses uri as url.Parse does, additionally accepting authorities listing several
+// comma separated hosts, e.g. zk://[fd00::1]:2181,[fd00::2]:2181/config, which url.Parse
+// rejects when they carry IPv6 literals. The hosts are kept as they are in the Host field.
+func parseURI(uri string) (*url.URL, error) {
+	u, err := url.Parse(uri)
+	if err == nil {
+		return u, nil
+	}
+
+	scheme, rest, found := strings.Cut(uri, "://")
+	if !found {
+		return nil, err
+	}
+
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+
+	userinfo, hosts := "", rest[:end]
+	if i := strings.LastIndex(hosts, "@"); i >= 0 {
+		userinfo, hosts = hosts[:i+1], hosts[i+1:]
+	}
+
+	if !strings.Contains(hosts, ",") {
+		return nil, err
+	}
+
+	for _, host := range strings.Split(hosts, ",") {
+		if len(host) == 0 || strings.Count(host, "[") != strings.Count(host, "]") {
+			return nil, err
+		}
+	}
+
+	multi, perr := url.Parse(scheme + "://" + userinfo + "hosts" + rest[end:])
+	if perr != nil {
+		return nil, err
+	}
+
+	multi.Host = hosts
+
+	return multi, nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/pem"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCliTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 22, "name": "Niaj"}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	invalidFile := filepath.Join(dir, "invalid.pem")
+	if err := os.WriteFile(invalidFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		caFile, skipVerify string
+		expected           testConf
+		err, warning       string
+	}{
+		{"", "", testConf{}, "certificate signed by unknown authority", ""},
+		{caFile, "", testConf{ID: 22, Name: "Niaj"}, "", ""},
+		{"", "true", testConf{ID: 22, Name: "Niaj"}, "", "WARNING: TLS certificates verification is DISABLED"},
+		{"", "maybe", testConf{}, "invalid value [maybe] of $TEST_TLS_SKIP_VERIFY, a boolean is expected", ""},
+		{invalidFile, "", testConf{}, "no valid CA certificates found in [" + invalidFile + "]", ""},
+		{filepath.Join(dir, "missing.pem"), "", testConf{}, "failed to read CA certificates", ""},
+	}
+
+	for _, c := range cases {
+		t.Setenv("TEST_CA_FILE", c.caFile)
+		t.Setenv("TEST_TLS_SKIP_VERIFY", c.skipVerify)
+
+		var logs bytes.Buffer
+
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", srv.URL + "/api.json"}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf, WithLogger(log.New(&logs, "", 0)))
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+
+		if !strings.Contains(logs.String(), c.warning) || (len(c.warning) == 0 && logs.Len() > 0) {
+			t.Errorf("expected warning: %q, but found: %q", c.warning, logs.String())
+		}
+	}
+}
+
+func TestCliIPFamily(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 24, "name": "Peggy"}`))
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		family   string
+		expected testConf
+		err      string
+	}{
+		{"", testConf{ID: 24, Name: "Peggy"}, ""},
+		{"ipv4", testConf{ID: 24, Name: "Peggy"}, ""},
+		{"IPv6", testConf{}, "no suitable address"},
+		{"ipx", testConf{}, "unsupported IP family [ipx], supported families are: dual, ipv4, ipv6"},
+	}
+
+	for _, c := range cases {
+		t.Setenv("TEST_IP_FAMILY", c.family)
+
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", srv.URL + "/api.json"}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
+
+func TestParseURI(t *testing.T) {
+	cases := []struct {
+		uri, host, path string
+		err             bool
+	}{
+		{"https://[fd00::1]:8443/api.json", "[fd00::1]:8443", "/api.json", false},
+		{"zk://zk1:2181,zk2/config", "zk1:2181,zk2", "/config", false},
+		{"zk://ops:secret@[fd00::1]:2181,[fd00::2]/config", "[fd00::1]:2181,[fd00::2]", "/config", false},
+		{"zk://[fd00::1]:2181,[fd00::2/config", "", "", true},
+		{"https://[fd00::1/api.json", "", "", true},
+	}
+
+	for _, c := range cases {
+		u, err := parseURI(c.uri)
+
+		if c.err != (err != nil) || (err == nil && (u.Host != c.host || u.Path != c.path)) {
+			t.Errorf("expected (%v, %v, error: %v) for [%v], but found: (%v, %v)", c.host, c.path, c.err, c.uri, u, err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oauthExpiryMargin is how long before their expiry cached tokens are refreshed.
+const oauthExpiryMargin = 30 * time.Second
+
+// oauthToken is an access token cached until shortly before its expiry.
+type oauthToken struct {
+	value  string
+	expiry time.Time
+}
+
+var (
+	// oauthTokensMutex guards oauthTokens.
+	oauthTokensMutex sync.Mutex
+
+	// oauthTokens caches the tokens obtained with the client credentials flow by token URL and client ID.
+	oauthTokens = make(map[string]*oauthToken)
+)
+
+// oauthClientToken returns an access token to send to target obtained with the OAuth2 client credentials
+// flow when $<envVarPrefix>_OAUTH_TOKEN_URL is defined, otherwise an empty string. The client is identified
+// by $<envVarPrefix>_OAUTH_CLIENT_ID and $<envVarPrefix>_OAUTH_CLIENT_SECRET, or the content of the file
+// pointed to by $<envVarPrefix>_OAUTH_CLIENT_SECRET_FILE, and the space separated scopes requested
+// are read from $<envVarPrefix>_OAUTH_SCOPES. Tokens are reused until shortly before they expire.
+// Neither the credentials nor the tokens are ever sent over plain HTTP, both the token URL and target
+// must use https.
+func oauthClientToken(ctx context.Context, o *options, target *url.URL) (string, error) {
+	getEnv := func(key string) string {
+		return envValue(o, "OAUTH_"+key)
+	}
+
+	tokenURL, clientID, secret := getEnv("TOKEN_URL"), getEnv("CLIENT_ID"), getEnv("CLIENT_SECRET")
+	if len(tokenURL) == 0 {
+		return "", nil
+	}
+
+	if !strings.EqualFold(target.Scheme, "https") {
+		return "", fmt.Errorf("refusing to send OAuth2 access token to [%v], https is required", target.Redacted())
+	}
+
+	if u, err := url.Parse(tokenURL); err != nil || !strings.EqualFold(u.Scheme, "https") {
+		return "", fmt.Errorf("invalid OAuth2 token URL [%v], https is required", tokenURL)
+	}
+
+	if file := getEnv("CLIENT_SECRET_FILE"); len(secret) == 0 && len(file) > 0 {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read OAuth2 client secret: %v", err)
+		}
+
+		secret = strings.TrimRight(string(data), "\r\n")
+	}
+
+	if len(clientID) == 0 || len(secret) == 0 {
+		return "", fmt.Errorf("OAuth2 client credentials are missing, $%vOAUTH_CLIENT_ID and either $%[1]vOAUTH_CLIENT_SECRET or $%[1]vOAUTH_CLIENT_SECRET_FILE must be defined", o.envVarPrefix)
+	}
+
+	// tokens are granted the scopes they are requested with, regardless of their order.
+	scopes := strings.Fields(getEnv("SCOPES"))
+	sort.Strings(scopes)
+
+	key := tokenURL + " " + clientID + " " + strings.Join(scopes, " ")
+
+	oauthTokensMutex.Lock()
+	defer oauthTokensMutex.Unlock()
+
+	if token, found := oauthTokens[key]; found && time.Now().Add(oauthExpiryMargin).Before(token.expiry) {
+		return token.value, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(scopes) > 0 {
+		form.Set("scope", strings.Join(scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(secret))
+
+	body, err := doRequest(httpsOnly(o.httpClient), req)
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain OAuth2 access token: %v", err)
+	}
+
+	res := &struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}{}
+
+	if err = json.Unmarshal(body, res); err != nil || len(res.AccessToken) == 0 {
+		return "", errors.New("failed to obtain OAuth2 access token: token endpoint returned no access token")
+	}
+
+	token := &oauthToken{value: res.AccessToken}
+	if res.ExpiresIn > 0 {
+		token.expiry = time.Now().Add(time.Duration(res.ExpiresIn) * time.Second)
+	}
+
+	oauthTokens[key] = token
+
+	return token.value, nil
+}
+
+// httpsOnly returns a copy of client refusing to follow redirects to URLs not using https, which would
+// send the credentials or the access token of its requests in clear.
+func httpsOnly(client *http.Client) *http.Client {
+	c := *client
+
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !strings.EqualFold(req.URL.Scheme, "https") {
+			return fmt.Errorf("refusing to follow redirect to [%v] with OAuth2 credentials, https is required", req.URL.Redacted())
+		}
+
+		if client.CheckRedirect != nil {
+			return client.CheckRedirect(req, via)
+		}
+
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+
+		return nil
+	}
+
+	return &c
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	// ociTitleAnnotation is the annotation carrying the file name of an OCI artifact layer.
+	ociTitleAnnotation = "org.opencontainers.image.title"
+
+	// ociManifestTypes are the manifest media types accepted from registries.
+	ociManifestTypes = "application/vnd.oci.image.manifest.v1+json, application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// ociChallengeParamRegex matches the parameters of a WWW-Authenticate bearer challenge.
+var ociChallengeParamRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// fetchOCI pulls a configuration artifact from an OCI registry, located by an
+// oci://[<user>:<password>@]<registry>/<repository>(:<tag>|@<digest>)[?layer=<title>] URI, e.g.
+// oci://registry.example.com/configs/api@sha256:9f86d0... which pins the exact artifact. The content
+// of the layer titled as specified is returned, or of the only layer when no title is specified.
+// Both the manifest and the layer are verified against their digests, and registries requiring
+// bearer tokens are supported, anonymously or with the credentials of the URI.
+func fetchOCI(ctx context.Context, o *options, u *url.URL) ([]byte, error) {
+	repo, ref := strings.TrimPrefix(u.Path, "/"), "latest"
+
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo, ref = repo[:i], repo[i+1:]
+	} else if i := strings.LastIndex(repo, ":"); i >= 0 {
+		repo, ref = repo[:i], repo[i+1:]
+	}
+
+	if len(u.Host) == 0 || len(repo) == 0 || len(ref) == 0 {
+		return nil, errors.New("URI must be in the form oci://<registry>/<repository>(:<tag>|@<digest>)[?layer=<title>]")
+	}
+
+	reg := &ociRegistry{o: o, base: "https://" + u.Host + "/v2/" + repo, user: u.User}
+
+	data, err := reg.get(ctx, "/manifests/"+ref, ociManifestTypes, digestOf(ref))
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull manifest: %v", err)
+	}
+
+	m := &struct {
+		Layers []struct {
+			Digest      string            `json:"digest"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"layers"`
+	}{}
+
+	if err = json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+
+	title := u.Query().Get("layer")
+
+	for _, layer := range m.Layers {
+		if (len(title) == 0 && len(m.Layers) == 1) || (len(title) > 0 && layer.Annotations[ociTitleAnnotation] == title) {
+			if len(digestOf(layer.Digest)) == 0 {
+				return nil, fmt.Errorf("unsupported layer digest [%v]", layer.Digest)
+			}
+
+			return reg.get(ctx, "/blobs/"+layer.Digest, "", layer.Digest)
+		}
+	}
+
+	if len(title) == 0 {
+		return nil, fmt.Errorf("artifact has %v layers, the layer must be selected by its title", len(m.Layers))
+	}
+
+	return nil, fmt.Errorf("artifact has no layer titled [%v]", title)
+}
+
+// digestOf returns ref if it is a sha256 digest, otherwise an empty string.
+func digestOf(ref string) string {
+	if strings.HasPrefix(ref, "sha256:") && len(ref) == len("sha256:")+sha256.Size*2 {
+		return ref
+	}
+
+	return ""
+}
+
+// ociRegistry is a client of a repository in an OCI distribution registry.
+type ociRegistry struct {
+	o     *options
+	base  string
+	user  *url.Userinfo
+	token string
+}
+
+// get requests the specified path of the repository, answering bearer token challenges if needed,
+// and verifies the content against digest if not empty.
+func (r *ociRegistry) get(ctx context.Context, path, accept, digest string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.base+path, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(accept) > 0 {
+			req.Header.Set("Accept", accept)
+		}
+
+		if len(r.token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+r.token)
+		} else if r.user != nil {
+			password, _ := r.user.Password()
+			req.SetBasicAuth(r.user.Username(), password)
+		}
+
+		res, err := r.o.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		challenge := res.Header.Get("WWW-Authenticate")
+
+		if res.StatusCode == http.StatusUnauthorized && attempt == 0 && strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+			if r.token, err = r.authorize(ctx, challenge); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return nil, fmt.Errorf("unexpected response status [%v]: %v", res.Status, strings.TrimSpace(string(body)))
+		}
+
+		if len(digest) > 0 {
+			if sum := sha256.Sum256(body); "sha256:"+hex.EncodeToString(sum[:]) != digest {
+				return nil, fmt.Errorf("content does not match digest [%v]", digest)
+			}
+		}
+
+		return body, nil
+	}
+}
+
+// authorize obtains a bearer token from the authorization service described by challenge.
+func (r *ociRegistry) authorize(ctx context.Context, challenge string) (string, error) {
+	params := make(map[string]string)
+	for _, m := range ociChallengeParamRegex.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(m[1])] = m[2]
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || len(params["realm"]) == 0 {
+		return "", fmt.Errorf("invalid authentication challenge [%v]", challenge)
+	}
+
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if val, found := params[key]; found {
+			query.Set(key, val)
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if r.user != nil {
+		password, _ := r.user.Password()
+		req.SetBasicAuth(r.user.Username(), password)
+	}
+
+	body, err := doRequest(r.o.httpClient, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain registry token: %v", err)
+	}
+
+	res := &struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+
+	if err = json.Unmarshal(body, res); err != nil || len(res.Token)+len(res.AccessToken) == 0 {
+		return "", errors.New("failed to obtain registry token: authorization service returned no token")
+	}
+
+	if len(res.Token) > 0 {
+		return res.Token, nil
+	}
+
+	return res.AccessToken, nil
+}
@@ -0,0 +1,622 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Option customizes the way Parse reads and interprets the configuration.
+type Option func(*options)
+
+// options holds the settings collected from the Option values passed to Parse.
+type options struct {
+	// ctx bounds the time spent fetching the configuration from remote sources.
+	ctx context.Context
+
+	// httpClient is the client used by the HTTP based configuration sources.
+	httpClient *http.Client
+
+	// tlsConfig holds the TLS settings shared by network based configuration sources, if customized.
+	tlsConfig *tls.Config
+
+	// network is the network dialed by the network based configuration sources, tcp, tcp4 or tcp6.
+	network string
+
+	// caFile is the path of the PEM bundle of additionally trusted CA certificates, if any.
+	caFile string
+
+	// logger receives the warnings about the configuration.
+	logger *log.Logger
+
+	// spiffe enables mTLS with SPIFFE SVIDs for the HTTP configuration sources.
+	spiffe bool
+
+	// spiffeIDs are the SPIFFE IDs the servers of the HTTP configuration sources are allowed to present.
+	spiffeIDs []string
+
+	// spiffeClient is the mTLS client used by the HTTP configuration sources when SPIFFE is enabled.
+	spiffeClient *http.Client
+
+	// defaultFS and defaultPath locate the default configuration embedded in the binary, if any.
+	defaultFS   fs.FS
+	defaultPath string
+
+	// retryAttempts is the number of attempts at fetching a configuration document before giving up on its
+	// source, waiting for retryBackoff after the first failure, doubling up to retryMaxBackoff.
+	retryAttempts   int
+	retryBackoff    time.Duration
+	retryMaxBackoff time.Duration
+
+	// cacheDir is the directory where the remote configuration documents are cached, encrypted with cacheKey.
+	cacheDir string
+	cacheKey []byte
+
+	// preValidate enables checking the structure of the configuration before resolving placeholders,
+	// against confType which is the type of the configuration structure.
+	preValidate bool
+	confType    reflect.Type
+
+	// budget bounds the time spent loading the configuration if positive, and stages accumulates
+	// the time spent at every stage of loading it.
+	budget time.Duration
+	stages map[string]time.Duration
+
+	// autoEnv enables binding every field of the configuration structure to an environment variable.
+	autoEnv bool
+
+	// secretCacheDir is the directory where the resolved secrets are cached for secretCacheTTL,
+	// encrypted with secretCacheKey.
+	secretCacheDir string
+	secretCacheKey []byte
+	secretCacheTTL time.Duration
+
+	// usageReport is where the usage report of the configuration is written if set.
+	usageReport io.Writer
+
+	// lint is the lint mode, error or warn, or empty if the configuration documents are not linted,
+	// and lintFindings are the findings of linting them.
+	lint         string
+	lintFindings []lintFinding
+
+	// caseInsensitiveEnv makes the names of the environment variables match regardless of their case.
+	caseInsensitiveEnv bool
+
+	// dotEnvFiles are the .env files defining environment variables, loaded into dotEnv.
+	dotEnvFiles []string
+	dotEnv      map[string]string
+
+	// execPlaceholders enables placeholders running commands, e.g. ${exec:op read op://vault/db/password}.
+	execPlaceholders bool
+
+	// typedPlaceholders replaces the JSON strings made of a placeholder only by the number, boolean or null
+	// they hold, if any.
+	typedPlaceholders bool
+
+	// windowsPlaceholders expands the references to environment variables of the form %APPDATA%.
+	windowsPlaceholders bool
+
+	// legacyPrefixes are the former environment variable prefixes variables fall back to, the ones of
+	// legacyWarned having been reported as deprecated already.
+	legacyPrefixes []string
+	legacyWarned   map[string]bool
+
+	// alpha enables the options of the fields tagged with `stability:"alpha"`.
+	alpha bool
+
+	// emergencyHook is called when a break-glass configuration is in effect.
+	emergencyHook func(expires time.Time, paths []string)
+
+	// strictPlaceholders fails on the placeholders that cannot be resolved.
+	strictPlaceholders bool
+
+	// envVars receives the names of the environment variables the configuration may be read from, instead
+	// of loading it, when not nil.
+	envVars *[]string
+
+	// instanceID identifies the instance the spread of the numeric fields is derived from, defaulting to the
+	// host name.
+	instanceID string
+
+	// forwardCompatible reports the options unknown to the configuration structure, and keeps them when
+	// converting documents.
+	forwardCompatible bool
+
+	// strictKeys rejects the options unknown to the configuration structure.
+	strictKeys bool
+
+	// deprecationHook receives the deprecated options found in the configuration, which fail loading it
+	// when strictDeprecations is set.
+	deprecationHook    func(path, message string)
+	strictDeprecations bool
+
+	// validatorTags enables checking the configuration against the constraints of the validate tags.
+	validatorTags bool
+
+	// schemaVersionKey is the option holding the version of the schema of the configuration documents,
+	// upgraded to schemaVersion by the migrations keyed by the versions they upgrade from.
+	schemaVersionKey string
+	schemaVersion    int
+	migrations       map[int]Migration
+
+	// zeroValueWarnings reports the options left to their zero values.
+	zeroValueWarnings bool
+
+	// validationHooks check the loaded configuration along with the sources of its options.
+	validationHooks []ValidationHook
+
+	// provenance receives the sources of the options of the loaded configuration when not nil.
+	provenance *Provenance
+
+	// environment replaces the environment of the process when not nil, and args replace its
+	// command line arguments.
+	environment map[string]string
+	args        []string
+
+	// lookupEnv replaces the environment of the process when not nil, taking precedence over environment.
+	lookupEnv func(name string) (string, bool)
+
+	// receiptSink is the file path or http(s) URL receipts of the configuration are emitted to, signed
+	// with receiptKey.
+	receiptSink string
+	receiptKey  crypto.Signer
+
+	// precedence is the chain of the configuration layers, from the lowest precedence to the highest, if any.
+	precedence []Layer
+
+	// envVarPrefix is the normalized prefix of the environment variables of the application.
+	envVarPrefix string
+
+	// cueSchema is a CUE document that the configuration must satisfy.
+	cueSchema string
+
+	// jsonSchema is a JSON schema that the configuration must satisfy, unless read from jsonSchemaFile.
+	jsonSchema     string
+	jsonSchemaFile string
+
+	// starlark enables configurations written in Starlark.
+	starlark bool
+
+	// jsonnetPaths are the directories searched for the files imported by Jsonnet configurations.
+	jsonnetPaths []string
+}
+
+// newOptions returns the options resulting from applying opts in order over the defaults.
+func newOptions(opts []Option) *options {
+	o := &options{
+		ctx:          context.Background(),
+		httpClient:   http.DefaultClient,
+		logger:       log.New(os.Stderr, "config: ", log.LstdFlags),
+		stages:       make(map[string]time.Duration),
+		dotEnv:       make(map[string]string),
+		legacyWarned: make(map[string]bool),
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+
+	return o
+}
+
+// WithCUESchema sets a CUE document that is unified with the configuration before
+// it is decoded, any constraint violation makes Parse return a descriptive error.
+// Definitions can be used to close the schema and reject unknown fields, e.g.
+//
+//	#Config: {
+//	  id:     int & >0
+//	  name:   string
+//	  online: bool | *false
+//	}
+//	#Config
+func WithCUESchema(schema string) Option {
+	return func(o *options) {
+		o.cueSchema = schema
+	}
+}
+
+// WithJSONSchema sets a JSON schema that the configuration must satisfy once its documents are merged and
+// their placeholders resolved, before it is decoded. Parse fails listing every violation along with the
+// dotted JSON path of the value it is about.
+func WithJSONSchema(schema string) Option {
+	return func(o *options) {
+		o.jsonSchema = schema
+	}
+}
+
+// WithJSONSchemaFile sets the path of a file holding a JSON schema that the configuration must satisfy,
+// see WithJSONSchema.
+func WithJSONSchemaFile(path string) Option {
+	return func(o *options) {
+		o.jsonSchemaFile = path
+	}
+}
+
+// WithContext sets the context used while fetching the configuration from remote sources,
+// it can be used to bound the time spent on startup.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithJsonnetImportPaths adds directories to the ones searched for the files imported by
+// configurations written in Jsonnet, in addition to the working directory and $JSONNET_PATH.
+func WithJsonnetImportPaths(paths ...string) Option {
+	return func(o *options) {
+		o.jsonnetPaths = append(o.jsonnetPaths, paths...)
+	}
+}
+
+// WithStarlark enables configurations written in Starlark, programs computing the configuration
+// object, for the rare applications that genuinely need logic in their configuration.
+func WithStarlark() Option {
+	return func(o *options) {
+		o.starlark = true
+	}
+}
+
+// WithSPIFFE makes the http and https configuration sources authenticate over mTLS with the X.509 SVID
+// of the workload, obtained from the SPIFFE Workload API at $SPIFFE_ENDPOINT_SOCKET, instead of
+// static certificate files. The servers must present an SVID of one of the specified SPIFFE IDs
+// e.g. spiffe://example.org/config-service, or of any ID of a trusted domain if none is specified.
+func WithSPIFFE(serverIDs ...string) Option {
+	return func(o *options) {
+		o.spiffe = true
+		o.spiffeIDs = append(o.spiffeIDs, serverIDs...)
+	}
+}
+
+// WithLogger sets the logger receiving the warnings about the configuration, which by default
+// are written to the standard error.
+func WithLogger(logger *log.Logger) Option {
+	return func(o *options) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
+
+// WithEmbeddedDefault sets the default configuration shipped with the binary, read from path in fsys,
+// usually an embed.FS, in any of the supported formats selected by the extension of path, otherwise json.
+// The configuration read from the command line, the environment or the configuration URIs is merged
+// over it, such that the defaults apply to whatever it leaves unspecified.
+func WithEmbeddedDefault(fsys fs.FS, path string) Option {
+	return func(o *options) {
+		o.defaultFS, o.defaultPath = fsys, path
+	}
+}
+
+// WithPrecedence declares the chain of the configuration layers, from the lowest precedence to the highest,
+// e.g. DefaultPrecedence, which are deep merged in order such that the values of a layer override the ones
+// of the layers before it, and layers that are not available are skipped. By default the configuration
+// is either the JSON string of -config, itself defaulting to $<envVarPrefix>_CONFIG, or the documents of the
+// configuration URIs, merged over the embedded default configuration.
+func WithPrecedence(layers ...Layer) Option {
+	return func(o *options) {
+		o.precedence = append([]Layer(nil), layers...)
+	}
+}
+
+// WithRetry makes fetching configuration documents from their sources try up to the specified number
+// of attempts, waiting for backoff after the first failed attempt and doubling it after every other one,
+// up to maxBackoff unless it is zero. Only then a source is given up on, and the next of the alternative
+// URIs of a document if any is tried, e.g. a local cache in
+//
+//	--config-uri "https://config.example.com/api.json|file:///var/cache/api.json"
+func WithRetry(attempts int, backoff, maxBackoff time.Duration) Option {
+	return func(o *options) {
+		o.retryAttempts, o.retryBackoff, o.retryMaxBackoff = attempts, backoff, maxBackoff
+	}
+}
+
+// WithCache caches every configuration document fetched from a remote source in dir, encrypted with
+// AES-GCM using key, which must be 16, 24 or 32 bytes long, so that the application can still start
+// with the last known configuration when the source is unavailable, in which case a warning is logged.
+// Cache files are replaced atomically, and they are named after the digest of the URIs, never the URIs.
+func WithCache(dir string, key []byte) Option {
+	return func(o *options) {
+		o.cacheDir, o.cacheKey = dir, key
+	}
+}
+
+// WithPreValidation makes Parse check that the configuration documents decode into the configuration
+// structure before resolving the placeholders of schemes, e.g. ${azkv:vault/secret}, so that malformed
+// documents fail fast without consuming the quota of secret stores. Such placeholders are taken as the
+// strings they are while checking, so they have to be quoted in JSON documents.
+func WithPreValidation() Option {
+	return func(o *options) {
+		o.preValidate = true
+	}
+}
+
+// WithBudget bounds the total time spent loading the configuration, from fetching it to decoding and
+// validating it. When the budget is exceeded, Parse fails with an error breaking the time spent down by
+// stage: fetch, decrypt, resolve, decode and validate, to help diagnosing slow starts.
+func WithBudget(budget time.Duration) Option {
+	return func(o *options) {
+		o.budget = budget
+	}
+}
+
+// WithAutoEnv binds every field of the configuration structure that is not tagged with `env:"<name>"`
+// to the environment variable named after its path, e.g. the field Host of the field Database is bound
+// to $<envVarPrefix>_DATABASE_HOST, so that every field can be overridden from the environment. Names
+// are derived from the JSON names of the fields if any, otherwise from their names, in upper snake case,
+// and fields tagged with `env:"-"` or `json:"-"` are never bound.
+func WithAutoEnv() Option {
+	return func(o *options) {
+		o.autoEnv = true
+	}
+}
+
+// WithSecretCache caches the values of the placeholders of remote schemes e.g. ${azkv:vault/secret} in dir
+// for ttl, encrypted with AES-GCM using key, which must be 16, 24 or 32 bytes long, so that applications
+// restarting in a loop do not resolve them again from their stores every time. The values of the file and
+// exec schemes, which are resolved locally, are never cached. The cache of a document is named after its
+// digest, so changing the document invalidates it. Since the secrets are written to dir, it is best kept
+// on a memory backed file system only readable by the application, e.g. $XDG_RUNTIME_DIR or /dev/shm, and
+// key is best read from somewhere else than the disk, e.g. the keyring of the operating system.
+func WithSecretCache(dir string, key []byte, ttl time.Duration) Option {
+	return func(o *options) {
+		o.secretCacheDir, o.secretCacheKey, o.secretCacheTTL = dir, key, ttl
+	}
+}
+
+// WithUsageReport makes Parse write to w the UsageReport of the configuration as JSON, telling which
+// options differ from their defaults, which are left to them, and which are given in the configuration
+// documents but never read by the application. Defaults are the values of the configuration structure
+// passed to Parse.
+func WithUsageReport(w io.Writer) Option {
+	return func(o *options) {
+		o.usageReport = w
+	}
+}
+
+// WithCaseInsensitiveEnv makes the names of the environment variables read by Parse match regardless
+// of their case, e.g. $App_Port matches $APP_PORT, which helps on platforms where their case is
+// inconsistent, e.g. Windows. Variables whose names match exactly are preferred.
+func WithCaseInsensitiveEnv() Option {
+	return func(o *options) {
+		o.caseInsensitiveEnv = true
+	}
+}
+
+// WithDotEnv loads environment variables from the specified .env files, or from the .env file of the
+// working directory if none is specified, to ease local development. Files that do not exist are skipped,
+// the variables of a file override the ones of the files before it, while variables of the environment
+// override them all. The variables are only seen by Parse, the environment of the process is unchanged.
+func WithDotEnv(files ...string) Option {
+	return func(o *options) {
+		if len(files) == 0 {
+			files = []string{".env"}
+		}
+
+		o.dotEnvFiles = append(o.dotEnvFiles, files...)
+	}
+}
+
+// WithReceipt makes Parse emit a signed Receipt of the configuration once it is loaded, giving auditors
+// verifiable evidence of which configuration ran where and when: the digest of the configuration, the
+// URIs it was loaded from, the release version and the identity of the instance along with the time.
+// The receipt is written to sink if it is a file path, or posted to it if it is an http(s) URL, and
+// it is signed with key, an ECDSA, Ed25519 or RSA key, so that VerifyReceipt checks it with the public
+// key. Parse fails if the receipt cannot be emitted.
+func WithReceipt(sink string, key crypto.Signer) Option {
+	return func(o *options) {
+		o.receiptSink, o.receiptKey = sink, key
+	}
+}
+
+// WithEnvironment makes Parse look the environment variables up in env instead of the environment of
+// the process, isolating it e.g. in tests running in parallel, which would otherwise share the variables
+// they set. Variables of .env files loaded by WithDotEnv are seen as well, while env takes precedence.
+func WithEnvironment(env map[string]string) Option {
+	return func(o *options) {
+		o.environment = make(map[string]string, len(env))
+		for key, val := range env {
+			o.environment[key] = val
+		}
+	}
+}
+
+// WithArgs makes Parse read the command line arguments from args instead of os.Args, the first
+// argument being the name of the program.
+func WithArgs(args ...string) Option {
+	return func(o *options) {
+		o.args = append([]string{}, args...)
+	}
+}
+
+// WithExecPlaceholders enables placeholders injecting the standard output of commands, e.g.
+// ${exec:op read op://vault/db/password}, to integrate with CLIs such as op, pass or the ones of cloud
+// metadata services. These are disabled by default since they run whatever command the configuration
+// documents specify, which must thus be trusted.
+func WithExecPlaceholders() Option {
+	return func(o *options) {
+		o.execPlaceholders = true
+	}
+}
+
+// WithTypedPlaceholders preserves the types of the values of placeholders of environment variables making
+// whole JSON strings, e.g. "port": "${PORT}" is replaced by "port": 8080 rather than "port": "8080" when
+// $<envVarPrefix>_PORT is 8080, so that such values may be decoded into numeric fields. Values that are
+// numbers, booleans or null are substituted without the quotes, while others remain strings.
+func WithTypedPlaceholders() Option {
+	return func(o *options) {
+		o.typedPlaceholders = true
+	}
+}
+
+// WithWindowsPlaceholders expands the references to environment variables of the form %APPDATA% as well,
+// easing the migration of the configurations of Windows services. These refer to variables regardless of
+// the environment variable prefix and match regardless of their case along with WithCaseInsensitiveEnv, as
+// on Windows. As in batch files, %% stands for a literal %, while references to undefined variables are
+// left untouched.
+func WithWindowsPlaceholders() Option {
+	return func(o *options) {
+		o.windowsPlaceholders = true
+	}
+}
+
+// WithLegacyPrefixes makes the environment variables named after the environment variable prefix fall back
+// to the ones named after the specified former prefixes in order, easing the migration of deployments when
+// an application is renamed, e.g. $NEWAPP_PORT falls back to $OLDAPP_PORT. Using a legacy variable logs a
+// deprecation warning, once per variable.
+func WithLegacyPrefixes(prefixes ...string) Option {
+	return func(o *options) {
+		o.legacyPrefixes = append(o.legacyPrefixes, prefixes...)
+	}
+}
+
+// WithEmergencyHook sets a function called whenever the break-glass configuration of
+// $<envVarPrefix>_EMERGENCY_CONFIG is in effect, with its expiry timestamp and the dotted JSON paths of the
+// options it overrides, e.g. to report its use to the metrics of the application besides the logs.
+func WithEmergencyHook(hook func(expires time.Time, paths []string)) Option {
+	return func(o *options) {
+		o.emergencyHook = hook
+	}
+}
+
+// WithStrictPlaceholders makes Parse fail listing every unresolved placeholder rather than replacing the
+// ones of undefined environment variables by empty strings, and leaving the ones of unknown schemes
+// untouched, which otherwise leads to confusing errors down the line. Placeholders with default values,
+// e.g. ${PORT:-8080}, are always resolved.
+func WithStrictPlaceholders() Option {
+	return func(o *options) {
+		o.strictPlaceholders = true
+	}
+}
+
+// WithLookupEnv makes Parse look the environment variables up with lookup instead of os.LookupEnv, so
+// that tests and applications embedding others control the environment without changing the one of the
+// process, e.g. by reading it from a store of their own. Since such variables cannot be enumerated, they
+// are neither matched regardless of their case by WithCaseInsensitiveEnv nor passed to Jsonnet, unlike the
+// ones of WithEnvironment.
+func WithLookupEnv(lookup func(name string) (string, bool)) Option {
+	return func(o *options) {
+		o.lookupEnv = lookup
+	}
+}
+
+// WithInstanceID sets the identity of the instance the spread of the fields tagged with `spread:"<N>%"` is
+// derived from, e.g. the name of its pod, instead of its host name, which may not be unique across a fleet.
+func WithInstanceID(id string) Option {
+	return func(o *options) {
+		o.instanceID = id
+	}
+}
+
+// WithForwardCompatibility eases running several versions of an application side by side, reporting the
+// options of the configuration documents unknown to the configuration structure, e.g. the ones written for
+// newer versions, as warnings rather than ignoring them silently, and keeping them as written when converting
+// documents with -convert rather than failing.
+func WithForwardCompatibility() Option {
+	return func(o *options) {
+		o.forwardCompatible = true
+	}
+}
+
+// WithValidatorTags makes Parse check the configuration once loaded against the constraints of the `validate`
+// tags of the fields of the configuration structure, in the syntax of github.com/go-playground/validator,
+// e.g. `validate:"min=1,url"` or `validate:"oneof=debug info"`, failing with every violated constraint.
+func WithValidatorTags() Option {
+	return func(o *options) {
+		o.validatorTags = true
+	}
+}
+
+// WithStrictKeys makes Parse fail listing the options of the configuration documents, of any format, unknown
+// to the configuration structure along with their dotted JSON paths, e.g. server.prot for server.port, rather
+// than ignoring them, so that typos are caught when the application starts. It takes precedence over
+// WithForwardCompatibility.
+func WithStrictKeys() Option {
+	return func(o *options) {
+		o.strictKeys = true
+	}
+}
+
+// WithDeprecationHook sets a function called with the dotted JSON path of every option of the configuration
+// of a field tagged with `deprecated:"<message>"`, and its message, e.g. to report the deployments still
+// using them to the metrics of the application besides the logs.
+func WithDeprecationHook(hook func(path, message string)) Option {
+	return func(o *options) {
+		o.deprecationHook = hook
+	}
+}
+
+// WithStrictDeprecations makes Parse fail listing the options of the configuration of fields tagged with
+// `deprecated:"<message>"` rather than warning about them, e.g. in the pipelines validating deployments.
+func WithStrictDeprecations() Option {
+	return func(o *options) {
+		o.strictDeprecations = true
+	}
+}
+
+// WithValidationHook adds a hook checking the configuration structure once loaded and checked by every other
+// mean, along with the sources of its options, so that invariants across options are enforced in a single
+// place, e.g. tls.cert is required when tls.enabled is true, or must not be left to its default value. Hooks
+// are called in the order they are added, and Parse fails listing every failure.
+func WithValidationHook(hook ValidationHook) Option {
+	return func(o *options) {
+		o.validationHooks = append(o.validationHooks, hook)
+	}
+}
+
+// WithZeroValueWarnings makes Parse warn about the options of the configuration still holding zero values once
+// every source is applied, unless their fields are tagged with `optional:"true"`, so that half-filled
+// configurations are noticed early, e.g. while rolling out an option.
+func WithZeroValueWarnings() Option {
+	return func(o *options) {
+		o.zeroValueWarnings = true
+	}
+}
+
+// WithSchemaVersion versions the schema of the configuration documents, current being the version the
+// application expects and key the top level option of the documents holding their versions, e.g. version,
+// which should be a field of the configuration structure as well. Documents of older versions are upgraded
+// by the migrations of WithMigration before they are checked and decoded, while the ones lacking the option
+// are of version 1.
+func WithSchemaVersion(key string, current int) Option {
+	return func(o *options) {
+		o.schemaVersionKey, o.schemaVersion = key, current
+	}
+}
+
+// WithMigration adds the migration upgrading the configuration documents from the version from of their
+// schema to the next one, see WithSchemaVersion. Migrations are chained, e.g. documents of version 1 are
+// upgraded to version 3 by the migrations from versions 1 and 2.
+func WithMigration(from int, migration Migration) Option {
+	return func(o *options) {
+		if o.migrations == nil {
+			o.migrations = make(map[int]Migration)
+		}
+
+		o.migrations[from] = migration
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// leafOrder maps the dotted paths of the leaves of the JSON documents docs, the way flatten finds them,
+// to their position in the documents, the ones of a document following the ones of the documents before
+// it. Documents encoding configuration structures list their fields in the order they are declared,
+// which is thus preserved by ordering paths with it, rather than alphabetically.
+func leafOrder(docs ...[]byte) map[string]int {
+	order := make(map[string]int)
+
+	for _, doc := range docs {
+		dec := json.NewDecoder(bytes.NewReader(doc))
+		dec.UseNumber()
+
+		_ = orderLeaves(dec, "", order)
+	}
+
+	return order
+}
+
+// orderLeaves reads the next value of dec, located at path, and records the position of its leaves in order.
+func orderLeaves(dec *json.Decoder, path string, order map[string]int) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	leaf := len(path) > 0
+
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			if tok, err = dec.Token(); err != nil {
+				return err
+			}
+
+			key, _ := tok.(string)
+			if len(path) > 0 {
+				key = path + "." + key
+			}
+
+			if err = orderLeaves(dec, key, order); err != nil {
+				return err
+			}
+
+			leaf = false
+		}
+
+		_, err = dec.Token()
+	case json.Delim('['):
+		// arrays are leaves, their elements are skipped.
+		for depth := 1; depth > 0 && err == nil; {
+			if tok, err = dec.Token(); tok == json.Delim('[') || tok == json.Delim('{') {
+				depth++
+			} else if tok == json.Delim(']') || tok == json.Delim('}') {
+				depth--
+			}
+		}
+	}
+
+	if _, found := order[path]; leaf && !found {
+		order[path] = len(order)
+	}
+
+	return err
+}
+
+// sortPaths sorts the dotted paths by their position in order, paths missing from it come last
+// and are sorted alphabetically.
+func sortPaths(paths []string, order map[string]int) {
+	sort.SliceStable(paths, func(i, j int) bool {
+		x, xFound := order[paths[i]]
+		y, yFound := order[paths[j]]
+
+		if xFound && yFound {
+			return x < y
+		}
+
+		if xFound != yFound {
+			return xFound
+		}
+
+		return paths[i] < paths[j]
+	})
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortPaths(t *testing.T) {
+	cases := []struct {
+		docs     []string
+		paths    []string
+		expected []string
+	}{
+		{[]string{`{"z": 1, "a": {"y": [1, {"b": 2}], "c": {}}, "m": null}`}, []string{"m", "a.c", "a.y", "z"}, []string{"z", "a.y", "a.c", "m"}},
+		{[]string{`{"z": 1}`, `{"b": 2, "z": 3, "a": 4}`}, []string{"a", "b", "z", "x", "c"}, []string{"z", "b", "a", "c", "x"}},
+		{[]string{`{"z": 1, "a": `}, []string{"a", "z"}, []string{"z", "a"}},
+		{[]string{`[1, 2]`, `"x"`}, []string{"b", "a"}, []string{"a", "b"}},
+	}
+
+	for _, c := range cases {
+		docs := make([][]byte, len(c.docs))
+		for i, doc := range c.docs {
+			docs[i] = []byte(doc)
+		}
+
+		paths := append([]string{}, c.paths...)
+		sortPaths(paths, leafOrder(docs...))
+
+		if !reflect.DeepEqual(paths, c.expected) {
+			t.Errorf("expected paths: %v, but found: %v", c.expected, paths)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// maxPlaceholderDepth is the maximum depth of the placeholders held by the values of environment variables.
+const maxPlaceholderDepth = 10
+
+var (
+	// placeHolderRegex expression must only allow a placeholder with the following rules:
+	// 	- All letters must be in uppercase.
+	// 	- Must start with "${" followed by a letter.
+	// 	- Must contain only letters, numbers or underscores.
+	// 	- Must end with a letter or a number, optionally followed by ":-" then a default value or
+	// 	  by ":?" then an error message, followed by a "}".
+	// 	- May be escaped by an additional leading "$", e.g. $${WORD} stands for the literal ${WORD}.
+	// 	- May refer to a variable of the environment regardless of the prefix with "${env:" followed
+	// 	  by its name made of letters, numbers or underscores, e.g. ${env:HOSTNAME}.
+	// 	- May be transformed by any of prefixTransforms, each followed by a ":", e.g. ${base64decode:CERT}.
+	// 	- May pipe the value of the variable into transforms, each preceded by a "|", before the default
+	// 	  value or the error message if any, e.g. ${REGION|trim|lower:-eu}.
+	placeHolderRegex = regexp.MustCompile("\\$?\\$\\{((?:base64(?:decode)?:)*)(?:env:([A-Za-z_][A-Za-z0-9_]*)|([A-Z][A-Z0-9_]*?[A-Z0-9]))(?:\\|([a-z][a-z0-9]*(?:\\|[a-z][a-z0-9]*)*))?(?::([-?])([^}]*))?\\}")
+
+	// typedPlaceHolderRegex expression matches the JSON strings made of a placeholder of an environment
+	// variable only, e.g. "${PORT}", along with the character preceding them, which is not an escape.
+	typedPlaceHolderRegex = regexp.MustCompile("(^|[^\\\\])\"(" + placeHolderRegex.String() + ")\"")
+
+	// jsonLiteralRegex expression matches the JSON literals that are not strings, i.e. numbers, booleans and null.
+	jsonLiteralRegex = regexp.MustCompile("\\A(?:-?(?:0|[1-9][0-9]*)(?:\\.[0-9]+)?(?:[eE][-+]?[0-9]+)?|true|false|null)\\z")
+
+	// windowsPlaceHolderRegex expression matches the references to environment variables of Windows,
+	// e.g. %APPDATA% or %ProgramFiles(x86)%, as well as %% standing for a literal %.
+	windowsPlaceHolderRegex = regexp.MustCompile("%%|%([A-Za-z_][A-Za-z0-9_()]*)%")
+
+	// schemePlaceHolderRegex expression must only allow a placeholder with the following rules:
+	// 	- Must start with "${" followed by a scheme made of lowercase letters or numbers
+	// 	  starting with a letter.
+	// 	- The scheme must be followed by a ":" then a non-empty reference.
+	// 	- Must end with a "}".
+	// 	- May be escaped by an additional leading "$", e.g. $${azkv:vault/secret}.
+	schemePlaceHolderRegex = regexp.MustCompile("\\$?\\$\\{([a-z][a-z0-9]*):([^}]+)\\}")
+
+	// anyPlaceHolderRegex expression matches the placeholders of environment variables, preferably, and the
+	// ones of schemes, so that both are expanded in a single pass.
+	anyPlaceHolderRegex = regexp.MustCompile(placeHolderRegex.String() + "|" + schemePlaceHolderRegex.String())
+
+	// refPlaceHolderRegex expression matches the placeholders referring to the values of other keys of
+	// the configuration, e.g. ${ref:server.host}, which may be escaped as well e.g. $${ref:server.host}.
+	refPlaceHolderRegex = regexp.MustCompile("\\$?\\$\\{ref:([^}]+)\\}")
+
+	// schemeRegex matches the schemes of placeholders, e.g. azkv.
+	schemeRegex = regexp.MustCompile("\\A[a-z][a-z0-9]*\\z")
+
+	// resolvers maps the schemes of placeholders e.g. ${azkv:vault/secret} to functions resolving
+	// the references of that scheme into values.
+	resolvers = struct {
+		sync.RWMutex
+		funcs map[string]func(ctx context.Context, o *options, ref string) (string, error)
+	}{funcs: map[string]func(ctx context.Context, o *options, ref string) (string, error){
+		"azkv": keyVaultSecret,
+		"exec": commandOutput,
+		"file": fileContent,
+	}}
+
+	// prefixTransforms are the transforms of the pipes that may precede the names of the variables of
+	// placeholders as well, as matched by placeHolderRegex, e.g. ${base64decode:CERT} for ${CERT|base64decode}.
+	prefixTransforms = map[string]bool{"base64": true, "base64decode": true}
+)
+
+// Resolver resolves the reference of a placeholder of a scheme, e.g. secret/db#password for
+// ${vault:secret/db#password}, into its value.
+type Resolver func(ctx context.Context, ref string) (string, error)
+
+// RegisterResolver makes resolver resolve the placeholders of the specified scheme, e.g. vault for
+// ${vault:secret/db#password}, so that applications can inject values of stores that are not supported
+// out of the box. The values are cached along with the others when WithSecretCache is set. It is meant
+// to be called from init functions, and it panics if resolver is nil, if the scheme is not made of
+// letters or numbers starting with a letter, or if it is already supported.
+func RegisterResolver(scheme string, resolver Resolver) {
+	scheme = strings.TrimSuffix(strings.ToLower(scheme), ":")
+
+	if !schemeRegex.MatchString(scheme) || resolver == nil {
+		panic("config: RegisterResolver requires a scheme of letters or numbers and a resolver")
+	}
+
+	resolvers.Lock()
+	defer resolvers.Unlock()
+
+	_, found := resolvers.funcs[scheme]
+	if found || prefixTransforms[scheme] || scheme == "env" || scheme == "ref" {
+		panic(fmt.Sprintf("config: RegisterResolver called twice for scheme [%v]", scheme))
+	}
+
+	resolvers.funcs[scheme] = func(ctx context.Context, _ *options, ref string) (string, error) {
+		return resolver(ctx, ref)
+	}
+}
+
+// expandPlaceholders replaces the placeholders found in doc by their values, placeholders of
+// environment variables e.g. ${PASSWORD} are expanded by expandEnvPlaceholders, while placeholders of a scheme
+// e.g. ${azkv:vault/secret} are resolved by the resolver of that scheme. Placeholders of unknown
+// schemes are left untouched. The values of the latter are cached when WithSecretCache is set.
+// Both are expanded in a single pass, so that the values of placeholders, e.g. secrets holding ${...},
+// are never expanded in turn.
+// Placeholders escaped by an additional leading "$", e.g. $${WORD}, are replaced by their literal
+// text without the escape, ${WORD} here.
+func expandPlaceholders(o *options, doc string, getEnv func(string, string) string) (string, error) {
+	var unresolved []string
+
+	secrets := loadSecretCache(o, doc)
+
+	resolveScheme := func(group string) (string, error) {
+		m := schemePlaceHolderRegex.FindStringSubmatch(group)
+
+		// references to other keys e.g. ${ref:server.host} are resolved once merged.
+		if m[1] == "ref" {
+			return group, nil
+		}
+
+		if isEscaped(group) {
+			return group[1:], nil
+		}
+
+		resolvers.RLock()
+		resolve, found := resolvers.funcs[m[1]]
+		resolvers.RUnlock()
+
+		if !found {
+			if o.strictPlaceholders {
+				unresolved = append(unresolved, group)
+			}
+
+			return group, nil
+		}
+
+		return secrets.resolve(o.ctx, o, m[1], m[2], resolve)
+	}
+
+	doc, names, err := expandAllEnv(o, doc, getEnv, resolveScheme)
+	if err != nil {
+		return "", err
+	}
+
+	secrets.save(o)
+
+	if len(unresolved)+len(names) > 0 {
+		return "", unresolvedError(append(unresolved, names...))
+	}
+
+	return doc, nil
+}
+
+// expandEnvPlaceholders replaces the placeholders of environment variables found in doc by their
+// values looked up by getEnv, which prefixes their names with the environment variable prefix, except
+// for placeholders of the form ${env:HOSTNAME} which refer to variables set regardless of the prefix,
+// e.g. by the platform. As in shells, placeholders of the form ${PORT:-8080} are replaced by their
+// default value, 8080 here, when the variable is either undefined or empty, while placeholders of the
+// form ${TOKEN:?must be set} fail with their message in that case.
+// Values may themselves hold placeholders, e.g. $<envVarPrefix>_BASE_URL may be https://${HOST}:${PORT},
+// which are expanded as well up to maxPlaceholderDepth levels deep, failing on cycles.
+// Values are piped into the transforms following the names of their variables in order, e.g. ${REGION|lower}
+// is replaced by the value of $<envVarPrefix>_REGION in lowercase, see RegisterTransform.
+// Values are finally transformed by the transforms preceding the names of their variables from the
+// nearest to the farthest, e.g. ${base64:TOKEN} is replaced by the base64 encoding of the value of
+// $<envVarPrefix>_TOKEN, while ${base64decode:CERT} is replaced by the decoded value of $<envVarPrefix>_CERT.
+// When WithWindowsPlaceholders is set, references of the form %APPDATA% are expanded first.
+// When WithTypedPlaceholders is set, JSON strings made of a placeholder only, e.g. "${PORT}", whose values
+// are numbers, booleans or null are replaced by these values without the quotes.
+// When WithStrictPlaceholders is set, placeholders of variables that are not defined and have no default
+// value fail, listing them all.
+func expandEnvPlaceholders(o *options, doc string, getEnv func(string, string) string) (string, error) {
+	doc, unresolved, err := expandAllEnv(o, doc, getEnv, nil)
+	if err == nil && len(unresolved) > 0 {
+		err = unresolvedError(unresolved)
+	}
+
+	return doc, err
+}
+
+// expandAllEnv expands the placeholders of environment variables found in doc as expandEnvPlaceholders
+// does, and returns the names of the variables of the unresolved ones when WithStrictPlaceholders is set.
+// The placeholders of schemes are replaced by resolveScheme in the same pass, unless it is nil.
+func expandAllEnv(o *options, doc string, getEnv func(string, string) string, resolveScheme func(group string) (string, error)) (string, []string, error) {
+	var err error
+
+	if o.windowsPlaceholders {
+		doc = expandWindowsEnv(o, doc)
+	}
+
+	if o.typedPlaceholders {
+		doc = typedPlaceHolderRegex.ReplaceAllStringFunc(doc, func(group string) string {
+			m := typedPlaceHolderRegex.FindStringSubmatch(group)
+			if err != nil || isEscaped(m[2]) {
+				return group
+			}
+
+			var val string
+			if val, err = expandEnv(o, m[2], getEnv, nil, nil, nil); err != nil || !jsonLiteralRegex.MatchString(val) {
+				return group
+			}
+
+			return m[1] + val
+		})
+
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	var (
+		unresolved []string
+		track      *[]string
+	)
+
+	if o.strictPlaceholders {
+		track = &unresolved
+	}
+
+	doc, err = expandEnv(o, doc, getEnv, nil, track, resolveScheme)
+
+	return doc, unresolved, err
+}
+
+// expandEnv expands the placeholders of environment variables found in doc, which is the value of
+// the last variable of chain, the names of the variables being expanded. The placeholders of variables
+// that are not defined and have no default value are added to unresolved unless it is nil. The placeholders
+// of schemes are replaced by resolveScheme unless it is nil, the values of variables never being resolved.
+func expandEnv(o *options, doc string, getEnv func(string, string) string, chain []string, unresolved *[]string, resolveScheme func(group string) (string, error)) (string, error) {
+	var err error
+
+	regex := placeHolderRegex
+	if resolveScheme != nil {
+		regex = anyPlaceHolderRegex
+	}
+
+	doc = regex.ReplaceAllStringFunc(doc, func(group string) string {
+		if err != nil {
+			return group
+		}
+
+		m := placeHolderRegex.FindStringSubmatch(group)
+		if m == nil || m[0] != group {
+			var val string
+			if val, err = resolveScheme(group); err != nil {
+				return group
+			}
+
+			return val
+		}
+
+		if isEscaped(group) {
+			return group[1:]
+		}
+
+		name, val := m[2], ""
+		if len(name) > 0 {
+			val, _ = lookupEnv(o, name)
+		} else {
+			name, val = o.envVarPrefix+m[3], getEnv(m[3], "")
+
+			// secrets may be read from the file of $<envVarPrefix>_<name>_FILE instead.
+			if _, found := lookupPrefixedEnv(o, m[3]); !found {
+				if val, _, err = lookupEnvFile(o, m[3]); err != nil {
+					return group
+				}
+			}
+		}
+
+		for _, prev := range chain {
+			if prev == name {
+				err = fmt.Errorf("placeholders of environment variables refer to each other: %v", placeholderChain(append(chain, name)))
+				return group
+			}
+		}
+
+		if len(chain) >= maxPlaceholderDepth {
+			err = fmt.Errorf("placeholders of environment variables are nested deeper than %v levels: %v", maxPlaceholderDepth, placeholderChain(append(chain, name)))
+			return group
+		}
+
+		if len(val) > 0 {
+			if val, err = expandEnv(o, val, getEnv, append(chain[:len(chain):len(chain)], name), unresolved, nil); err != nil {
+				return group
+			}
+		} else {
+			switch m[5] {
+			case "":
+				if unresolved != nil && !isDefined(o, m) {
+					*unresolved = append(*unresolved, "$"+name)
+				}
+			case "-":
+				val = m[6]
+			case "?":
+				if msg := strings.TrimSpace(m[6]); len(msg) > 0 {
+					err = fmt.Errorf("$%v is not set: %v", name, msg)
+				} else {
+					err = fmt.Errorf("$%v is not set", name)
+				}
+				return group
+			}
+		}
+
+		// the transforms preceding the name of the variable follow the pipeline, from the nearest to the farthest.
+		pipeline := m[4]
+		if len(m[1]) > 0 {
+			names := strings.Split(strings.TrimSuffix(m[1], ":"), ":")
+			for i := len(names) - 1; i >= 0; i-- {
+				pipeline = strings.TrimPrefix(pipeline+"|"+names[i], "|")
+			}
+		}
+
+		if val, err = pipe(name, val, pipeline); err != nil {
+			return group
+		}
+
+		return val
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return doc, nil
+}
+
+// expandWindowsEnv replaces the references to environment variables of the form %APPDATA% found in doc
+// by their values regardless of the environment variable prefix, escaped to be injected into JSON strings
+// since they are typically paths holding backslashes. As in batch files, %% stands for a literal %, while
+// references to variables that are not defined are left untouched.
+func expandWindowsEnv(o *options, doc string) string {
+	return windowsPlaceHolderRegex.ReplaceAllStringFunc(doc, func(group string) string {
+		if group == "%%" {
+			return "%"
+		}
+
+		val, found := lookupEnv(o, group[1:len(group)-1])
+		if !found {
+			return group
+		}
+
+		escaped := quote(val)
+
+		return escaped[1 : len(escaped)-1]
+	})
+}
+
+// isDefined reports whether the environment variable of the placeholder matched by m is defined, or the
+// one of the file holding its value for prefixed variables.
+func isDefined(o *options, m []string) bool {
+	if len(m[2]) > 0 {
+		_, found := lookupEnv(o, m[2])
+		return found
+	}
+
+	if _, found := lookupPrefixedEnv(o, m[3]); found {
+		return true
+	}
+
+	_, found := lookupPrefixedEnv(o, m[3]+"_FILE")
+
+	return found
+}
+
+// unresolvedError reports the unresolved placeholders, each once in order.
+func unresolvedError(unresolved []string) error {
+	seen := make(map[string]bool, len(unresolved))
+	names := make([]string, 0, len(unresolved))
+
+	for _, name := range unresolved {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	return fmt.Errorf("unresolved placeholders: %v", strings.Join(names, ", "))
+}
+
+// placeholderChain describes a chain of environment variables referring to each other.
+func placeholderChain(chain []string) string {
+	names := make([]string, len(chain))
+	for i, name := range chain {
+		names[i] = "$" + name
+	}
+
+	return strings.Join(names, " -> ")
+}
+
+// isEscaped reports whether the placeholder is escaped by an additional leading "$".
+func isEscaped(placeholder string) bool {
+	return strings.HasPrefix(placeholder, "$$")
+}
+
+// encodeBase64 encodes val in standard base64.
+func encodeBase64(val string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(val)), nil
+}
+
+// decodeBase64 decodes val, which may be encoded in standard or URL base64 with or without padding.
+func decodeBase64(val string) (string, error) {
+	val = strings.TrimSpace(val)
+
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		decoded, err := enc.DecodeString(val)
+		if err != nil {
+			continue
+		}
+
+		return string(decoded), nil
+	}
+
+	return "", errors.New("invalid base64 value")
+}
@@ -0,0 +1,319 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCliPlaceholderOperators(t *testing.T) {
+	cases := []struct {
+		env      map[string]string
+		config   string
+		expected testConf
+		err      string
+	}{
+		{nil, `{"id": ${ID:-8080}, "name": "${NAME:-Frank}"}`, testConf{ID: 8080, Name: "Frank"}, ""},
+		{map[string]string{"PH_ID": "9", "PH_NAME": "Grace"}, `{"id": ${ID:-8080}, "name": "${NAME:-Frank}"}`, testConf{ID: 9, Name: "Grace"}, ""},
+		{map[string]string{"PH_NAME": ""}, `{"id": 1, "name": "${NAME:-Frank Jr.: the 2nd}"}`, testConf{ID: 1, Name: "Frank Jr.: the 2nd"}, ""},
+		{nil, `{"id": 1, "name": "${NAME:-}"}`, testConf{ID: 1}, ""},
+		{nil, `{"id": ${ID}, "name": "${NAME}"}`, testConf{}, "invalid character"},
+		{map[string]string{"PH_NAME": "Heidi"}, `{"id": 1, "name": "${NAME:?the name must be set}"}`, testConf{ID: 1, Name: "Heidi"}, ""},
+		{nil, `{"id": 1, "name": "${NAME:?the name must be set}"}`, testConf{}, "$PH_NAME is not set: the name must be set"},
+		{map[string]string{"PH_NAME": ""}, `{"id": 1, "name": "${NAME:?}"}`, testConf{}, "$PH_NAME is not set"},
+		{map[string]string{"PH_NAME": "https://${HOST}:${PORT:-443}", "PH_HOST": "${DOMAIN}", "PH_DOMAIN": "example.com"}, `{"id": 1, "name": "${NAME}"}`, testConf{ID: 1, Name: "https://example.com:443"}, ""},
+		{map[string]string{"PH_NAME": "${HOST}", "PH_HOST": "${NAME}"}, `{"id": 1, "name": "${NAME}"}`, testConf{}, "placeholders of environment variables refer to each other: $PH_NAME -> $PH_HOST -> $PH_NAME"},
+		{map[string]string{"PH_NAME": "${NAME}"}, `{"id": 1, "name": "${NAME}"}`, testConf{}, "placeholders of environment variables refer to each other: $PH_NAME -> $PH_NAME"},
+		{map[string]string{"PH_NAME": "${NAME:?unset}x", "PH_ID": "${ID}"}, `{"id": 1, "name": "${ID:-x}"}`, testConf{}, "refer to each other: $PH_ID -> $PH_ID"},
+		{map[string]string{"PH_NAME": "Ivan"}, `{"id": 1, "name": "$${NAME} is ${NAME}, $${azkv:vault/secret} $${PORT:-80}"}`, testConf{ID: 1, Name: "${NAME} is Ivan, ${azkv:vault/secret} ${PORT:-80}"}, ""},
+		{map[string]string{"PH_NAME": "$${HOST}", "PH_HOST": "x"}, `{"id": 1, "name": "${NAME}"}`, testConf{ID: 1, Name: "${HOST}"}, ""},
+		{map[string]string{"PH_TEST_HOSTNAME": "prefixed", "PH_TEST_HOSTNAME_RAW": "raw"}, `{"id": 1, "name": "${env:PH_TEST_HOSTNAME_RAW}/${env:PH_TEST_MISSING:-none}/$${env:PH_TEST_HOSTNAME}"}`, testConf{ID: 1, Name: "raw/none/${env:PH_TEST_HOSTNAME}"}, ""},
+		{map[string]string{"PH_NAME": "${env:PH_HOST}", "PH_HOST": "${NAME}"}, `{"id": 1, "name": "${NAME}"}`, testConf{}, "placeholders of environment variables refer to each other: $PH_NAME -> $PH_HOST -> $PH_NAME"},
+		{nil, `{"id": 1, "name": "${env:PH_TEST_MISSING:?must be set by the platform}"}`, testConf{}, "$PH_TEST_MISSING is not set: must be set by the platform"},
+		{map[string]string{"PH_NAME": "a\"b"}, `{"id": 1, "name": "${base64:NAME}"}`, testConf{ID: 1, Name: "YSJi"}, ""},
+		{map[string]string{"PH_NAME": "bGluZSAxCmxpbmUgIjIiIDw+"}, `{"id": 1, "name": "${base64decode:NAME}"}`, testConf{ID: 1, Name: "line 1\nline \"2\" <>"}, ""},
+		{map[string]string{"PH_TEST_CERT": "Q0VSVA"}, `{"id": 1, "name": "${base64decode:env:PH_TEST_CERT}/${base64decode:NAME:-SGk=}"}`, testConf{ID: 1, Name: "CERT/Hi"}, ""},
+		{map[string]string{"PH_NAME": "Judy\n"}, `{"id": 1, "name": "${base64decode:base64:NAME}/$${base64:NAME}"}`, testConf{ID: 1, Name: "Judy\n/${base64:NAME}"}, ""},
+		{map[string]string{"PH_NAME": "not base64!"}, `{"id": 1, "name": "${base64decode:NAME}"}`, testConf{}, "failed to transform the value of $PH_NAME by [base64decode]: invalid base64 value"},
+		{map[string]string{"PH_V0": "${V1}", "PH_V1": "${V2}", "PH_V2": "${V3}", "PH_V3": "${V4}", "PH_V4": "${V5}", "PH_V5": "${V6}",
+			"PH_V6": "${V7}", "PH_V7": "${V8}", "PH_V8": "${V9}", "PH_V9": "${V10}", "PH_V10": "${V11}", "PH_V11": "deep"},
+			`{"id": 1, "name": "${V0}"}`, testConf{}, "placeholders of environment variables are nested deeper than 10 levels: $PH_V0 -> $PH_V1"},
+	}
+
+	for i, c := range cases {
+		c := c
+
+		// the cases are isolated from the environment of the process, and from each other.
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+
+			conf := &testConf{}
+
+			_, err := Parse("PH", "", nil, conf, WithEnvironment(c.env), WithArgs("", "-config", c.config))
+
+			if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+				t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+			}
+		})
+	}
+}
+
+func TestCliTypedPlaceholders(t *testing.T) {
+	cases := []struct {
+		env      map[string]string
+		config   string
+		expected testConf
+		err      string
+	}{
+		{map[string]string{"PH_ID": "8080", "PH_ONLINE": "true", "PH_NAME": "Grace"}, `{"id": "${ID}", "name": "${NAME}", "online": "${ONLINE}"}`, testConf{ID: 8080, Name: "Grace", Online: true}, ""},
+		{map[string]string{"PH_ID": "-1.5e1"}, `{"id": "${ID:-0}", "name": "${env:PH_MISSING:-null}", "online": "${ONLINE:-false}"}`, testConf{}, "cannot unmarshal number -1.5e1"},
+		{map[string]string{"PH_ID": "${PORT}", "PH_PORT": "443", "PH_NAME": "8080 "}, `{"id": "${ID}", "name": "${NAME}", "online": false}`, testConf{ID: 443, Name: "8080 "}, ""},
+		{map[string]string{"PH_ID": "1", "PH_NAME": "2"}, `{"id": ${ID}, "name": "$${NAME}, \"${NAME}\", ${NAME}"}`, testConf{ID: 1, Name: "${NAME}, \"2\", 2"}, ""},
+		{nil, `{"id": 2, "name": "${NAME:-null}", "online": "${ONLINE:-true}"}`, testConf{ID: 2, Online: true}, ""},
+		{map[string]string{"PH_NAME": "0x10"}, `{"id": 1, "name": "${NAME}"}`, testConf{ID: 1, Name: "0x10"}, ""},
+		{nil, `{"id": "${ID:?must be set}"}`, testConf{}, "$PH_ID is not set: must be set"},
+	}
+
+	for i, c := range cases {
+		c := c
+
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+
+			conf := &testConf{}
+
+			_, err := Parse("PH", "", nil, conf, WithTypedPlaceholders(), WithEnvironment(c.env), WithArgs("", "-config", c.config))
+
+			if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+				t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+			}
+		})
+	}
+}
+
+func TestCliWindowsPlaceholders(t *testing.T) {
+	env := map[string]string{"APPDATA": `C:\Users\Ada "Admin"\AppData`, "ProgramFiles(x86)": `C:\Program Files (x86)`, "PH_ID": "3"}
+
+	cases := []struct {
+		config   string
+		opts     []Option
+		expected testConf
+		err      string
+	}{
+		{`{"id": ${ID}, "name": "%APPDATA%\\app;%ProgramFiles(x86)%"}`, nil, testConf{ID: 3, Name: `C:\Users\Ada "Admin"\AppData\app;C:\Program Files (x86)`}, ""},
+		{`{"id": 1, "name": "100%% of %MISSING% %%APPDATA%% 50%"}`, nil, testConf{ID: 1, Name: "100% of %MISSING% %APPDATA% 50%"}, ""},
+		{`{"id": 1, "name": "%appdata%"}`, nil, testConf{ID: 1, Name: "%appdata%"}, ""},
+		{`{"id": 1, "name": "%appdata%"}`, []Option{WithCaseInsensitiveEnv()}, testConf{ID: 1, Name: `C:\Users\Ada "Admin"\AppData`}, ""},
+	}
+
+	for i, c := range cases {
+		c := c
+
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+
+			conf := &testConf{}
+
+			_, err := Parse("PH", "", nil, conf, append(c.opts, WithWindowsPlaceholders(), WithEnvironment(env), WithArgs("", "-config", c.config))...)
+
+			if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+				t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+			}
+		})
+	}
+
+	conf := &testConf{}
+
+	if _, err := Parse("PH", "", nil, conf, WithEnvironment(env), WithArgs("", "-config", `{"id": 1, "name": "%APPDATA%"}`)); err != nil || conf.Name != "%APPDATA%" {
+		t.Errorf("expected references left untouched by default, but found: (%+v, %v)", *conf, err)
+	}
+}
+
+func TestCliStrictPlaceholders(t *testing.T) {
+	cases := []struct {
+		env      map[string]string
+		config   string
+		expected testConf
+		err      string
+	}{
+		{map[string]string{"PH_ID": "1", "PH_NAME": ""}, `{"id": ${ID}, "name": "${NAME}${HOST:-}$${PORT}"}`, testConf{ID: 1, Name: "${PORT}"}, ""},
+		{map[string]string{"PH_ID": "${PORT}"}, `{"id": ${ID}, "name": "${HOST}:${PORT}/${env:PH_TEST_PATH}${base64:HOST} ${vault:secret} $${ssm:x}"}`, testConf{},
+			"unresolved placeholders: ${vault:secret}, $PH_PORT, $PH_HOST, $PH_TEST_PATH"},
+		{nil, `{"id": 1, "name": "${NAME:?must be set} ${HOST}"}`, testConf{}, "$PH_NAME is not set: must be set"},
+	}
+
+	for i, c := range cases {
+		c := c
+
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+
+			conf := &testConf{}
+
+			_, err := Parse("PH", "", nil, conf, WithStrictPlaceholders(), WithEnvironment(c.env), WithArgs("", "-config", c.config))
+
+			if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+				t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+			}
+		})
+	}
+}
+
+func TestCliResolvedPlaceholders(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret")
+
+	if err := os.WriteFile(secret, []byte("abc${HOME_X}def$${Y}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &testConf{}
+
+	// values of placeholders holding placeholders in turn are left untouched.
+	_, err := Parse("ZZ", "", nil, conf, WithEnvironment(map[string]string{"ZZ_HOME_X": "LEAK", "ZZ_ID": "7"}),
+		WithArgs("", "-config", `{"id": ${ID}, "name": "${file:`+secret+`}"}`))
+
+	if expected := (testConf{ID: 7, Name: "abc${HOME_X}def$${Y}"}); err != nil || *conf != expected {
+		t.Errorf("expected output: (%+v, <nil>), but found: (%+v, %v)", expected, *conf, err)
+	}
+}
+
+func TestCliCustomResolver(t *testing.T) {
+	secrets := map[string]string{"secret/db#password": "s3cr3t"}
+
+	RegisterResolver("Vault:", func(ctx context.Context, ref string) (string, error) {
+		if val, found := secrets[ref]; found {
+			return val, nil
+		}
+		return "", errors.New("no such secret")
+	})
+
+	defer func() {
+		resolvers.Lock()
+		delete(resolvers.funcs, "vault")
+		resolvers.Unlock()
+	}()
+
+	cases := []struct {
+		config   string
+		expected testConf
+		err      string
+	}{
+		{`{"id": 1, "name": "${vault:secret/db#password}"}`, testConf{ID: 1, Name: "s3cr3t"}, ""},
+		{`{"id": 1, "name": "$${vault:secret/db#password}"}`, testConf{ID: 1, Name: "${vault:secret/db#password}"}, ""},
+		{`{"id": 1, "name": "${vault:secret/missing}"}`, testConf{}, "no such secret"},
+		{`{"id": 1, "name": "${ssm:param}"}`, testConf{ID: 1, Name: "${ssm:param}"}, ""},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := Parse("RES", "", nil, conf, WithArgs("", "-config", c.config))
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+
+	for _, scheme := range []string{"VAULT", "file", "env", "ref", "base64", "", "1st", "s-3"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected RegisterResolver to panic for scheme [%v]", scheme)
+				}
+			}()
+
+			RegisterResolver(scheme, func(ctx context.Context, ref string) (string, error) { return "", nil })
+		}()
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected RegisterResolver to panic for nil resolver")
+			}
+		}()
+
+		RegisterResolver("ssm", nil)
+	}()
+}
+
+func TestCliPlaceholderTransforms(t *testing.T) {
+	RegisterTransform("slug", func(val string) (string, error) {
+		if len(val) == 0 {
+			return "", errors.New("empty value")
+		}
+		return strings.ReplaceAll(strings.ToLower(val), "/", "-"), nil
+	})
+
+	defer func() {
+		pipes.Lock()
+		delete(pipes.funcs, "slug")
+		pipes.Unlock()
+	}()
+
+	env := map[string]string{"PIPE_HOST": "Web-1", "PIPE_REGION": "  EU-West ", "PIPE_BRANCH": "Feature/X", "PIPE_EMPTY": "", "HOSTNAME": "node",
+		"PIPE_CERT": "bGluZTEKbGluZTI=", "PIPE_QUOTED": "IGEgImIiIFxjCg=="}
+
+	cases := []struct {
+		config   string
+		expected testConf
+		err      string
+	}{
+		{`{"id": 1, "name": "${HOST|upper}"}`, testConf{ID: 1, Name: "WEB-1"}, ""},
+		{`{"id": 1, "name": "${REGION|trim|lower}"}`, testConf{ID: 1, Name: "eu-west"}, ""},
+		{`{"id": 1, "name": "${MISSING|upper:-default}"}`, testConf{ID: 1, Name: "DEFAULT"}, ""},
+		{`{"id": 1, "name": "${env:HOSTNAME|upper}"}`, testConf{ID: 1, Name: "NODE"}, ""},
+		{`{"id": 1, "name": "${base64:HOST|lower}"}`, testConf{ID: 1, Name: "d2ViLTE="}, ""},
+		{`{"id": 1, "name": "${HOST|lower|base64}"}`, testConf{ID: 1, Name: "d2ViLTE="}, ""},
+		{`{"id": 1, "name": "${HOST|base64|base64decode|upper}"}`, testConf{ID: 1, Name: "WEB-1"}, ""},
+		{`{"id": 1, "name": "${CERT|base64decode|upper}"}`, testConf{ID: 1, Name: "LINE1\nLINE2"}, ""},
+		{`{"id": 1, "name": "${CERT|base64decode|base64}"}`, testConf{ID: 1, Name: "bGluZTEKbGluZTI="}, ""},
+		{`{"id": 1, "name": "${base64decode:CERT}/${QUOTED|base64decode|trim}"}`, testConf{ID: 1, Name: "line1\nline2/a \"b\" \\c"}, ""},
+		{`{"id": 1, "name": "${BRANCH|slug}"}`, testConf{ID: 1, Name: "feature-x"}, ""},
+		{`{"id": 1, "name": "$${HOST|upper}"}`, testConf{ID: 1, Name: "${HOST|upper}"}, ""},
+		{`{"id": 1, "name": "${HOST|reverse}"}`, testConf{}, "unknown transform [reverse] of the placeholder of $PIPE_HOST"},
+		{`{"id": 1, "name": "${EMPTY|slug}"}`, testConf{}, "failed to transform the value of $PIPE_EMPTY by [slug]: empty value"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := Parse("PIPE", "", nil, conf, WithEnvironment(env), WithArgs("", "-config", c.config))
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+
+	for _, name := range []string{"upper", "slug", "Slug", "", "1st"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected RegisterTransform to panic for name [%v]", name)
+				}
+			}()
+
+			RegisterTransform(name, func(val string) (string, error) { return val, nil })
+		}()
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Layer is a layer of the configuration in a precedence chain, see WithPrecedence.
+type Layer string
+
+const (
+	// LayerDefaults is the default configuration embedded in the binary, see WithEmbeddedDefault.
+	LayerDefaults Layer = "defaults"
+
+	// LayerFile is the configuration read from the local files among the configuration URIs, i.e. of the file scheme.
+	LayerFile Layer = "file"
+
+	// LayerEnv is the configuration JSON string of $<envVarPrefix>_CONFIG, or the document of the file
+	// $<envVarPrefix>_CONFIG_FILE points at.
+	LayerEnv Layer = "env"
+
+	// LayerFlags is the configuration JSON string of the -config command line option.
+	LayerFlags Layer = "flags"
+
+	// LayerRemote is the configuration fetched from the configuration URIs of the other schemes.
+	LayerRemote Layer = "remote"
+)
+
+// DefaultPrecedence is the conventional precedence chain, from the lowest precedence to the highest.
+var DefaultPrecedence = []Layer{LayerDefaults, LayerFile, LayerEnv, LayerFlags, LayerRemote}
+
+// Precedence returns the precedence chain declared by opts, from the lowest precedence to the highest,
+// or nil if none is declared.
+func Precedence(opts ...Option) []Layer {
+	return append([]Layer(nil), newOptions(opts).precedence...)
+}
+
+// loadChain loads every layer of the precedence chain of o that is available, and deep merges them in
+// order such that the values of a layer override the ones of the layers before it. The flagConfig is the
+// JSON string of the command line, nil if not specified, and the uris are split into the file and remote
+// layers by their scheme, while all the layers share the specified format if any.
+func loadChain(o *options, getEnv func(key, defVal string) string, flagConfig *string, uris []string, format string) ([]byte, error) {
+	var files, remotes []string
+
+	for _, uri := range uris {
+		if strings.HasPrefix(strings.ToLower(uri), "file:") {
+			files = append(files, uri)
+		} else {
+			remotes = append(remotes, uri)
+		}
+	}
+
+	seen := make(map[Layer]bool, len(o.precedence))
+
+	var merged interface{} = make(map[string]interface{})
+
+	for _, layer := range o.precedence {
+		if seen[layer] {
+			return nil, fmt.Errorf("configuration layer [%v] is listed more than once in the precedence chain", layer)
+		}
+		seen[layer] = true
+
+		var doc []byte
+		var err error
+
+		switch layer {
+		case LayerDefaults:
+			if o.defaultFS == nil {
+				continue
+			}
+			doc, err = embeddedDefault(o, getEnv)
+		case LayerFile, LayerRemote:
+			group := files
+			if layer == LayerRemote {
+				group = remotes
+			}
+
+			if len(group) == 0 {
+				continue
+			}
+
+			var f string
+			if doc, f, err = load(o, group, format); err == nil {
+				doc, err = interpret(o, f, string(doc), getEnv)
+			}
+		case LayerEnv:
+			f := format
+
+			val, found := lookupPrefixedEnv(o, "CONFIG")
+			if !found {
+				var file string
+				if val, file, found, err = configFile(o); err != nil {
+					return nil, err
+				} else if !found {
+					continue
+				}
+
+				if len(f) == 0 {
+					f = formatOf(file)
+				}
+			}
+			doc, err = interpret(o, f, val, getEnv)
+		case LayerFlags:
+			if flagConfig == nil {
+				continue
+			}
+			doc, err = interpret(o, format, *flagConfig, getEnv)
+		default:
+			return nil, fmt.Errorf("unsupported configuration layer [%v], supported layers are: defaults, env, file, flags, remote", layer)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		var val interface{}
+		if err = json.Unmarshal(doc, &val); err != nil {
+			return nil, fmt.Errorf("invalid %v configuration layer: %v", layer, err)
+		}
+
+		merged = deepMerge(merged, val)
+	}
+
+	return json.Marshal(merged)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCliPrecedence(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"online": false}`))
+	}))
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "api.json")
+	if err := os.WriteFile(file, []byte(`{"id": 36, "name": "file", "online": true}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := fstest.MapFS{"defaults.json": {Data: []byte(`{"id": 35, "name": "defaults"}`)}}
+
+	cases := []struct {
+		env        string
+		args       []string
+		precedence []Layer
+		expected   testConf
+		err        string
+	}{
+		{`{"name": "env"}`, []string{"-config-uri", "file://" + file}, DefaultPrecedence, testConf{ID: 36, Name: "env", Online: true}, ""},
+		{`{"name": "env"}`, []string{"-config", `{"name": "flags"}`, "-config-uri", "file://" + file + " " + srv.URL}, DefaultPrecedence, testConf{ID: 36, Name: "flags"}, ""},
+		{`{"name": "env"}`, []string{"-config", `{"name": "flags"}`, "-config-uri", "file://" + file}, []Layer{LayerFlags, LayerEnv, LayerFile}, testConf{ID: 36, Name: "file", Online: true}, ""},
+		{"", nil, DefaultPrecedence, testConf{ID: 35, Name: "defaults"}, ""},
+		{"", nil, []Layer{LayerEnv, LayerEnv}, testConf{}, "configuration layer [env] is listed more than once in the precedence chain"},
+		{"", nil, []Layer{"cli"}, testConf{}, "unsupported configuration layer [cli], supported layers are: defaults, env, file, flags, remote"},
+		{`{"id": `, nil, DefaultPrecedence, testConf{}, "invalid env configuration layer"},
+	}
+
+	for _, c := range cases {
+		if len(c.env) > 0 {
+			t.Setenv("TEST_CONFIG", c.env)
+		} else {
+			os.Unsetenv("TEST_CONFIG")
+		}
+
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: append([]string{""}, c.args...)}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf, WithEmbeddedDefault(fsys, "defaults.json"), WithPrecedence(c.precedence...))
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+
+	if layers := Precedence(WithPrecedence(DefaultPrecedence...)); !reflect.DeepEqual(layers, DefaultPrecedence) {
+		t.Errorf("expected precedence: %v, but found: %v", DefaultPrecedence, layers)
+	}
+
+	if layers := Precedence(); layers != nil {
+		t.Errorf("expected no precedence, but found: %v", layers)
+	}
+}
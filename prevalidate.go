@@ -0,0 +1,46 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// preValidate checks that doc written in the specified format decodes into the configuration
+// structure before any placeholder of a scheme is resolved, which may consume the quota of a
+// secret store or a remote service. Placeholders of environment variables are expanded, while
+// placeholders of schemes are taken as the strings they are.
+func preValidate(o *options, format, doc string, getEnv func(string, string) string) error {
+	doc, err := expandEnvPlaceholders(o, doc, getEnv)
+	if err != nil {
+		return err
+	}
+
+	data, err := toJSON(o, format, []byte(strings.TrimSpace(doc)))
+	if err == nil {
+		err = json.Unmarshal(data, reflect.New(o.confType).Interface())
+	}
+
+	if err != nil {
+		return fmt.Errorf("configuration is malformed, checked before resolving placeholders: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCliPreValidation(t *testing.T) {
+	var resolved int
+
+	resolvers.funcs["fake"] = func(ctx context.Context, o *options, ref string) (string, error) {
+		resolved++
+		return "Dave", nil
+	}
+	defer delete(resolvers.funcs, "fake")
+
+	t.Setenv("TEST_ID", "44")
+
+	cases := []struct {
+		config   string
+		opts     []Option
+		expected testConf
+		err      string
+		resolved int
+	}{
+		{`{"id": ${ID}, "name": "${fake:name}"}`, []Option{WithPreValidation()}, testConf{ID: 44, Name: "Dave"}, "", 1},
+		{`{"id": "${ID}", "name": "${fake:name}"}`, []Option{WithPreValidation()}, testConf{}, "configuration is malformed, checked before resolving placeholders: json: cannot unmarshal string", 0},
+		{`{"id": "${ID}", "name": "${fake:name}"}`, nil, testConf{Name: "Dave"}, "json: cannot unmarshal string", 1},
+		{`{"id": ${ID}, "name": "${fake:name}"`, []Option{WithPreValidation()}, testConf{}, "configuration is malformed", 0},
+	}
+
+	for _, c := range cases {
+		resolved = 0
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config", c.config}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf, c.opts...)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected || resolved != c.resolved {
+			t.Errorf("expected output: (%+v, %v) with %v resolutions, but found: (%+v, %v) with %v resolutions", c.expected, c.err, c.resolved, *conf, err, resolved)
+		}
+	}
+}
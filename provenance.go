@@ -0,0 +1,170 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Origin is the source the value of an option of the configuration comes from.
+type Origin string
+
+const (
+	// OriginDefault is the value the configuration structure holds before being loaded, including the ones
+	// of the default tags and of the SetDefaults methods.
+	OriginDefault Origin = "default"
+
+	// OriginDocument is the value of the configuration documents.
+	OriginDocument Origin = "document"
+
+	// OriginEnv is the value of the environment variable bound to the field of the option.
+	OriginEnv Origin = "env"
+
+	// OriginSpread is the value spread across instances by the spread tag of the field of the option.
+	OriginSpread Origin = "spread"
+
+	// OriginEmergency is the value of the break-glass configuration of $<envVarPrefix>_EMERGENCY_CONFIG.
+	OriginEmergency Origin = "emergency"
+)
+
+// Provenance maps the dotted JSON paths of the options of the configuration, e.g. tls.cert, to the sources
+// of their values. Arrays are options as a whole.
+type Provenance map[string]Origin
+
+// ValidationHook checks the loaded configuration structure conf as a whole, knowing where each of its
+// options comes from, see WithValidationHook.
+type ValidationHook func(conf interface{}, provenance Provenance) error
+
+// provenanceTracker records the sources of the options of the configuration while Parse loads it.
+type provenanceTracker struct {
+	provenance Provenance
+
+	// leaves are the options of the configuration structure, keyed by their dotted JSON paths, as of the
+	// last recorded source, and given are the ones of the configuration document.
+	leaves, given map[string]interface{}
+}
+
+// newProvenanceTracker returns a tracker of the sources of the options of the configuration, defaults being
+// the configuration structure encoded as JSON before it is loaded from the configuration document doc, or nil
+// when neither validation hooks are set nor the sources are asked for, since they are of no use then.
+func newProvenanceTracker(o *options, defaults, doc []byte) (*provenanceTracker, error) {
+	if len(o.validationHooks) == 0 && o.provenance == nil {
+		return nil, nil
+	}
+
+	t := &provenanceTracker{
+		provenance: make(Provenance),
+		leaves:     make(map[string]interface{}),
+		given:      make(map[string]interface{}),
+	}
+
+	for _, f := range []struct {
+		doc    []byte
+		leaves map[string]interface{}
+	}{{defaults, t.leaves}, {doc, t.given}} {
+		var val interface{}
+		if err := json.Unmarshal(f.doc, &val); err != nil {
+			return nil, fmt.Errorf("failed to track the sources of the configuration: %v", err)
+		}
+
+		flatten("", val, f.leaves)
+	}
+
+	for path := range t.leaves {
+		t.provenance[path] = OriginDefault
+	}
+
+	return t, nil
+}
+
+// record attributes the options of the configuration structure conf changed since the last recorded source
+// to source, as well as the ones given by the configuration document when source is OriginDocument, even
+// if they hold their default values.
+func (t *provenanceTracker) record(source Origin, conf interface{}) error {
+	if t == nil {
+		return nil
+	}
+
+	values, err := json.Marshal(conf)
+	if err != nil {
+		return fmt.Errorf("failed to track the sources of the configuration: %v", err)
+	}
+
+	var val interface{}
+	if err = json.Unmarshal(values, &val); err != nil {
+		return fmt.Errorf("failed to track the sources of the configuration: %v", err)
+	}
+
+	leaves := make(map[string]interface{})
+	flatten("", val, leaves)
+
+	for path, val := range leaves {
+		_, given := t.given[path]
+
+		if prev, found := t.leaves[path]; !found || !jsonEqual(prev, val) || (source == OriginDocument && given) {
+			t.provenance[path] = source
+		} else if _, found = t.provenance[path]; !found {
+			t.provenance[path] = OriginDefault
+		}
+	}
+
+	// options removed, e.g. pointers set to null by the document, are no longer part of the configuration.
+	for path := range t.provenance {
+		if _, found := leaves[path]; !found {
+			delete(t.provenance, path)
+		}
+	}
+
+	t.leaves = leaves
+
+	return nil
+}
+
+// callValidationHooks calls the validation hooks of WithValidationHook in the order they are set with the
+// configuration structure conf and the sources of its options recorded by t, and fails listing every failure.
+// The sources are handed to the caller of Parse as well when asked for.
+func callValidationHooks(o *options, t *provenanceTracker, conf interface{}) error {
+	if t == nil {
+		return nil
+	}
+
+	if o.provenance != nil {
+		*o.provenance = t.provenance
+	}
+
+	var failures []string
+
+	for _, hook := range o.validationHooks {
+		// hooks are handed a copy, so that they cannot alter the sources seen by the ones after them.
+		provenance := make(Provenance, len(t.provenance))
+		for path, source := range t.provenance {
+			provenance[path] = source
+		}
+
+		if err := hook(conf, provenance); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("invalid configuration: %v", strings.Join(failures, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type provenanceConf struct {
+	Name    string `json:"name"`
+	Workers int    `json:"workers" default:"4"`
+	Port    int    `json:"port" env:"PORT"`
+	TLS     struct {
+		Enabled bool   `json:"enabled"`
+		Cert    string `json:"cert"`
+	} `json:"tls"`
+}
+
+func TestCliValidationHook(t *testing.T) {
+	var seen Provenance
+
+	requireCert := func(conf interface{}, provenance Provenance) error {
+		seen = provenance
+
+		if c := conf.(*provenanceConf); c.TLS.Enabled && len(c.TLS.Cert) == 0 {
+			return errors.New("[tls.cert] is required when [tls.enabled] is true")
+		}
+
+		return nil
+	}
+
+	_, err := Parse("PROVENANCE", "", nil, &provenanceConf{}, WithEnvironment(map[string]string{"PROVENANCE_PORT": "80"}),
+		WithArgs("", "-config", `{"name": "app", "workers": 4, "tls": {"enabled": true, "cert": "cert.pem"}}`), WithValidationHook(requireCert))
+
+	if err != nil {
+		t.Fatalf("expected no error, but found: %v", err)
+	}
+
+	expected := Provenance{
+		"name":        OriginDocument,
+		"workers":     OriginDocument,
+		"port":        OriginEnv,
+		"tls.enabled": OriginDocument,
+		"tls.cert":    OriginDocument,
+	}
+
+	if !reflect.DeepEqual(seen, expected) {
+		t.Errorf("expected provenance: %v, but found: %v", expected, seen)
+	}
+
+	_, err = Parse("PROVENANCE", "", nil, &provenanceConf{}, WithEnvironment(nil),
+		WithArgs("", "-config", `{"tls": {"enabled": true}}`), WithValidationHook(requireCert), WithValidationHook(func(interface{}, Provenance) error {
+			return errors.New("[name] must be set")
+		}))
+
+	if expected := "invalid configuration: [tls.cert] is required when [tls.enabled] is true, [name] must be set"; err == nil || err.Error() != expected {
+		t.Errorf("expected error: %v, but found: %v", expected, err)
+	}
+
+	expected = Provenance{
+		"name":        OriginDefault,
+		"workers":     OriginDefault,
+		"port":        OriginDefault,
+		"tls.enabled": OriginDocument,
+		"tls.cert":    OriginDefault,
+	}
+
+	if !reflect.DeepEqual(seen, expected) {
+		t.Errorf("expected provenance: %v, but found: %v", expected, seen)
+	}
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Receipt is the evidence of the configuration an instance of an application loaded, emitted signed by
+// Parse when WithReceipt is set.
+type Receipt struct {
+	// ConfigHash is the SHA-256 digest of the configuration structure encoded as JSON, in the form
+	// sha256:<hex digest>.
+	ConfigHash string `json:"configHash"`
+
+	// Sources are the URIs the configuration was loaded from without their credentials, empty if it
+	// was read from the command line or the environment.
+	Sources []string `json:"sources"`
+
+	// Version is the release version of the application, if known.
+	Version string `json:"version,omitempty"`
+
+	// Hostname and PID identify the instance that loaded the configuration.
+	Hostname string `json:"hostname"`
+	PID      int    `json:"pid"`
+
+	// Timestamp is the time the configuration was loaded.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// signedReceipt is the envelope of a signed receipt, the signature being computed over the payload,
+// the receipt encoded as JSON, before it is encoded in base64.
+type signedReceipt struct {
+	Algorithm string `json:"alg"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// emitReceipt signs the receipt of the configuration doc, encoded as JSON, loaded from uris with the key set by WithReceipt,
+// and writes it to the receipt sink, a file path or an http(s) URL it is posted to.
+func emitReceipt(o *options, info *ReleaseInfo, uris []string, doc []byte) error {
+	sum := sha256.Sum256(doc)
+	hostname, _ := os.Hostname()
+
+	receipt := &Receipt{
+		ConfigHash: "sha256:" + hex.EncodeToString(sum[:]),
+		Sources:    make([]string, 0, len(uris)),
+		Hostname:   hostname,
+		PID:        os.Getpid(),
+		Timestamp:  time.Now().UTC(),
+	}
+
+	if info != nil {
+		receipt.Version = info.ReleaseVersion
+	}
+
+	for _, uri := range uris {
+		for _, alt := range strings.Split(uri, "|") {
+			if u, err := parseURI(alt); err == nil {
+				receipt.Sources = append(receipt.Sources, u.Redacted())
+			}
+		}
+	}
+
+	payload, err := json.Marshal(receipt)
+	if err != nil {
+		return err
+	}
+
+	alg, sig, err := signReceipt(o.receiptKey, payload)
+	if err != nil {
+		return fmt.Errorf("failed to sign the configuration receipt: %v", err)
+	}
+
+	data, err := json.Marshal(&signedReceipt{
+		Algorithm: alg,
+		Payload:   base64.RawURLEncoding.EncodeToString(payload),
+		Signature: base64.RawURLEncoding.EncodeToString(sig),
+	})
+	if err != nil {
+		return err
+	}
+
+	if sink := strings.ToLower(o.receiptSink); strings.HasPrefix(sink, "http://") || strings.HasPrefix(sink, "https://") {
+		req, err := http.NewRequestWithContext(o.ctx, http.MethodPost, o.receiptSink, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to send the configuration receipt: %v", err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+
+		if _, err = doRequest(o.httpClient, req); err != nil {
+			return fmt.Errorf("failed to send the configuration receipt: %v", err)
+		}
+
+		return nil
+	}
+
+	if err = os.MkdirAll(filepath.Dir(o.receiptSink), 0700); err == nil {
+		err = os.WriteFile(o.receiptSink, append(data, '\n'), 0600)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write the configuration receipt: %v", err)
+	}
+
+	return nil
+}
+
+// signReceipt signs payload with key, and returns the signature along with the name of its algorithm.
+func signReceipt(key crypto.Signer, payload []byte) (string, []byte, error) {
+	digest := sha256.Sum256(payload)
+
+	switch key.Public().(type) {
+	case ed25519.PublicKey:
+		sig, err := key.Sign(rand.Reader, payload, crypto.Hash(0))
+		return "EdDSA", sig, err
+	case *ecdsa.PublicKey:
+		sig, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+		return "ES256", sig, err
+	case *rsa.PublicKey:
+		sig, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+		return "RS256", sig, err
+	}
+
+	return "", nil, fmt.Errorf("unsupported key type [%T], supported types are: ECDSA, Ed25519, RSA", key.Public())
+}
+
+// VerifyReceipt checks the signature of a receipt emitted by Parse with the public key of the key it was
+// signed with, and returns the receipt if the signature is valid.
+func VerifyReceipt(data []byte, key crypto.PublicKey) (*Receipt, error) {
+	signed := &signedReceipt{}
+	if err := json.Unmarshal(data, signed); err != nil {
+		return nil, fmt.Errorf("invalid configuration receipt: %v", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(signed.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration receipt payload: %v", err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signed.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration receipt signature: %v", err)
+	}
+
+	digest := sha256.Sum256(payload)
+	valid := false
+
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		valid = signed.Algorithm == "EdDSA" && ed25519.Verify(k, payload, sig)
+	case *ecdsa.PublicKey:
+		valid = signed.Algorithm == "ES256" && ecdsa.VerifyASN1(k, digest[:], sig)
+	case *rsa.PublicKey:
+		valid = signed.Algorithm == "RS256" && rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil
+	default:
+		return nil, fmt.Errorf("unsupported key type [%T], supported types are: ECDSA, Ed25519, RSA", key)
+	}
+
+	if !valid {
+		return nil, errors.New("the signature of the configuration receipt is invalid")
+	}
+
+	receipt := &Receipt{}
+	if err = json.Unmarshal(payload, receipt); err != nil {
+		return nil, fmt.Errorf("invalid configuration receipt payload: %v", err)
+	}
+
+	return receipt, nil
+}
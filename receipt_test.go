@@ -0,0 +1,113 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCliReceipt(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var posted []byte
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/receipts" {
+			http.NotFound(w, r)
+			return
+		}
+		posted, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "receipts", "api.json")
+	hostname, _ := os.Hostname()
+	sum := sha256.Sum256([]byte(`{"id":8,"name":"Olivia","online":false}`))
+
+	cases := []struct {
+		args    []string
+		sink    string
+		key     crypto.Signer
+		sources []string
+		err     string
+	}{
+		{[]string{"", "-config", `{"id": 8, "name": "Olivia"}`}, file, edKey, []string{}, ""},
+		{[]string{"", "-config-uri", "file://" + filepath.Join(dir, "api.json")}, srv.URL + "/receipts", ecKey, []string{"file://" + filepath.Join(dir, "api.json")}, ""},
+		{[]string{"", "-config", `{"id": 8, "name": "Olivia"}`}, file, rsaKey, []string{}, ""},
+		{[]string{"", "-config", `{"id": 8, "name": "Olivia"}`}, srv.URL + "/missing", edKey, nil, "failed to send the configuration receipt: unexpected response status [404 Not Found]"},
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "api.json"), []byte(`{"name": "Olivia", "id": 8}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		posted = nil
+		os.Remove(file)
+
+		_, err := withMockedArgs(&input{args: c.args}, func(in *input) (string, error) {
+			return Parse("TEST", "", &ReleaseInfo{ReleaseVersion: "2.0.0"}, &testConf{}, WithReceipt(c.sink, c.key))
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) {
+			t.Errorf("expected error: %v, but found: %v", c.err, err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		data := posted
+		if data == nil {
+			data, _ = os.ReadFile(file)
+		}
+
+		receipt, err := VerifyReceipt(data, c.key.Public())
+		if err != nil {
+			t.Errorf("expected a valid receipt, but found: %v", err)
+			continue
+		}
+
+		if receipt.ConfigHash != "sha256:"+hex.EncodeToString(sum[:]) || !reflect.DeepEqual(receipt.Sources, c.sources) ||
+			receipt.Version != "2.0.0" || receipt.Hostname != hostname || receipt.PID != os.Getpid() || receipt.Timestamp.IsZero() {
+			t.Errorf("unexpected receipt: %+v", receipt)
+		}
+
+		if _, err = VerifyReceipt(data, ecKey.Public()); c.key != ecKey && err == nil {
+			t.Error("expected the receipt not to be verified with another key")
+		}
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redisTimeout bounds the time spent talking to Redis when the context of Parse has no earlier deadline.
+var redisTimeout = 30 * time.Second
+
+// fetchRedis reads the value of a key from Redis, located by a
+// redis[s]://[[<user>]:<password>@]<host>[:<port>][/<db>]?key=<key> URI, where rediss connects over TLS.
+// When the URI carries no credentials they are read from $<envVarPrefix>_REDIS_USERNAME and
+// $<envVarPrefix>_REDIS_PASSWORD if defined.
+func fetchRedis(ctx context.Context, o *options, u *url.URL) ([]byte, error) {
+	key := u.Query().Get("key")
+	if len(u.Host) == 0 || len(key) == 0 {
+		return nil, errors.New("URI must be in the form redis[s]://[[<user>]:<password>@]<host>[:<port>][/<db>]?key=<key>")
+	}
+
+	addr := u.Host
+	if len(u.Port()) == 0 {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+
+	conn, err := dial(ctx, o, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(redisTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	if err = conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(u.Scheme, "rediss") {
+		tc := tls.Client(conn, newTLSConfig(o, u.Hostname()))
+		if err = tc.HandshakeContext(ctx); err != nil {
+			return nil, err
+		}
+		conn = tc
+	}
+
+	c := &redisConn{w: conn, r: bufio.NewReader(conn)}
+
+	user, password := envValue(o, "REDIS_USERNAME"), envValue(o, "REDIS_PASSWORD")
+	if u.User != nil {
+		user, password = u.User.Username(), ""
+		if p, found := u.User.Password(); found {
+			password = p
+		}
+	}
+
+	if len(password) > 0 {
+		args := []string{"AUTH", password}
+		if len(user) > 0 {
+			args = []string{"AUTH", user, password}
+		}
+
+		if _, err = c.do(args...); err != nil {
+			return nil, fmt.Errorf("authentication failed: %v", err)
+		}
+	}
+
+	if db := strings.Trim(u.Path, "/"); len(db) > 0 && db != "0" {
+		if _, err = c.do("SELECT", db); err != nil {
+			return nil, err
+		}
+	}
+
+	val, err := c.do("GET", key)
+	if err != nil {
+		return nil, err
+	}
+
+	if val == nil {
+		return nil, fmt.Errorf("key [%v] does not exist", key)
+	}
+
+	return val, nil
+}
+
+// redisConn is a minimal client connection speaking the Redis serialization protocol.
+type redisConn struct {
+	w io.Writer
+	r *bufio.Reader
+}
+
+// do sends a command and returns its reply, nil replies are returned as nil,
+// and error replies are returned as errors.
+func (c *redisConn) do(args ...string) ([]byte, error) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	if _, err := io.WriteString(c.w, b.String()); err != nil {
+		return nil, err
+	}
+
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) == 0 {
+		return nil, errors.New("invalid redis reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, errors.New(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis reply [%v]", line)
+		}
+
+		if n < 0 {
+			return nil, nil
+		}
+
+		data := make([]byte, n+2)
+		if _, err = io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+
+		return data[:n], nil
+	default:
+		return nil, fmt.Errorf("unexpected redis reply [%v]", line)
+	}
+}
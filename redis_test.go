@@ -0,0 +1,158 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRedis starts a Redis server speaking just enough of the protocol to serve values of the
+// specified databases to clients authenticated with password, it returns the server address.
+func fakeRedis(t *testing.T, password string, dbs map[string]map[string]string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+
+				r, db, authenticated := bufio.NewReader(conn), "0", len(password) == 0
+
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+
+					n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+					args := make([]string, n)
+					for i := range args {
+						r.ReadString('\n')
+						arg, _ := r.ReadString('\n')
+						args[i] = strings.TrimSuffix(arg, "\r\n")
+					}
+
+					switch cmd := strings.ToUpper(args[0]); {
+					case cmd == "AUTH" && args[len(args)-1] == password:
+						authenticated = true
+						io.WriteString(conn, "+OK\r\n")
+					case cmd == "AUTH":
+						io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+					case !authenticated:
+						io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+					case cmd == "SELECT":
+						db = args[1]
+						io.WriteString(conn, "+OK\r\n")
+					case cmd == "GET":
+						if val, found := dbs[db][args[1]]; found {
+							fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(val), val)
+						} else {
+							io.WriteString(conn, "$-1\r\n")
+						}
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestCliRedis(t *testing.T) {
+	addr := fakeRedis(t, "secret", map[string]map[string]string{
+		"0": {"app:config": `{"id":19,"name":"default db"}`},
+		"2": {"app:config": `{"id":20,"name":"db 2","online":true}`},
+	})
+
+	t.Setenv("TEST_REDIS_PASSWORD", "secret")
+
+	cases := []struct {
+		uri      string
+		expected testConf
+		err      string
+	}{
+		{"redis://" + addr + "?key=app:config", testConf{ID: 19, Name: "default db"}, ""},
+		{"redis://:secret@" + addr + "/2?key=app:config", testConf{ID: 20, Name: "db 2", Online: true}, ""},
+		{"redis://:wrong@" + addr + "?key=app:config", testConf{}, "failed to fetch configuration from [redis://:xxxxx@" + addr + "?key=app:config]: authentication failed: WRONGPASS invalid password"},
+		{"redis://" + addr + "?key=missing", testConf{}, "failed to fetch configuration from [redis://" + addr + "?key=missing]: key [missing] does not exist"},
+		{"redis://" + addr, testConf{}, "failed to fetch configuration from [redis://" + addr + "]: URI must be in the form redis[s]://[[<user>]:<password>@]<host>[:<port>][/<db>]?key=<key>"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && err.Error() != c.err) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
+
+func TestCliRedisTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// the server accepts connections but never replies, closing them once the client gives up.
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				io.Copy(io.Discard, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	defer func(timeout time.Duration) { redisTimeout = timeout }(redisTimeout)
+	redisTimeout = 100 * time.Millisecond
+
+	conf := &testConf{}
+
+	_, err = withMockedArgs(&input{args: []string{"", "-config-uri", "redis://" + l.Addr().String() + "?key=app:config"}}, func(in *input) (string, error) {
+		return Parse("TEST", "", nil, conf)
+	})
+
+	if err == nil || !strings.HasSuffix(err.Error(), "i/o timeout") {
+		t.Errorf("expected a timeout error, but found: %v", err)
+	}
+}
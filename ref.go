@@ -0,0 +1,152 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// resolveRefs replaces the placeholders of the form ${ref:server.host} found in the values of the JSON
+// document doc by the values of the keys they refer to, located by their dotted paths where the items
+// of lists are located by their indexes, e.g. ${ref:servers.0.host}. A value that is a placeholder
+// alone is replaced by the referred value whatever its type, while placeholders within longer strings
+// are replaced by the referred values as strings, objects and lists being encoded as JSON.
+// Referred values may themselves hold placeholders, up to maxPlaceholderDepth levels deep.
+func resolveRefs(doc []byte) ([]byte, error) {
+	if !refPlaceHolderRegex.Match(doc) {
+		return doc, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+
+	var root interface{}
+	if err := dec.Decode(&root); err != nil {
+		// invalid documents are reported when they are decoded.
+		return doc, nil
+	}
+
+	resolved, err := resolveRefsIn(root, root, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(resolved)
+}
+
+// resolveRefsIn replaces the placeholders of references found in val, which is the value of the last
+// key of chain, the paths of the keys being resolved, by the values they refer to within root.
+func resolveRefsIn(root, val interface{}, chain []string) (interface{}, error) {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolved, err := resolveRefsIn(root, item, chain)
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = resolved
+		}
+		return obj, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := resolveRefsIn(root, item, chain)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = resolved
+		}
+		return list, nil
+	case string:
+		// values made of a single placeholder take the referred value whatever its type.
+		if m := refPlaceHolderRegex.FindStringSubmatch(v); m != nil && m[0] == v && !isEscaped(v) {
+			return lookupRef(root, m[1], chain)
+		}
+
+		var err error
+
+		s := refPlaceHolderRegex.ReplaceAllStringFunc(v, func(group string) string {
+			if err != nil {
+				return group
+			}
+
+			if isEscaped(group) {
+				return group[1:]
+			}
+
+			var ref interface{}
+			if ref, err = lookupRef(root, refPlaceHolderRegex.FindStringSubmatch(group)[1], chain); err != nil {
+				return group
+			}
+
+			if str, ok := ref.(string); ok {
+				return str
+			}
+
+			data, _ := json.Marshal(ref)
+
+			return string(data)
+		})
+
+		return s, err
+	}
+
+	return val, nil
+}
+
+// lookupRef returns the resolved value of the key located by path within root.
+func lookupRef(root interface{}, path string, chain []string) (interface{}, error) {
+	chain = append(chain[:len(chain):len(chain)], path)
+
+	for _, prev := range chain[:len(chain)-1] {
+		if prev == path {
+			return nil, fmt.Errorf("configuration references refer to each other: %v", strings.Join(chain, " -> "))
+		}
+	}
+
+	if len(chain) > maxPlaceholderDepth {
+		return nil, fmt.Errorf("configuration references are nested deeper than %v levels: %v", maxPlaceholderDepth, strings.Join(chain, " -> "))
+	}
+
+	val := root
+
+	for _, key := range strings.Split(path, ".") {
+		switch v := val.(type) {
+		case map[string]interface{}:
+			item, found := v[key]
+			if !found {
+				return nil, fmt.Errorf("configuration reference [%v] does not exist", path)
+			}
+			val = item
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("configuration reference [%v] does not exist", path)
+			}
+			val = v[i]
+		default:
+			return nil, fmt.Errorf("configuration reference [%v] does not exist", path)
+		}
+	}
+
+	return resolveRefsIn(root, val, chain)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type refConf struct {
+	Server struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	} `json:"server"`
+	URL     string            `json:"url"`
+	Port    int               `json:"port"`
+	Backup  map[string]string `json:"backup"`
+	Servers []string          `json:"servers"`
+}
+
+func TestCliRefs(t *testing.T) {
+	cases := []struct {
+		config   string
+		expected string
+		err      string
+	}{
+		{`{"server": {"host": "db", "port": 5432}, "url": "postgres://${ref:server.host}:${ref:server.port}", "port": "${ref:server.port}"}`,
+			`{"server":{"host":"db","port":5432},"url":"postgres://db:5432","port":5432,"backup":null,"servers":null}`, ""},
+		{`{"servers": ["a", "${ref:server.host}"], "server": {"host": "${ref:servers.0}"}, "backup": "${ref:server}"}`,
+			`{"server":{"host":"a","port":0},"url":"","port":0,"backup":{"host":"a"},"servers":["a","a"]}`, ""},
+		{`{"url": "$${ref:server.host} ${ref:servers}", "servers": ["a"]}`,
+			`{"server":{"host":"","port":0},"url":"${ref:server.host} [\"a\"]","port":0,"backup":null,"servers":["a"]}`, ""},
+		{`{"url": "${ref:server.host}"}`, "", "configuration reference [server.host] does not exist"},
+		{`{"url": "${ref:servers.1}", "servers": ["a"]}`, "", "configuration reference [servers.1] does not exist"},
+		{`{"url": "${ref:server.host}", "server": {"host": "${ref:url}"}}`, "", "configuration references refer to each other"},
+	}
+
+	for _, c := range cases {
+		conf := &refConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config", c.config}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) {
+			t.Errorf("expected error: %v, but found: %v", c.err, err)
+			continue
+		}
+
+		if err == nil {
+			expected := &refConf{}
+			if err = json.Unmarshal([]byte(c.expected), expected); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(conf, expected) {
+				t.Errorf("expected configuration: %+v, but found: %+v", expected, conf)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// checkRequired fails listing the dotted JSON paths of the fields of the configuration structure conf tagged
+// with `required:"true"` that hold zero values once every source is applied, in the order they are declared,
+// so that missing options are reported at once when loading rather than discovered at runtime. The fields of
+// nested structures that are not set, i.e. nil pointers, are not checked, unless they are required as well,
+// while the ones of the elements of slices are, e.g. servers[0].host.
+func checkRequired(conf interface{}) error {
+	v := reflect.ValueOf(conf)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	missing, err := missingFields(v.Elem(), "")
+	if err != nil {
+		return err
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required options: %v", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// missingFields returns the dotted JSON paths of the required fields of the structure v holding zero values,
+// where path is the path of v.
+func missingFields(v reflect.Value, path string) ([]string, error) {
+	var missing []string
+
+	err := walkFields(v, path, nil, func(field reflect.Value, f reflect.StructField, path string) (bool, error) {
+		tag := f.Tag.Get("required")
+		if len(tag) == 0 {
+			return true, nil
+		}
+
+		required, err := strconv.ParseBool(tag)
+		if err != nil {
+			return false, fmt.Errorf("invalid required tag [%v] of field [%v], a boolean is expected", tag, path)
+		}
+
+		if required && field.IsZero() {
+			missing = append(missing, path)
+			return false, nil
+		}
+
+		return true, nil
+	})
+
+	return missing, err
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+type requiredBase struct {
+	Region string `json:"region" required:"true"`
+}
+
+type requiredConf struct {
+	requiredBase
+	Name     string `json:"name" required:"true"`
+	Port     int    `json:"port" required:"true" env:"PORT"`
+	Optional string `json:"optional" required:"false"`
+	Database struct {
+		Host string `json:"host" required:"true"`
+	} `json:"database"`
+	Cache *struct {
+		Host string `json:"host" required:"true"`
+	} `json:"cache"`
+	Tracing *struct {
+		Endpoint string `json:"endpoint"`
+	} `json:"tracing" required:"true"`
+	Servers []*struct {
+		Host string `json:"host" required:"true"`
+	} `json:"servers"`
+}
+
+func TestCliRequired(t *testing.T) {
+	cases := []struct {
+		config string
+		env    map[string]string
+		err    string
+	}{
+		{`{}`, nil, "missing required options: region, name, port, database.host, tracing"},
+		{`{"region": "eu", "name": "app", "database": {"host": "db"}, "tracing": {}}`, map[string]string{"REQ_PORT": "80"}, ""},
+		{`{"region": "eu", "name": "app", "port": 80, "database": {"host": "db"}, "tracing": {}, "cache": {}}`, nil, "missing required options: cache.host"},
+		{`{"region": "eu", "name": "app", "port": 80, "database": {"host": "db"}, "tracing": {}, "servers": [{"host": "a"}, null, {}]}`, nil, "missing required options: servers[2].host"},
+	}
+
+	for _, c := range cases {
+		_, err := Parse("REQ", "", nil, &requiredConf{}, WithEnvironment(c.env), WithArgs("", "-config", c.config))
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || err.Error() != c.err)) {
+			t.Errorf("expected error: %v, but found: %v", c.err, err)
+		}
+	}
+
+	_, err := Parse("REQ", "", nil, &struct {
+		Name string `json:"name" required:"yes"`
+	}{}, WithEnvironment(nil), WithArgs("", "-config", `{}`))
+
+	if expected := "invalid required tag [yes] of field [name], a boolean is expected"; err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected error: %v, but found: %v", expected, err)
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"time"
+)
+
+// fetchWithRetry fetches the configuration document located by uri as fetch does, retrying failed
+// attempts as configured with the WithRetry option, waiting between attempts for an exponentially
+// growing backoff bounded by the maximum one.
+func fetchWithRetry(o *options, uri string) ([]byte, string, error) {
+	defer track(o, "fetch")()
+
+	backoff := o.retryBackoff
+
+	for attempt := 1; ; attempt++ {
+		doc, format, err := fetch(o.ctx, o, uri)
+		if err == nil || attempt >= o.retryAttempts || o.ctx.Err() != nil {
+			return doc, format, err
+		}
+
+		o.logger.Printf("attempt %v of %v failed, retrying in %v: %v", attempt, o.retryAttempts, backoff, err)
+
+		timer := time.NewTimer(backoff)
+
+		select {
+		case <-timer.C:
+		case <-o.ctx.Done():
+			timer.Stop()
+			return nil, "", err
+		}
+
+		if backoff *= 2; o.retryMaxBackoff > 0 && backoff > o.retryMaxBackoff {
+			backoff = o.retryMaxBackoff
+		}
+	}
+}
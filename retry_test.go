@@ -0,0 +1,81 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCliRetry(t *testing.T) {
+	var failures, requests int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests++; requests <= failures {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id": 38, "name": "remote"}`))
+	}))
+	defer srv.Close()
+
+	cache := filepath.Join(t.TempDir(), "api.json")
+	if err := os.WriteFile(cache, []byte(`{"id": 38, "name": "cache"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		uri                string
+		attempts, failures int
+		expected           testConf
+		err                string
+		requests           int
+	}{
+		{srv.URL, 3, 2, testConf{ID: 38, Name: "remote"}, "", 3},
+		{srv.URL, 2, 2, testConf{}, "unexpected response status [503 Service Unavailable]", 2},
+		{srv.URL + "|file://" + cache, 2, 2, testConf{ID: 38, Name: "cache"}, "", 2},
+		{srv.URL, 0, 1, testConf{}, "unexpected response status [503 Service Unavailable]", 1},
+	}
+
+	for _, c := range cases {
+		var logs bytes.Buffer
+
+		failures, requests = c.failures, 0
+		failedEndpoints.at = make(map[string]time.Time)
+
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf, WithRetry(c.attempts, time.Millisecond, 2*time.Millisecond), WithLogger(log.New(&logs, "", 0)))
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected || requests != c.requests {
+			t.Errorf("expected output: (%+v, %v) after %v requests, but found: (%+v, %v) after %v requests", c.expected, c.err, c.requests, *conf, err, requests)
+		}
+
+		if retries := strings.Count(logs.String(), "retrying in"); retries != c.requests-1 {
+			t.Errorf("expected %v retries to be logged, but found: %q", c.requests-1, logs.String())
+		}
+	}
+}
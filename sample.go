@@ -0,0 +1,176 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// jsonMarshalerType and textMarshalerType are the types of the values encoding themselves,
+	// whose fields are thus not described.
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Sample returns a sample configuration document of conf, a structure or a pointer to one holding the
+// default values, in JSON with comments as accepted by Parse. Fields tagged with `desc:"<description>"`
+// are preceded by their descriptions as comments, so that the document is self-documenting for the
+// operators editing it by hand. Fields are written in the order they are declared.
+func Sample(conf interface{}) ([]byte, error) {
+	data, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	descs := make(map[string]string)
+	if t := reflect.TypeOf(conf); t != nil {
+		describe(t, "", descs, make(map[reflect.Type]bool))
+	}
+
+	var b bytes.Buffer
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err = writeSample(&b, dec, "", "", descs); err != nil {
+		return nil, fmt.Errorf("failed to write the sample configuration: %v", err)
+	}
+
+	b.WriteByte('\n')
+
+	return b.Bytes(), nil
+}
+
+// describe adds the descriptions of the fields of t, located at path, to descs, keyed by their dotted
+// JSON paths, the elements of slices being denoted by [], e.g. servers[].host. The types of the structures
+// holding t are recorded in parents, see walkType.
+func describe(t reflect.Type, path string, descs map[string]string, parents map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		if t.Kind() != reflect.Ptr {
+			path += "[]"
+		}
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || reflect.PointerTo(t).Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return
+	}
+
+	_ = walkType(t, path, parents, func(_ reflect.Value, f reflect.StructField, name string) (bool, error) {
+		// embedded structures share the path of the structure embedding them, and have no descriptions.
+		if desc := f.Tag.Get("desc"); len(desc) > 0 && name != path {
+			descs[name] = desc
+		}
+
+		describe(f.Type, name, descs, parents)
+
+		return false, nil
+	})
+}
+
+// writeSample writes the next value of dec, located at path, to b indented by indent, preceding the
+// keys of objects by the descriptions of descs as comments.
+func writeSample(b *bytes.Buffer, dec *json.Decoder, path, indent string, descs map[string]string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('{'), json.Delim('['):
+		isObject, closing := tok == json.Delim('{'), "]"
+		if isObject {
+			closing = "}"
+		}
+
+		b.WriteString(tok.(json.Delim).String())
+
+		i := 0
+		for ; dec.More(); i++ {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString("\n")
+
+			elemPath := path + "[]"
+
+			if isObject {
+				if tok, err = dec.Token(); err != nil {
+					return err
+				}
+
+				key, _ := tok.(string)
+
+				elemPath = key
+				if len(path) > 0 {
+					elemPath = path + "." + key
+				}
+
+				if desc, found := descs[elemPath]; found {
+					for _, line := range strings.Split(desc, "\n") {
+						b.WriteString(strings.TrimRight(indent+"  // "+line, " ") + "\n")
+					}
+				}
+
+				b.WriteString(indent + "  " + quote(key) + ": ")
+			} else {
+				b.WriteString(indent + "  ")
+			}
+
+			if err = writeSample(b, dec, elemPath, indent+"  ", descs); err != nil {
+				return err
+			}
+		}
+
+		if _, err = dec.Token(); err != nil {
+			return err
+		}
+
+		if i > 0 {
+			b.WriteString("\n" + indent)
+		}
+
+		b.WriteString(closing)
+	case nil:
+		b.WriteString("null")
+	default:
+		if s, ok := tok.(string); ok {
+			b.WriteString(quote(s))
+		} else {
+			fmt.Fprint(b, tok)
+		}
+	}
+
+	return nil
+}
+
+// quote returns s as a JSON string, without escaping HTML characters.
+func quote(s string) string {
+	var b bytes.Buffer
+
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
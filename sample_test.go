@@ -0,0 +1,102 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type sampleServer struct {
+	Host string `json:"host" desc:"The host name of the server."`
+	Port int    `json:"port" desc:"The port of the server."`
+}
+
+type sampleLogging struct {
+	Level string `json:"level" desc:"The level of the logs, one of: debug, info."`
+}
+
+type sampleConf struct {
+	sampleLogging
+	Name     string          `json:"name" desc:"The name of the service,\nas reported to the registry."`
+	Servers  []sampleServer  `json:"servers" desc:"The servers to connect to."`
+	Database *sampleServer   `json:"database,omitempty"`
+	Timeout  time.Time       `json:"timeout" desc:"The time the service shuts down at."`
+	Tags     []string        `json:"tags"`
+	Extra    map[string]bool `json:"extra"`
+	Secret   string          `json:"-" desc:"Never written."`
+}
+
+func TestSample(t *testing.T) {
+	conf := &sampleConf{
+		sampleLogging: sampleLogging{Level: "info"},
+		Name:          "a<b>",
+		Servers:       []sampleServer{{Host: "one", Port: 1}},
+		Database:      &sampleServer{Host: "db"},
+		Tags:          []string{},
+		Secret:        "s3cr3t",
+	}
+
+	expected := `{
+  // The level of the logs, one of: debug, info.
+  "level": "info",
+  // The name of the service,
+  // as reported to the registry.
+  "name": "a<b>",
+  // The servers to connect to.
+  "servers": [
+    {
+      // The host name of the server.
+      "host": "one",
+      // The port of the server.
+      "port": 1
+    }
+  ],
+  "database": {
+    // The host name of the server.
+    "host": "db",
+    // The port of the server.
+    "port": 0
+  },
+  // The time the service shuts down at.
+  "timeout": "0001-01-01T00:00:00Z",
+  "tags": [],
+  "extra": null
+}
+`
+
+	sample, err := Sample(conf)
+	if err != nil || string(sample) != expected {
+		t.Fatalf("expected sample: (%v, <nil>), but found: (%v, %v)", expected, string(sample), err)
+	}
+
+	parsed := &sampleConf{}
+	if err = json.Unmarshal(stripJSONC(sample), parsed); err != nil {
+		t.Fatal(err)
+	}
+
+	conf.Secret = ""
+	if !reflect.DeepEqual(parsed, conf) {
+		t.Errorf("expected the sample to parse into: %+v, but found: %+v", conf, parsed)
+	}
+
+	if _, err = Sample(make(chan int)); err == nil {
+		t.Errorf("expected error of unsupported type, but found none")
+	}
+}
@@ -0,0 +1,326 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// schemaDialect is the dialect of the JSON schemas derived from configuration structures.
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// schema is a JSON schema, limited to the keywords derived from configuration structures.
+type schema struct {
+	Dialect              string           `json:"$schema,omitempty"`
+	Type                 string           `json:"type,omitempty"`
+	Description          string           `json:"description,omitempty"`
+	Deprecated           bool             `json:"deprecated,omitempty"`
+	Default              json.RawMessage  `json:"default,omitempty"`
+	Enum                 []string         `json:"enum,omitempty"`
+	Minimum              json.RawMessage  `json:"minimum,omitempty"`
+	Maximum              json.RawMessage  `json:"maximum,omitempty"`
+	MinLength            *int             `json:"minLength,omitempty"`
+	MaxLength            *int             `json:"maxLength,omitempty"`
+	MinItems             *int             `json:"minItems,omitempty"`
+	MaxItems             *int             `json:"maxItems,omitempty"`
+	MinProperties        *int             `json:"minProperties,omitempty"`
+	MaxProperties        *int             `json:"maxProperties,omitempty"`
+	Items                *schema          `json:"items,omitempty"`
+	Properties           schemaProperties `json:"properties,omitempty"`
+	AdditionalProperties *schema          `json:"additionalProperties,omitempty"`
+	Required             []string         `json:"required,omitempty"`
+}
+
+// schemaProperties are the properties of an object, written in the order their fields are declared.
+type schemaProperties []schemaProperty
+
+// schemaProperty is a property of an object, along with its schema.
+type schemaProperty struct {
+	name   string
+	schema *schema
+}
+
+// MarshalJSON writes the properties as a JSON object, in order.
+func (p schemaProperties) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+
+	b.WriteByte('{')
+
+	for i, prop := range p {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		data, err := json.Marshal(prop.schema)
+		if err != nil {
+			return nil, err
+		}
+
+		b.WriteString(strconv.Quote(prop.name))
+		b.WriteByte(':')
+		b.Write(data)
+	}
+
+	b.WriteByte('}')
+
+	return []byte(b.String()), nil
+}
+
+// JSONSchema returns the JSON schema of the configuration documents of conf, a structure or a pointer to one
+// holding the default values, derived from its fields and their tags, so that editors and pipelines check the
+// documents before they are deployed:
+//
+//	desc:"<description>"    describes the option.
+//	default:"<value>"       is the default value of the option, as are the values conf holds.
+//	required:"true"         requires the option.
+//	min, max, len and oneof constrain the option as Parse does, see Parse.
+//	deprecated:"<message>"  marks the option deprecated.
+//
+// Options of any type are accepted for the values encoding themselves as JSON, while the ones encoding
+// themselves as text are strings.
+func JSONSchema(conf interface{}) ([]byte, error) {
+	v := reflect.ValueOf(conf)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot derive the JSON schema of [%T], a structure is expected", conf)
+	}
+
+	s, err := schemaOf(v, make(map[reflect.Type]bool))
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive the JSON schema of [%T]: %v", conf, err)
+	}
+
+	s.Dialect = schemaDialect
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive the JSON schema of [%T]: %v", conf, err)
+	}
+
+	return append(data, '\n'), nil
+}
+
+// schemaOf returns the schema of the value v, described by the zero value of its type when unknown, where
+// visiting holds the structures being described, whose recursive occurrences accept any value.
+func schemaOf(v reflect.Value, visiting map[reflect.Type]bool) (*schema, error) {
+	t := v.Type()
+
+	switch {
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return &schema{}, nil
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &schema{Type: "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &schema{Type: "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &schema{Type: "integer", Minimum: json.RawMessage("0")}, nil
+	case reflect.Float32, reflect.Float64:
+		return &schema{Type: "number"}, nil
+	case reflect.String:
+		return &schema{Type: "string"}, nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			v = reflect.New(t.Elem())
+		}
+
+		return schemaOf(v.Elem(), visiting)
+	case reflect.Slice, reflect.Array:
+		// byte slices are encoded as base64 strings.
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &schema{Type: "string"}, nil
+		}
+
+		items, err := schemaOf(reflect.New(t.Elem()).Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+
+		return &schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		values, err := schemaOf(reflect.New(t.Elem()).Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+
+		return &schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		if visiting[t] {
+			return &schema{}, nil
+		}
+
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		s := &schema{Type: "object"}
+
+		return s, describeFields(v, s, "", visiting)
+	}
+
+	return &schema{}, nil
+}
+
+// describeFields adds the properties of the fields of the structure v, located at path, to the schema s
+// of the object holding them.
+func describeFields(v reflect.Value, s *schema, path string, visiting map[reflect.Type]bool) error {
+	for i := 0; i < v.NumField(); i++ {
+		field, f := v.Field(i), v.Type().Field(i)
+
+		name, found := jsonField(f)
+		if !found {
+			continue
+		}
+
+		// embedded structures share the object of the structure embedding them.
+		if len(name) == 0 {
+			embedded := field
+			if embedded.Kind() == reflect.Ptr {
+				if embedded.IsNil() {
+					embedded = reflect.New(f.Type.Elem())
+				}
+				embedded = embedded.Elem()
+			}
+
+			if err := describeFields(embedded, s, path, visiting); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fieldPath := joinPath(path, name)
+
+		prop, err := schemaOf(field, visiting)
+		if err != nil {
+			return err
+		}
+
+		if err = describeField(prop, field, f, fieldPath); err != nil {
+			return err
+		}
+
+		if required, _ := strconv.ParseBool(f.Tag.Get("required")); required {
+			s.Required = append(s.Required, name)
+		}
+
+		s.Properties = append(s.Properties, schemaProperty{name: name, schema: prop})
+	}
+
+	return nil
+}
+
+// describeField adds the description, default value and constraints of the field f holding the value v,
+// located at path, to its schema s.
+func describeField(s *schema, v reflect.Value, f reflect.StructField, path string) error {
+	s.Description = f.Tag.Get("desc")
+
+	if message, found := f.Tag.Lookup("deprecated"); found {
+		s.Deprecated = true
+
+		if len(s.Description) == 0 {
+			s.Description = message
+		}
+	}
+
+	// the default values of nested structures are the ones of their own fields.
+	if !isNestedStruct(f.Type) {
+		if tag, found := f.Tag.Lookup("default"); found && v.IsZero() {
+			v = reflect.New(f.Type).Elem()
+			if err := setFromString(v, tag); err != nil {
+				return fmt.Errorf("invalid default value [%v] of field [%v]: %v", tag, path, err)
+			}
+		}
+
+		if !v.IsZero() {
+			data, err := json.Marshal(v.Interface())
+			if err != nil {
+				return fmt.Errorf("invalid default value of field [%v]: %v", path, err)
+			}
+
+			s.Default = data
+		}
+	}
+
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if values, found := f.Tag.Lookup("oneof"); found && t.Kind() == reflect.String {
+		for _, value := range strings.Split(values, ",") {
+			s.Enum = append(s.Enum, strings.TrimSpace(value))
+		}
+	}
+
+	for _, constraint := range []string{"min", "max", "len"} {
+		bound, found := f.Tag.Lookup(constraint)
+		if !found {
+			continue
+		}
+
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			b := reflect.New(t).Elem()
+			if err := setFromString(b, bound); err != nil {
+				return fmt.Errorf("invalid constraint [%v] of field [%v]: %v", constraint, path, err)
+			}
+
+			data, _ := json.Marshal(b.Interface())
+
+			switch constraint {
+			case "min":
+				s.Minimum = data
+			case "max":
+				s.Maximum = data
+			}
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+			n, err := strconv.Atoi(bound)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid constraint [%v] of field [%v], a length is expected", constraint, path)
+			}
+
+			lower, upper := &s.MinLength, &s.MaxLength
+			switch {
+			case t.Kind() == reflect.Map:
+				lower, upper = &s.MinProperties, &s.MaxProperties
+			case t.Kind() != reflect.String && s.Type == "array":
+				lower, upper = &s.MinItems, &s.MaxItems
+			}
+
+			if constraint != "max" {
+				*lower = &n
+			}
+
+			if constraint != "min" {
+				*upper = &n
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type SchemaBase struct {
+	Region string `json:"region" desc:"The region of the instance." required:"true"`
+}
+
+type schemaNode struct {
+	Name     string        `json:"name"`
+	Children []*schemaNode `json:"children"`
+}
+
+type schemaConf struct {
+	SchemaBase
+	Port    int               `json:"port" default:"8080" min:"1" max:"65535"`
+	Timeout time.Duration     `json:"timeout" min:"1s"`
+	Level   string            `json:"level" oneof:"debug,info"`
+	Code    string            `json:"code" len:"2"`
+	Hosts   []string          `json:"hosts" max:"3"`
+	Labels  map[string]string `json:"labels" min:"1"`
+	Weight  uint              `json:"weight"`
+	Ratio   *float64          `json:"ratio"`
+	Verbose bool              `json:"verbose" deprecated:"use level instead"`
+	Start   time.Time         `json:"start"`
+	Key     []byte            `json:"key"`
+	Tree    schemaNode        `json:"tree"`
+	Ignored string            `json:"-"`
+	hidden  string
+}
+
+const expectedSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "properties": {
+    "region": {
+      "type": "string",
+      "description": "The region of the instance.",
+      "default": "eu"
+    },
+    "port": {
+      "type": "integer",
+      "default": 8080,
+      "minimum": 1,
+      "maximum": 65535
+    },
+    "timeout": {
+      "type": "integer",
+      "minimum": 1000000000
+    },
+    "level": {
+      "type": "string",
+      "enum": [
+        "debug",
+        "info"
+      ]
+    },
+    "code": {
+      "type": "string",
+      "minLength": 2,
+      "maxLength": 2
+    },
+    "hosts": {
+      "type": "array",
+      "maxItems": 3,
+      "items": {
+        "type": "string"
+      }
+    },
+    "labels": {
+      "type": "object",
+      "minProperties": 1,
+      "additionalProperties": {
+        "type": "string"
+      }
+    },
+    "weight": {
+      "type": "integer",
+      "minimum": 0
+    },
+    "ratio": {
+      "type": "number"
+    },
+    "verbose": {
+      "type": "boolean",
+      "description": "use level instead",
+      "deprecated": true
+    },
+    "start": {},
+    "key": {
+      "type": "string"
+    },
+    "tree": {
+      "type": "object",
+      "properties": {
+        "name": {
+          "type": "string"
+        },
+        "children": {
+          "type": "array",
+          "items": {}
+        }
+      }
+    }
+  },
+  "required": [
+    "region"
+  ]
+}
+`
+
+func TestJSONSchema(t *testing.T) {
+	conf := &schemaConf{SchemaBase: SchemaBase{Region: "eu"}}
+
+	schema, err := JSONSchema(conf)
+	if err != nil {
+		t.Fatalf("expected no error, but found: %v", err)
+	}
+
+	if string(schema) != expectedSchema {
+		t.Errorf("expected schema:\n%v\nbut found:\n%v", expectedSchema, string(schema))
+	}
+
+	// the schema accepts the documents Parse does, and rejects the others.
+	cases := []struct {
+		config string
+		err    string
+	}{
+		{`{"region": "eu", "port": 80, "level": "info"}`, ""},
+		{`{"port": 0, "level": "trace"}`, "the configuration does not satisfy the JSON schema: [.] missing property 'region', [level] value must be one of 'debug', 'info', [port] minimum: got 0, want 1"},
+	}
+
+	for _, c := range cases {
+		_, err := Parse("SCHEMA", "", nil, nil, WithEnvironment(nil), WithArgs("", "-config", c.config), WithJSONSchema(string(schema)))
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || err.Error() != c.err)) {
+			t.Errorf("expected error: %v, but found: %v", c.err, err)
+		}
+	}
+
+	if _, err = JSONSchema("conf"); err == nil || err.Error() != "cannot derive the JSON schema of [string], a structure is expected" {
+		t.Errorf("expected an error for a configuration that is not a structure, but found: %v", err)
+	}
+
+	if _, err = JSONSchema(&struct {
+		Port int `json:"port" min:"one"`
+	}{}); err == nil || !strings.Contains(err.Error(), "invalid constraint [min] of field [port]") {
+		t.Errorf("expected an error for an invalid constraint, but found: %v", err)
+	}
+}
+
+func TestCliSchema(t *testing.T) {
+	out, err := Parse("SCHEMA", "", nil, &schemaConf{}, WithEnvironment(nil), WithArgs("", "-schema"))
+	if err != nil {
+		t.Fatalf("expected no error, but found: %v", err)
+	}
+
+	// the default values of the tags are applied to the configuration before its schema is derived.
+	if !strings.HasPrefix(out, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",`) || !strings.Contains(out, `"default": 8080`) || strings.Contains(out, `"default": "eu"`) {
+		t.Errorf("unexpected schema:\n%v", out)
+	}
+
+	if _, err = Parse("SCHEMA", "", nil, nil, WithEnvironment(nil), WithArgs("", "-schema")); err == nil || err.Error() != "the -schema flag requires a configuration structure" {
+		t.Errorf("expected an error without a configuration structure, but found: %v", err)
+	}
+}
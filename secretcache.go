@@ -0,0 +1,113 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"time"
+)
+
+// uncachedSchemes are the schemes of the placeholders resolved locally, whose values are never cached so
+// that rotated secrets mounted as files and changing outputs of commands are seen at once, and so that the
+// outputs of commands are not written to disk.
+var uncachedSchemes = map[string]bool{"exec": true, "file": true}
+
+// secretCache holds the values of the placeholders of remote schemes e.g. ${azkv:vault/secret} resolved
+// for a configuration document, keyed by placeholder, such that restarts shortly after do not resolve them
+// again from their stores.
+type secretCache struct {
+	path   string
+	values map[string]string
+	dirty  bool
+}
+
+// loadSecretCache returns the secret cache of doc, holding the values cached within the time to live of
+// the cache if any, or nil if the secret cache is disabled or doc has no such placeholders. Cache files are
+// named after the digest of the document, so that any change to the document, including its references,
+// invalidates its cache.
+func loadSecretCache(o *options, doc string) *secretCache {
+	if len(o.secretCacheDir) == 0 || !schemePlaceHolderRegex.MatchString(doc) {
+		return nil
+	}
+
+	defer track(o, "decrypt")()
+
+	sum := sha256.Sum256([]byte(doc))
+	cache := &secretCache{
+		path:   filepath.Join(o.secretCacheDir, "secrets-"+hex.EncodeToString(sum[:])+".cache"),
+		values: make(map[string]string),
+	}
+
+	plain, at, err := readSealed(o.secretCacheKey, cache.path)
+	if err != nil || time.Since(at) > o.secretCacheTTL {
+		return cache
+	}
+
+	values := make(map[string]string)
+	if err = json.Unmarshal(plain, &values); err != nil {
+		o.logger.Printf("WARNING: ignoring the secret cache, since it is corrupted: %v", err)
+		return cache
+	}
+
+	cache.values = values
+
+	return cache
+}
+
+// resolve returns the cached value of the placeholder of scheme referring to ref, otherwise resolves
+// it with resolver and caches it.
+func (c *secretCache) resolve(ctx context.Context, o *options, scheme, ref string,
+	resolver func(ctx context.Context, o *options, ref string) (string, error)) (string, error) {
+	if c == nil || uncachedSchemes[scheme] {
+		return resolver(ctx, o, ref)
+	}
+
+	key := scheme + ":" + ref
+	if val, found := c.values[key]; found {
+		return val, nil
+	}
+
+	val, err := resolver(ctx, o, ref)
+	if err == nil {
+		c.values[key], c.dirty = val, true
+	}
+
+	return val, err
+}
+
+// save writes the cache when any value was resolved since it was loaded, failing to do so is only
+// logged since the values are resolved again on the next start.
+func (c *secretCache) save(o *options) {
+	if c == nil || !c.dirty {
+		return
+	}
+
+	defer track(o, "encrypt")()
+
+	plain, err := json.Marshal(c.values)
+	if err == nil {
+		err = writeSealed(o.secretCacheKey, c.path, plain)
+	}
+
+	if err != nil {
+		o.logger.Printf("WARNING: failed to cache the resolved secrets: %v", err)
+	}
+}
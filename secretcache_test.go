@@ -0,0 +1,116 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCliSecretCache(t *testing.T) {
+	var resolved int
+	var down bool
+
+	resolvers.funcs["fake"] = func(ctx context.Context, o *options, ref string) (string, error) {
+		if down {
+			return "", errors.New("store unavailable")
+		}
+		resolved++
+		return "Eve", nil
+	}
+	defer delete(resolvers.funcs, "fake")
+
+	dir := t.TempDir()
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	cases := []struct {
+		config   string
+		key      []byte
+		ttl      time.Duration
+		down     bool
+		expected testConf
+		err      string
+		warning  string
+		resolved int
+	}{
+		{`{"id": 1, "name": "${fake:name}"}`, key, time.Hour, false, testConf{ID: 1, Name: "Eve"}, "", "", 1},
+		{`{"id": 1, "name": "${fake:name}"}`, key, time.Hour, true, testConf{ID: 1, Name: "Eve"}, "", "", 0},
+		{`{"id": 2, "name": "${fake:name}"}`, key, time.Hour, true, testConf{}, "store unavailable", "", 0},
+		{`{"id": 1, "name": "${fake:name}"}`, key, time.Nanosecond, false, testConf{ID: 1, Name: "Eve"}, "", "", 1},
+		{`{"id": 1, "name": "${fake:name}"}`, []byte("fedcba9876543210fedcba9876543210"), time.Hour, false, testConf{ID: 1, Name: "Eve"}, "", "", 1},
+		{`{"id": 1, "name": "${fake:name}"}`, []byte("short"), time.Hour, false, testConf{ID: 1, Name: "Eve"}, "", "WARNING: failed to cache the resolved secrets: invalid configuration cache key", 1},
+	}
+
+	for _, c := range cases {
+		resolved, down = 0, c.down
+		conf := &testConf{}
+		var logs bytes.Buffer
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config", c.config}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf, WithSecretCache(dir, c.key, c.ttl), WithLogger(log.New(&logs, "", 0)))
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected || resolved != c.resolved {
+			t.Errorf("expected output: (%+v, %v) with %v resolutions, but found: (%+v, %v) with %v resolutions", c.expected, c.err, c.resolved, *conf, err, resolved)
+		}
+
+		if (len(c.warning) == 0 && logs.Len() > 0) || !strings.Contains(logs.String(), c.warning) {
+			t.Errorf("expected warning [%v], but found [%v]", c.warning, logs.String())
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	for _, file := range files {
+		data, _ := os.ReadFile(file)
+		if bytes.Contains(data, []byte("Eve")) {
+			t.Errorf("expected the secrets to be encrypted in [%v]", file)
+		}
+	}
+}
+
+func TestCliSecretCacheLocalSchemes(t *testing.T) {
+	dir, cacheDir := t.TempDir(), t.TempDir()
+	secret := filepath.Join(dir, "name")
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	// rotated secrets mounted as files are seen at once, and never written to the cache.
+	for _, name := range []string{"Eve", "Mallory"} {
+		if err := os.WriteFile(secret, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		conf := &testConf{}
+
+		_, err := Parse("CACHE", "", nil, conf, WithEnvironment(nil), WithArgs("", "-config", `{"id": 1, "name": "${file:`+secret+`}"}`),
+			WithSecretCache(cacheDir, key, time.Hour))
+
+		if expected := (testConf{ID: 1, Name: name}); err != nil || *conf != expected {
+			t.Errorf("expected output: (%+v, <nil>), but found: (%+v, %v)", expected, *conf, err)
+		}
+	}
+
+	if files, _ := filepath.Glob(filepath.Join(cacheDir, "*")); len(files) > 0 {
+		t.Errorf("expected no cache file, but found: %v", files)
+	}
+}
@@ -0,0 +1,319 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source is a configuration source, it fetches the configuration document from wherever it is kept.
+// Sources learning the format of the document while loading it, e.g. from a Content-Type header, may
+// also implement a Format() string method returning it after Load, one of the supported formats or an
+// empty string if unknown, which is used unless a format is explicitly specified.
+type Source interface {
+	// Name describes the source in errors, e.g. the URI of the document without its credentials.
+	Name() string
+
+	// Load fetches the configuration document.
+	Load(ctx context.Context) ([]byte, error)
+}
+
+// formatter is implemented by sources reporting the format of the documents they load.
+type formatter interface {
+	Format() string
+}
+
+// SourceFactory creates the Source of the configuration document located by a URI.
+type SourceFactory func(u *url.URL) (Source, error)
+
+// sources maps the supported URI schemes to functions creating the Source of the configuration
+// document located by a URI of that scheme.
+var sources = struct {
+	sync.RWMutex
+	factories map[string]func(o *options, u *url.URL) (Source, error)
+}{factories: map[string]func(o *options, u *url.URL) (Source, error){
+	"azappconfig": builtinSource(fetchAppConfig),
+	"azkv":        builtinSource(fetchKeyVault),
+	"configmap":   builtinSource(fetchConfigMap),
+	"file":        builtinSource(fetchFile),
+	"git":         builtinSource(fetchGit),
+	"gs":          builtinSource(fetchGCS),
+	"http":        newHTTPSource,
+	"https":       newHTTPSource,
+	"nats":        builtinSource(fetchNATS),
+	"oci":         builtinSource(fetchOCI),
+	"redis":       builtinSource(fetchRedis),
+	"rediss":      builtinSource(fetchRedis),
+	"secret":      builtinSource(fetchSecret),
+	"spring":      builtinSource(fetchSpring),
+	"sql":         builtinSource(fetchSQL),
+	"txt":         builtinSource(fetchTXT),
+	"zk":          builtinSource(fetchZooKeeper),
+}}
+
+// RegisterSource makes the sources created by factory available for configuration URIs of the
+// specified scheme, so that applications can fetch their configuration from stores that are not
+// supported out of the box. It is meant to be called from init functions, and it panics if factory
+// is nil, if the scheme is not made of letters or numbers, or if it is already supported.
+func RegisterSource(scheme string, factory SourceFactory) {
+	scheme = strings.ToLower(scheme)
+
+	if !schemeRegex.MatchString(scheme) || factory == nil {
+		panic("config: RegisterSource requires a scheme of letters or numbers and a factory")
+	}
+
+	sources.Lock()
+	defer sources.Unlock()
+
+	if _, found := sources.factories[scheme]; found {
+		panic(fmt.Sprintf("config: RegisterSource called twice for scheme [%v]", scheme))
+	}
+
+	sources.factories[scheme] = func(_ *options, u *url.URL) (Source, error) {
+		return factory(u)
+	}
+}
+
+// builtinSource returns a function creating sources fetching documents with fetch.
+func builtinSource(fetch func(ctx context.Context, o *options, u *url.URL) ([]byte, error)) func(o *options, u *url.URL) (Source, error) {
+	return func(o *options, u *url.URL) (Source, error) {
+		return &fetchSource{o: o, u: u, fetch: fetch}, nil
+	}
+}
+
+// fetchSource is a built-in Source, fetching the document located by u with fetch.
+type fetchSource struct {
+	o     *options
+	u     *url.URL
+	fetch func(ctx context.Context, o *options, u *url.URL) ([]byte, error)
+}
+
+// Name returns the URI of the document without its credentials.
+func (s *fetchSource) Name() string {
+	return s.u.Redacted()
+}
+
+// Load fetches the document.
+func (s *fetchSource) Load(ctx context.Context) ([]byte, error) {
+	return s.fetch(ctx, s.o, s.u)
+}
+
+// load fetches the configuration documents located by uris and returns them along with their format.
+// The format of every document is the specified one if any, otherwise the one matching the extension
+// of its URI or else json. Several documents are translated into JSON and merged in order, such that
+// the values of a document override the ones of the documents before it.
+func load(o *options, uris []string, format string) ([]byte, string, error) {
+	if len(uris) == 1 {
+		return loadOne(o, uris[0], format)
+	}
+
+	var merged interface{} = make(map[string]interface{})
+
+	for _, uri := range uris {
+		doc, f, err := loadOne(o, uri, format)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if doc, err = toJSON(o, f, doc); err != nil {
+			return nil, "", err
+		}
+
+		var val interface{}
+		if err = json.Unmarshal(doc, &val); err != nil {
+			return nil, "", fmt.Errorf("invalid configuration fetched from [%v]: %v", uri, err)
+		}
+
+		merged = deepMerge(merged, val)
+	}
+
+	doc, err := json.Marshal(merged)
+
+	return doc, "json", err
+}
+
+// loadOne fetches the configuration document located by uri and returns it along with its format,
+// bundles are merged into a single JSON document. The uri may list alternative endpoints separated
+// by |, in which case the document is fetched from the first of them that succeeds.
+func loadOne(o *options, uri string, format string) ([]byte, string, error) {
+	var doc []byte
+	var fetched string
+	var err error
+
+	// alternative endpoints are separated by |, which is never part of a valid URI.
+	if uris := strings.Split(uri, "|"); len(uris) > 1 {
+		doc, fetched, err = fetchFailover(o, uris)
+	} else {
+		doc, fetched, err = fetchWithRetry(o, uri)
+	}
+
+	// remote documents are cached when enabled, and the cached copy is used when they cannot be fetched.
+	if len(o.cacheDir) > 0 && !strings.HasPrefix(strings.ToLower(uri), "file:") {
+		if err == nil {
+			if cacheErr := writeCache(o, uri, doc, fetched); cacheErr != nil {
+				o.logger.Printf("WARNING: failed to cache the configuration: %v", cacheErr)
+			}
+		} else if cached, f, at, cacheErr := readCache(o, uri); cacheErr == nil {
+			o.logger.Printf("WARNING: using the configuration cached at %v, since it could not be fetched: %v", at.Format(time.RFC3339), err)
+			doc, fetched, err = cached, f, nil
+		}
+	}
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if doc, err = gunzip(doc); err != nil {
+		return nil, "", err
+	}
+
+	// archives are bundles of layered documents that are merged into a single JSON document.
+	if isBundle(doc) {
+		doc, err = unbundle(o, doc)
+		return doc, "json", err
+	}
+
+	if len(format) == 0 {
+		format = fetched
+	}
+
+	if len(format) == 0 {
+		format = detectFormat(doc)
+	}
+
+	return doc, format, nil
+}
+
+// fetch retrieves the configuration document located by uri and returns it along with its format as
+// reported by the source, otherwise the one matching the extension of the URI if any.
+func fetch(ctx context.Context, o *options, uri string) ([]byte, string, error) {
+	u, err := parseURI(uri)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid configuration URI [%v]: %v", uri, err)
+	}
+
+	// schemes of the form <scheme>+srv discover the endpoints of the source through DNS SRV records.
+	scheme := strings.ToLower(u.Scheme)
+	srv := strings.HasSuffix(scheme, "+srv")
+	scheme = strings.TrimSuffix(scheme, "+srv")
+
+	// schemes of the form <source>+<transport> e.g. git+https are handled by the source.
+	sources.RLock()
+	factory, found := sources.factories[scheme]
+	if i := strings.Index(scheme, "+"); !found && i > 0 {
+		factory, found = sources.factories[scheme[:i]]
+	}
+	sources.RUnlock()
+
+	if !found {
+		return nil, "", fmt.Errorf("unsupported configuration URI scheme [%v], supported schemes are: %v", u.Scheme, strings.Join(schemeNames(), ", "))
+	}
+
+	open := func(u *url.URL) (Source, error) {
+		return factory(o, u)
+	}
+
+	var doc []byte
+	var source Source
+	name := u.Redacted()
+
+	if srv {
+		doc, source, err = fetchSRV(ctx, u, open)
+	} else if source, err = open(u); err == nil {
+		name = source.Name()
+		doc, err = source.Load(ctx)
+	}
+
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch configuration from [%v]: %v", name, err)
+	}
+
+	if f, ok := source.(formatter); ok {
+		if format := strings.ToLower(f.Format()); len(format) > 0 {
+			return doc, format, nil
+		}
+	}
+
+	// opaque URIs e.g. file:config.json carry their path in the opaque part.
+	if len(u.Opaque) > 0 {
+		return doc, formatOf(u.Opaque), nil
+	}
+
+	return doc, formatOf(u.Path), nil
+}
+
+// schemeNames returns the sorted URI schemes of the supported configuration sources.
+func schemeNames() []string {
+	sources.RLock()
+	names := make([]string, 0, len(sources.factories))
+
+	for name := range sources.factories {
+		names = append(names, name)
+	}
+	sources.RUnlock()
+
+	sort.Strings(names)
+
+	return names
+}
+
+// formatOf returns the name of the configuration format matching the extension
+// of the specified path, or an empty string if none matches.
+func formatOf(p string) string {
+	if ext := strings.ToLower(strings.TrimPrefix(path.Ext(p), ".")); len(ext) > 0 {
+		if _, found := formats[ext]; found {
+			return ext
+		}
+
+		if _, found := codecOf(ext); found {
+			return ext
+		}
+	}
+
+	return ""
+}
+
+// doRequest sends req using client and returns the response body,
+// any response with a non 2xx status code is reported as an error.
+func doRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected response status [%v]: %v", res.Status, strings.TrimSpace(string(body)))
+	}
+
+	return body, nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// memorySource is a custom Source serving documents from memory.
+type memorySource struct {
+	name string
+	docs map[string]string
+}
+
+func (s *memorySource) Name() string {
+	return "memory " + s.name
+}
+
+func (s *memorySource) Load(ctx context.Context) ([]byte, error) {
+	if doc, found := s.docs[s.name]; found {
+		return []byte(doc), nil
+	}
+	return nil, errors.New("no such document")
+}
+
+func TestCliCustomSource(t *testing.T) {
+	docs := map[string]string{
+		"api.json": `{"id": 30, "name": "Walter"}`,
+		"api.cue":  "id: 31\nname: \"Wendy\"",
+	}
+
+	RegisterSource("mem", func(u *url.URL) (Source, error) {
+		if len(u.Path) <= 1 {
+			return nil, errors.New("URI must be in the form mem:///<name>")
+		}
+		return &memorySource{name: u.Path[1:], docs: docs}, nil
+	})
+
+	defer func() {
+		sources.Lock()
+		delete(sources.factories, "mem")
+		sources.Unlock()
+	}()
+
+	cases := []struct {
+		uri      string
+		expected testConf
+		err      string
+	}{
+		{"mem:///api.json", testConf{ID: 30, Name: "Walter"}, ""},
+		{"mem:///api.cue", testConf{ID: 31, Name: "Wendy"}, ""},
+		{"mem:///missing.json", testConf{}, "failed to fetch configuration from [memory missing.json]: no such document"},
+		{"mem:///", testConf{}, "URI must be in the form mem:///<name>"},
+		{"s3://bucket/api.json", testConf{}, "supported schemes are: azappconfig, azkv, configmap, file, git, gs, http, https, mem, nats"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+
+	for _, scheme := range []string{"MEM", "https", "", "my-store", "1mem", "mem store"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected RegisterSource to panic for scheme [%v]", scheme)
+				}
+			}()
+
+			RegisterSource(scheme, func(u *url.URL) (Source, error) { return nil, nil })
+		}()
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
+)
+
+// spiffeClient returns an HTTP client authenticating to servers over mTLS with the X.509 SVID
+// obtained from the SPIFFE Workload API, whose address is read from $SPIFFE_ENDPOINT_SOCKET.
+// Servers must present an SVID of one of the specified SPIFFE IDs, or of any ID trusted by
+// the bundles of the workload if none is specified. The client shares the network settings of
+// o, except for TLS. The returned closer stops watching the Workload API for SVID rotations.
+func spiffeClient(ctx context.Context, o *options, ids []string) (*http.Client, io.Closer, error) {
+	authorizer := tlsconfig.AuthorizeAny()
+
+	if len(ids) > 0 {
+		allowed := make([]spiffeid.ID, len(ids))
+
+		for i, id := range ids {
+			var err error
+			if allowed[i], err = spiffeid.FromString(id); err != nil {
+				return nil, nil, fmt.Errorf("invalid SPIFFE ID [%v]: %v", id, err)
+			}
+		}
+
+		authorizer = tlsconfig.AuthorizeOneOf(allowed...)
+	}
+
+	source, err := workloadapi.NewX509Source(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to obtain SVID from the SPIFFE Workload API: %v", err)
+	}
+
+	transport := newTransport(o)
+	transport.TLSClientConfig = tlsconfig.MTLSClientConfig(source, source, authorizer)
+
+	return &http.Client{Transport: transport}, source, nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// applySpread scales the numeric fields of the configuration structure conf tagged with `spread:"<N>%"` by
+// a factor between 1-N% and 1+N%, derived from the identity of the instance and the path of the field, so
+// that a fleet sharing the same values, e.g. polling intervals, does not act in lockstep while every
+// instance keeps its own values across restarts. Nested structures are walked as well, including the elements
+// of slices and arrays of structures.
+func applySpread(o *options, conf interface{}) error {
+	v := reflect.ValueOf(conf)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	id := o.instanceID
+	if len(id) == 0 {
+		id, _ = os.Hostname()
+	}
+
+	return spreadStruct(id, v.Elem(), "")
+}
+
+// spreadStruct scales the fields of the structure v tagged with a spread for the instance id, where path is
+// the dotted JSON path of v in the configuration structure.
+func spreadStruct(id string, v reflect.Value, path string) error {
+	return walkFields(v, path, nil, func(field reflect.Value, f reflect.StructField, path string) (bool, error) {
+		tag := f.Tag.Get("spread")
+		if len(tag) == 0 {
+			return true, nil
+		}
+
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				return false, nil
+			}
+			field = field.Elem()
+		}
+
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(tag, "%"), 64)
+		if err != nil || percent <= 0 || percent >= 100 {
+			return false, fmt.Errorf("invalid spread [%v] of field [%v], it must be a percentage between 0 and 100 exclusive", tag, path)
+		}
+
+		factor := 1 + spreadFactor(id, path)*percent/100
+
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n := math.Round(float64(field.Int()) * factor)
+			if n < math.MinInt64 || n >= math.MaxInt64 || field.OverflowInt(int64(n)) {
+				return false, fmt.Errorf("the spread value of field [%v] overflows [%v]", path, field.Type())
+			}
+			field.SetInt(int64(n))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			n := math.Round(float64(field.Uint()) * factor)
+			if n >= math.MaxUint64 || field.OverflowUint(uint64(n)) {
+				return false, fmt.Errorf("the spread value of field [%v] overflows [%v]", path, field.Type())
+			}
+			field.SetUint(uint64(n))
+		case reflect.Float32, reflect.Float64:
+			field.SetFloat(field.Float() * factor)
+		default:
+			return false, fmt.Errorf("field [%v] of type [%v] cannot be spread, only numbers can", path, field.Type())
+		}
+
+		return false, nil
+	})
+}
+
+// spreadFactor returns a number between -1 and 1 derived from the identity of the instance id and the
+// path of the field, uniformly distributed across instances.
+func spreadFactor(id, path string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+
+	return float64(h.Sum64())/math.MaxUint64*2 - 1
+}
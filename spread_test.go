@@ -0,0 +1,131 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type spreadConf struct {
+	Interval time.Duration `json:"interval" spread:"10%"`
+	Workers  uint8         `json:"workers" spread:"50"`
+	Ratio    float64       `json:"ratio" spread:"20%"`
+	Fixed    int           `json:"fixed"`
+	Poller   *struct {
+		Period int `json:"period" spread:"10%"`
+	} `json:"poller"`
+	Pollers []struct {
+		Period int `json:"period" spread:"10%"`
+	} `json:"pollers"`
+	Ignored string `json:"-" spread:"10%"`
+}
+
+func TestCliSpread(t *testing.T) {
+	parse := func(id string, conf interface{}) error {
+		_, err := Parse("SPREAD", "", nil, conf, WithInstanceID(id), WithEnvironment(nil),
+			WithArgs("", "-config", `{"interval": 1000000000, "workers": 100, "ratio": 1, "fixed": 5, "poller": {"period": 600}, "pollers": [{"period": 600}, {"period": 600}]}`))
+		return err
+	}
+
+	within := func(val, base, percent float64) bool {
+		return val >= base*(1-percent/100)-0.5 && val <= base*(1+percent/100)+0.5
+	}
+
+	var first spreadConf
+	if err := parse("instance-0", &first); err != nil {
+		t.Fatal(err)
+	}
+
+	distinct := 0
+
+	for i := 0; i < 20; i++ {
+		id := "instance-" + string(rune('a'+i))
+
+		conf, again := &spreadConf{}, &spreadConf{}
+		if err := parse(id, conf); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := parse(id, again); err != nil {
+			t.Fatal(err)
+		}
+
+		if *conf.Poller != *again.Poller || conf.Interval != again.Interval || conf.Workers != again.Workers || conf.Ratio != again.Ratio {
+			t.Errorf("expected the spread of instance [%v] to be deterministic, but found: %+v and %+v", id, *conf, *again)
+		}
+
+		if !within(float64(conf.Interval), float64(time.Second), 10) || !within(float64(conf.Workers), 100, 50) ||
+			!within(conf.Ratio, 1, 20) || !within(float64(conf.Poller.Period), 600, 10) || conf.Fixed != 5 ||
+			!within(float64(conf.Pollers[0].Period), 600, 10) || !within(float64(conf.Pollers[1].Period), 600, 10) {
+			t.Errorf("expected the values of instance [%v] to be spread within bounds, but found: %+v, %+v", id, *conf, *conf.Poller)
+		}
+
+		if conf.Pollers[0] != again.Pollers[0] || conf.Pollers[1] != again.Pollers[1] {
+			t.Errorf("expected the spread of the elements of instance [%v] to be deterministic, but found: %+v and %+v", id, conf.Pollers, again.Pollers)
+		}
+
+		if conf.Interval != first.Interval {
+			distinct++
+		}
+	}
+
+	if distinct == 0 {
+		t.Errorf("expected the values to be spread across instances, but found the same ones")
+	}
+
+	cases := []struct {
+		conf interface{}
+		err  string
+	}{
+		{&struct {
+			Interval int `json:"interval" spread:"100%"`
+		}{}, "invalid spread [100%] of field [interval]"},
+		{&struct {
+			Interval int `json:"interval" spread:"x"`
+		}{}, "invalid spread [x] of field [interval]"},
+		{&struct {
+			Name string `json:"name" spread:"10%"`
+		}{}, "field [name] of type [string] cannot be spread"},
+		{&struct {
+			Servers [1]struct {
+				Weight string `json:"weight" spread:"10%"`
+			} `json:"servers"`
+		}{}, "field [servers[0].weight] of type [string] cannot be spread"},
+	}
+
+	for _, c := range cases {
+		if err := parse("instance", c.conf); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error: %v, but found: %v", c.err, err)
+		}
+	}
+
+	// the first instance whose spread raises the value overflows the field.
+	id := "instance"
+	for spreadFactor(id, "workers") < 0.6 {
+		id += "-"
+	}
+
+	overflow := &struct {
+		Workers int8 `json:"workers" spread:"50%"`
+	}{}
+
+	if err := parse(id, overflow); err == nil || !strings.Contains(err.Error(), "the spread value of field [workers] overflows [int8]") {
+		t.Errorf("expected an overflow error, but found: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// springIndexRegex matches the list indexes of the property names of Spring, e.g. servers[0].
+var springIndexRegex = regexp.MustCompile(`\[(\d+)\]`)
+
+// fetchSpring reads the configuration of an application from a Spring Cloud Config Server, located
+// by a spring[+<transport>]://[<user>:<password>@]<host>[:<port>]/<application>/<profile>[/<label>] URI,
+// e.g. spring+http://config:8888/api/production/main, where the transport defaults to https.
+// The dotted property names of the property sources returned by the server, lists included, are turned
+// into the equivalent configuration trees, which are merged by their precedence.
+func fetchSpring(ctx context.Context, o *options, u *url.URL) ([]byte, error) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(u.Host) == 0 || len(parts) < 2 || len(parts) > 3 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return nil, errors.New("URI must be in the form spring[+<transport>]://<host>[:<port>]/<application>/<profile>[/<label>]")
+	}
+
+	transport := "https"
+	if i := strings.Index(u.Scheme, "+"); i > 0 {
+		transport = strings.ToLower(u.Scheme[i+1:])
+	}
+
+	endpoint := &url.URL{Scheme: transport, Host: u.Host, Path: u.Path}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if u.User != nil {
+		password, _ := u.User.Password()
+		req.SetBasicAuth(u.User.Username(), password)
+	}
+
+	body, err := doRequest(o.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &struct {
+		PropertySources []struct {
+			Name   string                 `json:"name"`
+			Source map[string]interface{} `json:"source"`
+		} `json:"propertySources"`
+	}{}
+
+	if err = json.Unmarshal(body, env); err != nil {
+		return nil, fmt.Errorf("invalid Spring Cloud Config environment: %v", err)
+	}
+
+	var merged interface{} = make(map[string]interface{})
+
+	// property sources are listed from the highest precedence to the lowest, and lists are never merged.
+	for i := len(env.PropertySources) - 1; i >= 0; i-- {
+		src := env.PropertySources[i]
+		tree := make(map[string]interface{})
+
+		// names are sorted so that conflicting properties e.g. a and a.b are always reported.
+		names := make([]string, 0, len(src.Source))
+		for name := range src.Source {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			path := strings.Split(springIndexRegex.ReplaceAllString(name, ".[$1]"), ".")
+			if err = setPath(tree, path, src.Source[name]); err != nil {
+				return nil, fmt.Errorf("property [%v] of [%v] conflicts with another one: %v", name, src.Name, err)
+			}
+		}
+
+		merged = deepMerge(merged, springLists(tree))
+	}
+
+	return json.Marshal(merged)
+}
+
+// springLists replaces the objects of val whose keys are all list indexes e.g. [0] with the equivalent lists.
+func springLists(val interface{}) interface{} {
+	obj, ok := val.(map[string]interface{})
+	if !ok {
+		return val
+	}
+
+	list := make([]interface{}, len(obj))
+
+	for key, v := range obj {
+		obj[key] = springLists(v)
+
+		if list != nil {
+			if i, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(key, "["), "]")); err == nil && strings.HasPrefix(key, "[") && i < len(list) {
+				list[i] = obj[key]
+			} else {
+				list = nil
+			}
+		}
+	}
+
+	if list != nil && len(list) > 0 {
+		return list
+	}
+
+	return obj
+}
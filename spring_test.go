@@ -0,0 +1,83 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCliSpring(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "config" || password != "s3cr3t" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/production/main":
+			w.Write([]byte(`{
+				"name": "api",
+				"profiles": ["production"],
+				"label": "main",
+				"propertySources": [
+					{"name": "api-production.yml", "source": {"name": "Zoe", "tags[0]": "prod"}},
+					{"name": "api.yml", "source": {"id": 40, "name": "Default", "online": true, "tags[0]": "a", "tags[1]": "b", "limits.rate": 10}}
+				]
+			}`))
+		case "/api/conflict":
+			w.Write([]byte(`{"propertySources": [{"name": "api.yml", "source": {"name": "Zoe", "name.first": "Zoe"}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	type springConf struct {
+		testConf
+		Tags   []string       `json:"tags"`
+		Limits map[string]int `json:"limits"`
+	}
+
+	cases := []struct {
+		uri      string
+		expected springConf
+		err      string
+	}{
+		{"spring+http://config:s3cr3t@" + host + "/api/production/main", springConf{testConf{ID: 40, Name: "Zoe", Online: true}, []string{"prod"}, map[string]int{"rate": 10}}, ""},
+		{"spring+http://config:s3cr3t@" + host + "/api/conflict", springConf{}, "property [name.first] of [api.yml] conflicts with another one: key [name] is not an object"},
+		{"spring+http://" + host + "/api/production", springConf{}, "unexpected response status [401 Unauthorized]"},
+		{"spring+http://" + host + "/api", springConf{}, "URI must be in the form spring[+<transport>]://<host>[:<port>]/<application>/<profile>[/<label>]"},
+	}
+
+	for _, c := range cases {
+		conf := &springConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || !reflect.DeepEqual(*conf, c.expected) {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
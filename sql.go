@@ -0,0 +1,94 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// fetchSQL assembles the configuration out of the key/value rows returned by a query against a
+// database, located by a sql://<driver>?query=<query>[&dsn=<dsn>] URI, e.g.
+//
+//	sql://postgres?query=SELECT+key,value+FROM+settings+WHERE+app='api'
+//
+// The driver must be registered with database/sql by the application, usually by importing its package,
+// and the data source name defaults to $<envVarPrefix>_SQL_DSN which keeps credentials out of the URI.
+// The query must return two columns, the first is a dot separated path of the key in the configuration,
+// and the second is its value, which is taken as JSON if valid, otherwise as a string.
+func fetchSQL(ctx context.Context, o *options, u *url.URL) ([]byte, error) {
+	query := u.Query()
+
+	driver, stmt, dsn := u.Host, query.Get("query"), query.Get("dsn")
+	if len(driver) == 0 || len(stmt) == 0 {
+		return nil, errors.New("URI must be in the form sql://<driver>?query=<query>[&dsn=<dsn>]")
+	}
+
+	if len(dsn) == 0 {
+		dsn = envValue(o, "SQL_DSN")
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %v", err)
+	}
+	defer rows.Close()
+
+	tree := make(map[string]interface{})
+
+	for rows.Next() {
+		var key string
+		var val sql.NullString
+
+		if err = rows.Scan(&key, &val); err != nil {
+			return nil, fmt.Errorf("query must return key and value columns: %v", err)
+		}
+
+		path := strings.Split(key, ".")
+		for _, p := range path {
+			if len(p) == 0 {
+				return nil, fmt.Errorf("invalid key [%v]", key)
+			}
+		}
+
+		var v interface{}
+		if val.Valid && json.Unmarshal([]byte(val.String), &v) != nil {
+			v = val.String
+		}
+
+		if err = setPath(tree, path, v); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %v", err)
+	}
+
+	return json.Marshal(tree)
+}
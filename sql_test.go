@@ -0,0 +1,134 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// fakeSQLTables maps the data source names of the fake SQL driver to the rows their queries return.
+var fakeSQLTables = map[string][][]driver.Value{
+	"settings": {
+		{"id", "23"},
+		{"name", "Olivia"},
+		{"online", "true"},
+		{"extra.tags", `["a","b"]`},
+	},
+	"conflict": {
+		{"name", "Olivia"},
+		{"name.first", "Olivia"},
+	},
+}
+
+func init() {
+	sql.Register("fake", fakeSQLDriver{})
+}
+
+type fakeSQLDriver struct{}
+
+func (fakeSQLDriver) Open(dsn string) (driver.Conn, error) {
+	rows, found := fakeSQLTables[dsn]
+	if !found {
+		return nil, errors.New("unknown database")
+	}
+	return &fakeSQLConn{rows: rows}, nil
+}
+
+type fakeSQLConn struct {
+	rows [][]driver.Value
+}
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	if !strings.HasPrefix(query, "SELECT") {
+		return nil, errors.New("syntax error")
+	}
+	return &fakeSQLStmt{rows: c.rows}, nil
+}
+
+func (c *fakeSQLConn) Close() error { return nil }
+
+func (c *fakeSQLConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type fakeSQLStmt struct {
+	rows [][]driver.Value
+}
+
+func (s *fakeSQLStmt) Close() error { return nil }
+
+func (s *fakeSQLStmt) NumInput() int { return 0 }
+
+func (s *fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *fakeSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeSQLRows{rows: s.rows}, nil
+}
+
+type fakeSQLRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeSQLRows) Columns() []string { return []string{"key", "value"} }
+
+func (r *fakeSQLRows) Close() error { return nil }
+
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestCliSQL(t *testing.T) {
+	t.Setenv("TEST_SQL_DSN", "settings")
+
+	query := url.QueryEscape("SELECT key, value FROM settings")
+
+	cases := []struct {
+		uri      string
+		expected testConf
+		err      string
+	}{
+		{"sql://fake?query=" + query, testConf{ID: 23, Name: "Olivia", Online: true}, ""},
+		{"sql://fake?dsn=conflict&query=" + query, testConf{}, "key [name] is not an object"},
+		{"sql://fake?dsn=missing&query=" + query, testConf{}, "unknown database"},
+		{"sql://fake?query=DELETE", testConf{}, "query failed: syntax error"},
+		{"sql://unknown?query=" + query, testConf{}, `unknown driver "unknown"`},
+		{"sql://fake", testConf{}, "URI must be in the form sql://<driver>?query=<query>[&dsn=<dsn>]"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
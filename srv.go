@@ -0,0 +1,78 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// lookupSRV resolves SRV records, tests replace it to avoid depending on DNS.
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+// fetchSRV discovers the endpoints of the source located by a <scheme>+srv://<name>/... URI, e.g.
+// https+srv://_config._tcp.internal/api.json, through the DNS SRV records of <name>, then fetches
+// the configuration from the first of them that succeeds, through the sources created by open,
+// and returns it along with the source it was loaded from.
+// Targets are tried in the order of their priority, and randomly by weight among the ones of the
+// same priority.
+func fetchSRV(ctx context.Context, u *url.URL, open func(u *url.URL) (Source, error)) ([]byte, Source, error) {
+	name := u.Hostname()
+	if len(name) == 0 {
+		return nil, nil, fmt.Errorf("URI must be in the form %v://<name>/...", u.Scheme)
+	}
+
+	_, records, err := lookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve SRV records of [%v]: %v", name, err)
+	}
+
+	if len(records) == 0 || (len(records) == 1 && records[0].Target == ".") {
+		return nil, nil, fmt.Errorf("no SRV records found for [%v]", name)
+	}
+
+	var errs []error
+
+	for _, rec := range records {
+		target := *u
+		target.Scheme = strings.TrimSuffix(strings.ToLower(u.Scheme), "+srv")
+		target.Host = net.JoinHostPort(strings.TrimSuffix(rec.Target, "."), strconv.Itoa(int(rec.Port)))
+
+		source, err := open(&target)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		doc, err := source.Load(ctx)
+		if err == nil {
+			return doc, source, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, nil, err
+		}
+
+		errs = append(errs, fmt.Errorf("%v: %v", target.Host, err))
+	}
+
+	return nil, nil, fmt.Errorf("all SRV targets of [%v] failed: %v", name, errors.Join(errs...))
+}
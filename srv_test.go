@@ -0,0 +1,86 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCliSRV(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 25, "name": "Rupert"}`))
+	}))
+	defer srv.Close()
+
+	_, p, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	port, _ := strconv.Atoi(p)
+
+	records := map[string][]*net.SRV{
+		"_config._tcp.internal": {
+			{Target: "127.0.0.1.", Port: 1, Priority: 1},
+			{Target: "127.0.0.1.", Port: uint16(port), Priority: 2},
+		},
+		"_down._tcp.internal": {
+			{Target: "127.0.0.1.", Port: 1, Priority: 1},
+		},
+		"_none._tcp.internal": {
+			{Target: ".", Port: 0},
+		},
+	}
+
+	defer func(lookup func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)) {
+		lookupSRV = lookup
+	}(lookupSRV)
+
+	lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		if recs, found := records[name]; found {
+			return name, recs, nil
+		}
+		return "", nil, errors.New("no such host")
+	}
+
+	cases := []struct {
+		uri      string
+		expected testConf
+		err      string
+	}{
+		{"http+srv://_config._tcp.internal/api.json", testConf{ID: 25, Name: "Rupert"}, ""},
+		{"http+srv://_down._tcp.internal/api.json", testConf{}, "all SRV targets of [_down._tcp.internal] failed: 127.0.0.1:1:"},
+		{"http+srv://_none._tcp.internal/api.json", testConf{}, "no SRV records found for [_none._tcp.internal]"},
+		{"http+srv://_missing._tcp.internal/api.json", testConf{}, "failed to resolve SRV records of [_missing._tcp.internal]: no such host"},
+		{"s3+srv://_config._tcp.internal/api.json", testConf{}, "unsupported configuration URI scheme [s3+srv]"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
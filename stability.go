@@ -0,0 +1,76 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// alphaFields returns the JSON paths, as lists of keys, of the fields of the structure t tagged with
+// `stability:"alpha"`, located at path. The fields of alpha structures are alpha as a whole.
+func alphaFields(t reflect.Type, path []string) [][]string {
+	var fields [][]string
+
+	for _, f := range taggedFields(t, path, "stability", func(value string) bool { return value == "alpha" }, make(map[reflect.Type]bool)) {
+		fields = append(fields, f.path)
+	}
+
+	return fields
+}
+
+// gateAlpha removes the options of the fields tagged with `stability:"alpha"` from the JSON document doc
+// unless alpha options are enabled, warning about each of them, so that experimental options may be shipped
+// without committing to them. Keys match the names of fields regardless of their case, as when decoding.
+func gateAlpha(o *options, doc []byte) ([]byte, error) {
+	if o.alpha || o.confType == nil {
+		return doc, nil
+	}
+
+	fields := alphaFields(o.confType, nil)
+	if len(fields) == 0 {
+		return doc, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+
+	var root map[string]interface{}
+	if err := dec.Decode(&root); err != nil {
+		// documents that are not objects are left to fail decoding.
+		return doc, nil
+	}
+
+	var gated bool
+
+	for _, field := range fields {
+		matchKeys(root, field, func(obj map[string]interface{}, key string) {
+			delete(obj, key)
+			gated = true
+
+			o.logger.Printf("WARNING: ignoring alpha option [%v], enable alpha options with -enable-alpha-config or $%vALPHA=1", strings.Join(field, "."), o.envVarPrefix)
+		})
+	}
+
+	if !gated {
+		return doc, nil
+	}
+
+	return json.Marshal(root)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type alphaConf struct {
+	Name  string `json:"name"`
+	Turbo bool   `json:"turbo" stability:"alpha" env:"TURBO"`
+	Cache struct {
+		Size  int `json:"size"`
+		Shard int `json:"shard" stability:"alpha"`
+	} `json:"cache"`
+	Beta *struct {
+		Level int `json:"level"`
+	} `json:"beta,omitempty" stability:"alpha"`
+}
+
+func TestCliAlphaOptions(t *testing.T) {
+	config := `{"name": "n", "TURBO": true, "cache": {"size": 2, "shard": 3}, "beta": {"level": 4}}`
+
+	enabled := alphaConf{Name: "n", Turbo: true, Beta: &struct {
+		Level int `json:"level"`
+	}{Level: 4}}
+	enabled.Cache.Size, enabled.Cache.Shard = 2, 3
+
+	disabled := alphaConf{Name: "n"}
+	disabled.Cache.Size = 2
+
+	cases := []struct {
+		env      map[string]string
+		args     []string
+		expected alphaConf
+		logs     []string
+		err      string
+	}{
+		{nil, []string{"", "-config", config}, disabled, []string{
+			"WARNING: ignoring alpha option [turbo], enable alpha options with -enable-alpha-config or $ALPHA_ALPHA=1",
+			"WARNING: ignoring alpha option [cache.shard], enable alpha options with -enable-alpha-config or $ALPHA_ALPHA=1",
+			"WARNING: ignoring alpha option [beta], enable alpha options with -enable-alpha-config or $ALPHA_ALPHA=1",
+		}, ""},
+		{nil, []string{"", "-enable-alpha-config", "-config", config}, enabled, nil, ""},
+		{map[string]string{"ALPHA_ALPHA": "1"}, []string{"", "-config", config}, enabled, nil, ""},
+		{map[string]string{"ALPHA_ALPHA": "0", "ALPHA_TURBO": "true"}, []string{"", "-config", `{"name": "n", "cache": {"size": 2}}`}, disabled, []string{
+			"WARNING: ignoring alpha option [turbo] set by $ALPHA_TURBO, enable alpha options with -enable-alpha-config or $ALPHA_ALPHA=1",
+		}, ""},
+		{map[string]string{"ALPHA_ALPHA": "yes"}, []string{""}, alphaConf{}, nil, "invalid value [yes] of $ALPHA_ALPHA, a boolean is expected"},
+	}
+
+	for _, c := range cases {
+		var logs bytes.Buffer
+
+		conf := &alphaConf{}
+
+		_, err := Parse("ALPHA", "", nil, conf, WithEnvironment(c.env), WithArgs(c.args...), WithLogger(log.New(&logs, "", 0)))
+
+		var found []string
+		if logs.Len() > 0 {
+			found = strings.Split(strings.TrimSuffix(logs.String(), "\n"), "\n")
+		}
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || !reflect.DeepEqual(*conf, c.expected) || !reflect.DeepEqual(found, c.logs) {
+			t.Errorf("expected output: (%+v, %v, %v), but found: (%+v, %v, %v)", c.expected, c.logs, c.err, *conf, found, err)
+		}
+	}
+}
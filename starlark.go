@@ -0,0 +1,140 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// starlarkMaxSteps bounds the computation a Starlark configuration is allowed to perform.
+const starlarkMaxSteps = 10000000
+
+// evalStarlark executes doc as a Starlark program that must assign the configuration object to
+// a global named "config". The program has no access to the file system nor to load statements,
+// it is only given the following builtins:
+//
+//	env(name, default="")  the value of the environment variable $<envVarPrefix>_<name>.
+//	profile                the value of $<envVarPrefix>_PROFILE, e.g. "production".
+//	instance.hostname      the host name of the running instance.
+//	instance.pid           the process identifier of the running instance.
+func evalStarlark(o *options, doc []byte) ([]byte, error) {
+	if !o.starlark {
+		return nil, errors.New("starlark configurations are disabled, they have to be enabled with the WithStarlark option")
+	}
+
+	getEnv := prefixedEnv(o)
+
+	hostname, _ := os.Hostname()
+
+	predeclared := starlark.StringDict{
+		"env": starlark.NewBuiltin("env", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var name, defVal string
+			if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "name", &name, "default?", &defVal); err != nil {
+				return nil, err
+			}
+			return starlark.String(getEnv(name, defVal)), nil
+		}),
+		"profile": starlark.String(getEnv("PROFILE", "")),
+		"instance": starlarkstruct.FromStringDict(starlark.String("instance"), starlark.StringDict{
+			"hostname": starlark.String(hostname),
+			"pid":      starlark.MakeInt(os.Getpid()),
+		}),
+	}
+
+	thread := &starlark.Thread{Name: "config"}
+	thread.SetMaxExecutionSteps(starlarkMaxSteps)
+
+	stop := context.AfterFunc(o.ctx, func() { thread.Cancel(o.ctx.Err().Error()) })
+	defer stop()
+
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, "config.star", doc, predeclared)
+	if err != nil {
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			return nil, fmt.Errorf("starlark evaluation failed: %v", evalErr.Backtrace())
+		}
+		return nil, fmt.Errorf("starlark evaluation failed: %v", err)
+	}
+
+	conf, found := globals["config"]
+	if !found {
+		return nil, errors.New("starlark configuration must assign the configuration object to a global named config")
+	}
+
+	val, err := fromStarlark(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(val)
+}
+
+// fromStarlark converts a Starlark value into its equivalent JSON compatible Go value.
+func fromStarlark(v starlark.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Int:
+		if i, ok := v.Int64(); ok {
+			return i, nil
+		}
+		return json.Number(v.String()), nil
+	case starlark.Float:
+		return float64(v), nil
+	case *starlark.Dict:
+		obj := make(map[string]interface{}, v.Len())
+		for _, item := range v.Items() {
+			key, ok := item[0].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("starlark dict keys must be strings, found: %v", item[0].Type())
+			}
+
+			val, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, err
+			}
+
+			obj[string(key)] = val
+		}
+		return obj, nil
+	case starlark.Indexable:
+		arr := make([]interface{}, v.Len())
+		for i := range arr {
+			val, err := fromStarlark(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+
+			arr[i] = val
+		}
+		return arr, nil
+	default:
+		return nil, fmt.Errorf("starlark values of type %v cannot be part of the configuration", v.Type())
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCliStarlark(t *testing.T) {
+	t.Setenv("STAR_PROFILE", "production")
+	t.Setenv("STAR_NAME", "Heidi")
+
+	hostname, _ := os.Hostname()
+
+	cases := []struct {
+		script   string
+		opts     []Option
+		expected testConf
+		err      string
+	}{
+		{`config = {"id": 11, "name": env("NAME") + "@" + instance.hostname, "online": profile == "production"}`,
+			[]Option{WithStarlark()}, testConf{ID: 11, Name: "Heidi@" + hostname, Online: true}, ""},
+		{`config = {"id": len([x for x in range(3)]), "name": env("MISSING", "none")}`,
+			[]Option{WithStarlark()}, testConf{ID: 3, Name: "none"}, ""},
+		{`config = {"id": 1}`, nil, testConf{}, "starlark configurations are disabled"},
+		{`conf = {"id": 1}`, []Option{WithStarlark()}, testConf{}, "starlark configuration must assign"},
+		{`load("x.star", "y")`, []Option{WithStarlark()}, testConf{}, "starlark evaluation failed: "},
+		{"def f():\n  for i in range(100000000):\n    pass\nf()\nconfig = {}", []Option{WithStarlark()}, testConf{}, "starlark evaluation failed: "},
+		{`config = {"id": set}`, []Option{WithStarlark()}, testConf{}, "starlark evaluation failed: "},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-format", "star", "-config", c.script}}, func(in *input) (string, error) {
+			return Parse("STAR", "", nil, conf, c.opts...)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.HasPrefix(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// taggedField is a field of the configuration structure located by its JSON path, as a list of keys,
+// along with the value of its tag.
+type taggedField struct {
+	path  []string
+	value string
+}
+
+// taggedFields returns the fields of the structure t, located at path, whose tag of the specified name has
+// a value accepted by accept. The fields of the structures of accepted fields are not walked, they are
+// accepted as a whole. The types of the structures holding t are recorded in parents, see walkType.
+func taggedFields(t reflect.Type, path []string, tag string, accept func(value string) bool, parents map[reflect.Type]bool) []taggedField {
+	var fields []taggedField
+
+	_ = walkType(t, strings.Join(path, "."), parents, func(_ reflect.Value, f reflect.StructField, _ string) (bool, error) {
+		name, _ := jsonField(f)
+
+		value := f.Tag.Get(tag)
+		accepted := accept(value)
+
+		// embedded structures share the path of the structure embedding them.
+		if len(name) == 0 && !accepted {
+			fields = append(fields, taggedFields(f.Type, path, tag, accept, parents)...)
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			name = f.Name
+		}
+
+		fieldPath := append(path[:len(path):len(path)], name)
+
+		if accepted {
+			fields = append(fields, taggedField{fieldPath, value})
+		} else if isNestedStruct(f.Type) {
+			fields = append(fields, taggedFields(f.Type, fieldPath, tag, accept, parents)...)
+		}
+
+		return false, nil
+	})
+
+	return fields
+}
+
+// matchKeys calls visit with every object of the decoded JSON document root holding the option located by
+// field, a JSON path as a list of keys, along with its key. Keys match the names of fields regardless of
+// their case, as when decoding.
+func matchKeys(root map[string]interface{}, field []string, visit func(obj map[string]interface{}, key string)) {
+	objs := []map[string]interface{}{root}
+
+	for i, name := range field {
+		var nested []map[string]interface{}
+
+		for _, obj := range objs {
+			for key, val := range obj {
+				if !strings.EqualFold(key, name) {
+					continue
+				}
+
+				if i < len(field)-1 {
+					if val, ok := val.(map[string]interface{}); ok {
+						nested = append(nested, val)
+					}
+					continue
+				}
+
+				visit(obj, key)
+			}
+		}
+
+		objs = nested
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+type taggedNode struct {
+	Name  string      `json:"name" deprecated:"use id instead"`
+	Next  *taggedNode `json:"next"`
+	Child struct {
+		Node *taggedNode `json:"node" deprecated:"use next instead"`
+		Tree *taggedNode `json:"tree"`
+	} `json:"child"`
+}
+
+func TestTaggedFieldsRecursive(t *testing.T) {
+	fields := taggedFields(reflect.TypeOf(taggedNode{}), nil, "deprecated", func(value string) bool { return len(value) > 0 }, make(map[reflect.Type]bool))
+
+	// the fields of recursive structures are not walked again.
+	expected := []taggedField{{[]string{"name"}, "use id instead"}, {[]string{"child", "node"}, "use next instead"}}
+
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected output: %+v, but found: %+v", expected, fields)
+	}
+}
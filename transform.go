@@ -0,0 +1,97 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Transform transforms the value of a placeholder piped into it, e.g. ${HOSTNAME|upper}.
+type Transform func(val string) (string, error)
+
+// pipes maps the names of the transforms values of placeholders may be piped into to these transforms.
+var pipes = struct {
+	sync.RWMutex
+	funcs map[string]Transform
+}{funcs: map[string]Transform{
+	"base64":       encodeBase64,
+	"base64decode": decodeBase64,
+	"lower":        func(val string) (string, error) { return strings.ToLower(val), nil },
+	"trim":         func(val string) (string, error) { return strings.TrimSpace(val), nil },
+	"upper":        func(val string) (string, error) { return strings.ToUpper(val), nil },
+}}
+
+// escapingTransforms are the transforms of the pipes whose outputs may hold any text, e.g. a multi-line PEM
+// certificate, so that the values of the pipelines holding them are escaped to be injected into JSON strings.
+var escapingTransforms = map[string]bool{"base64decode": true}
+
+// RegisterTransform makes the values of placeholders piped into the transform of the specified name, e.g.
+// slug for ${BRANCH|slug}, transformed by transform, besides the built-in ones: base64, base64decode, lower,
+// trim and upper, the base64 ones preceding the names of variables as well, e.g. ${base64decode:CERT}. It is
+// meant to be called from init functions, and it panics if transform is nil, if the name is not made of
+// lowercase letters or numbers starting with a letter, or if it is already registered.
+func RegisterTransform(name string, transform Transform) {
+	if !schemeRegex.MatchString(name) || transform == nil {
+		panic("config: RegisterTransform requires a name of lowercase letters or numbers and a transform")
+	}
+
+	pipes.Lock()
+	defer pipes.Unlock()
+
+	if _, found := pipes.funcs[name]; found {
+		panic(fmt.Sprintf("config: RegisterTransform called twice for transform [%v]", name))
+	}
+
+	pipes.funcs[name] = transform
+}
+
+// pipe transforms val, the value of the placeholder of the environment variable name, by the transforms of
+// the pipeline in order, e.g. trim|lower. Transforms are given the raw values, which are escaped once at the
+// end of pipelines holding any of escapingTransforms.
+func pipe(name, val, pipeline string) (string, error) {
+	if len(pipeline) == 0 {
+		return val, nil
+	}
+
+	var escape bool
+
+	for _, step := range strings.Split(pipeline, "|") {
+		pipes.RLock()
+		transform, found := pipes.funcs[step]
+		pipes.RUnlock()
+
+		if !found {
+			return "", fmt.Errorf("unknown transform [%v] of the placeholder of $%v", step, name)
+		}
+
+		var err error
+		if val, err = transform(val); err != nil {
+			return "", fmt.Errorf("failed to transform the value of $%v by [%v]: %v", name, step, err)
+		}
+
+		escape = escape || escapingTransforms[step]
+	}
+
+	if escape {
+		escaped := quote(val)
+		val = escaped[1 : len(escaped)-1]
+	}
+
+	return val, nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// setPath sets val in tree at the location described by path, creating the intermediate
+// objects as needed. It fails if an intermediate location already holds a non-object value.
+func setPath(tree map[string]interface{}, path []string, val interface{}) error {
+	for i, key := range path[:len(path)-1] {
+		next, found := tree[key]
+
+		if !found {
+			next = make(map[string]interface{})
+			tree[key] = next
+		}
+
+		obj, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("key [%v] is not an object", strings.Join(path[:i+1], "."))
+		}
+
+		tree = obj
+	}
+
+	tree[path[len(path)-1]] = val
+
+	return nil
+}
+
+// deepMerge merges src into dst and returns the result, objects are merged recursively
+// while any other value of src replaces the one of dst.
+func deepMerge(dst, src interface{}) interface{} {
+	dstObj, ok := dst.(map[string]interface{})
+	if !ok {
+		return src
+	}
+
+	srcObj, ok := src.(map[string]interface{})
+	if !ok {
+		return src
+	}
+
+	for key, val := range srcObj {
+		if existing, found := dstObj[key]; found {
+			dstObj[key] = deepMerge(existing, val)
+		} else {
+			dstObj[key] = val
+		}
+	}
+
+	return dstObj
+}
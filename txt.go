@@ -0,0 +1,89 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// txtAttributeRegex matches the TXT records holding a single <key>=<value> attribute.
+var txtAttributeRegex = regexp.MustCompile(`\A([A-Za-z0-9_-]+(?:\.[A-Za-z0-9_-]+)*)=(.*)\z`)
+
+// lookupTXT resolves TXT records, tests replace it to avoid depending on DNS.
+var lookupTXT = net.DefaultResolver.LookupTXT
+
+// fetchTXT reads a small bootstrap configuration, e.g. the URI of the actual configuration service,
+// from the DNS TXT records of a domain name, located by a txt://<name> URI. When every record is a
+// <key>=<value> attribute, the dotted keys are set to their values, taken as JSON if valid, otherwise
+// as strings. Otherwise the single TXT record of the name is the configuration document.
+func fetchTXT(ctx context.Context, o *options, u *url.URL) ([]byte, error) {
+	name := u.Hostname()
+	if len(name) == 0 {
+		return nil, errors.New("URI must be in the form txt://<name>")
+	}
+
+	records, err := lookupTXT(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve TXT records of [%v]: %v", name, err)
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no TXT records found for [%v]", name)
+	}
+
+	attrs := make([][]string, 0, len(records))
+
+	for _, record := range records {
+		m := txtAttributeRegex.FindStringSubmatch(record)
+		if m == nil {
+			if len(records) > 1 {
+				return nil, fmt.Errorf("TXT records of [%v] must all be <key>=<value> attributes, unless there is only one", name)
+			}
+
+			return []byte(record), nil
+		}
+
+		attrs = append(attrs, m[1:])
+	}
+
+	// the order of the records is not significant in DNS, so attributes are sorted by their keys,
+	// which also makes conflicting keys e.g. a and a.b always reported.
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i][0] < attrs[j][0] })
+
+	tree := make(map[string]interface{})
+
+	for _, attr := range attrs {
+		var val interface{}
+		if json.Unmarshal([]byte(attr[1]), &val) != nil {
+			val = attr[1]
+		}
+
+		if err = setPath(tree, strings.Split(attr[0], "."), val); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(tree)
+}
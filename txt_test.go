@@ -0,0 +1,69 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCliTXT(t *testing.T) {
+	records := map[string][]string{
+		"_config.edge.example.com":   {"online=true", "name=Alan", "id=41"},
+		"_json.edge.example.com":     {`{"id": 42, "name": "Barbara"}`},
+		"_mixed.edge.example.com":    {`{"id": 42}`, "name=Barbara"},
+		"_conflict.edge.example.com": {"name=Alan", "name.first=Alan"},
+	}
+
+	defer func(lookup func(ctx context.Context, name string) ([]string, error)) {
+		lookupTXT = lookup
+	}(lookupTXT)
+
+	lookupTXT = func(ctx context.Context, name string) ([]string, error) {
+		if recs, found := records[name]; found {
+			return recs, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	cases := []struct {
+		uri      string
+		expected testConf
+		err      string
+	}{
+		{"txt://_config.edge.example.com", testConf{ID: 41, Name: "Alan", Online: true}, ""},
+		{"txt://_json.edge.example.com", testConf{ID: 42, Name: "Barbara"}, ""},
+		{"txt://_mixed.edge.example.com", testConf{}, "TXT records of [_mixed.edge.example.com] must all be <key>=<value> attributes, unless there is only one"},
+		{"txt://_conflict.edge.example.com", testConf{}, "key [name] is not an object"},
+		{"txt://_missing.edge.example.com", testConf{}, "failed to resolve TXT records of [_missing.edge.example.com]: no such host"},
+		{"txt:///", testConf{}, "URI must be in the form txt://<name>"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf)
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// jsonUnmarshalerType is the type of the values decoding themselves from JSON, e.g. json.RawMessage.
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unknownOptions returns the dotted JSON paths of the options of the JSON document doc that match no field
+// of the structure t, e.g. the ones written for a newer version of the application, sorted. Keys match the
+// names of fields regardless of their case, as when decoding. Invalid documents have no unknown options.
+func unknownOptions(t reflect.Type, doc []byte) []string {
+	var val interface{}
+	if err := json.Unmarshal(doc, &val); err != nil {
+		return nil
+	}
+
+	var unknown []string
+	walkUnknown(t, val, "", &unknown)
+
+	sort.Strings(unknown)
+
+	return unknown
+}
+
+// walkUnknown adds the paths of the options of val, located at path, that match no field of t to unknown.
+func walkUnknown(t reflect.Type, val interface{}, path string, unknown *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return
+	}
+
+	join := func(key string) string {
+		if len(path) == 0 {
+			return key
+		}
+		return path + "." + key
+	}
+
+	switch v := val.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := jsonFields(t)
+			for key, elem := range v {
+				if ft, found := fields[strings.ToLower(key)]; found {
+					walkUnknown(ft, elem, join(key), unknown)
+				} else {
+					*unknown = append(*unknown, join(key))
+				}
+			}
+		case reflect.Map:
+			for key, elem := range v {
+				walkUnknown(t.Elem(), elem, join(key), unknown)
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, elem := range v {
+				walkUnknown(t.Elem(), elem, fmt.Sprintf("%v[%v]", path, i), unknown)
+			}
+		}
+	}
+}
+
+// jsonFields maps the lowercase JSON names of the fields of the structure t, including the ones of the
+// structures it embeds, to their types.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, found := jsonField(f)
+		if !found {
+			continue
+		}
+
+		if len(name) == 0 {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			// the fields of the embedding structure take precedence over the embedded ones.
+			for key, embedded := range jsonFields(ft) {
+				if _, found := fields[key]; !found {
+					fields[key] = embedded
+				}
+			}
+			continue
+		}
+
+		fields[strings.ToLower(name)] = f.Type
+	}
+
+	return fields
+}
+
+// reportUnknown fails listing the options of the JSON document doc unknown to the configuration structure
+// when they are rejected, see WithStrictKeys, or warns about them when forward compatibility is enabled, see
+// WithForwardCompatibility, they are ignored silently otherwise.
+func reportUnknown(o *options, doc []byte) error {
+	if (!o.strictKeys && !o.forwardCompatible) || o.confType == nil {
+		return nil
+	}
+
+	unknown := unknownOptions(o.confType, doc)
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	if o.strictKeys {
+		return fmt.Errorf("unknown configuration options: %v", strings.Join(unknown, ", "))
+	}
+
+	o.logger.Printf("WARNING: ignoring options unknown to this version of the application: %v", strings.Join(unknown, ", "))
+
+	return nil
+}
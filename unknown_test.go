@@ -0,0 +1,120 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type unknownBase struct {
+	Region string `json:"region"`
+}
+
+type unknownConf struct {
+	unknownBase
+	Name    string                     `json:"name"`
+	Timeout time.Duration              `json:"timeout"`
+	Started time.Time                  `json:"started"`
+	Servers []struct{ Host string }    `json:"servers"`
+	Labels  map[string]struct{ V int } `json:"labels"`
+	Extra   json.RawMessage            `json:"extra"`
+	Ignored string                     `json:"-"`
+}
+
+func TestUnknownOptions(t *testing.T) {
+	doc := `{"region": "eu", "NAME": "app", "started": "2024-01-01T00:00:00Z", "retries": 3, "servers": [{"host": "a"}, {"host": "b", "port": 1}],
+		"labels": {"x": {"v": 1, "w": 2}}, "extra": {"anything": true}, "ignored": "x", "tls": {"enabled": true}}`
+
+	expected := []string{"ignored", "labels.x.w", "retries", "servers[1].port", "tls"}
+
+	if unknown := unknownOptions(reflect.TypeOf(unknownConf{}), []byte(doc)); !reflect.DeepEqual(unknown, expected) {
+		t.Errorf("expected unknown options: %v, but found: %v", expected, unknown)
+	}
+
+	if unknown := unknownOptions(reflect.TypeOf(unknownConf{}), []byte(`{`)); unknown != nil {
+		t.Errorf("expected invalid documents to have no unknown options, but found: %v", unknown)
+	}
+}
+
+func TestCliForwardCompatibility(t *testing.T) {
+	var logs bytes.Buffer
+
+	conf := &testConf{}
+
+	_, err := Parse("FWD", "", nil, conf, WithForwardCompatibility(), WithLogger(log.New(&logs, "", 0)), WithEnvironment(nil),
+		WithArgs("", "-config", `{"id": 1, "name": "app", "retries": 3}`))
+
+	if expected := "WARNING: ignoring options unknown to this version of the application: retries\n"; err != nil || conf.ID != 1 || logs.String() != expected {
+		t.Errorf("expected output: (%q, <nil>), but found: (%q, %v)", expected, logs.String(), err)
+	}
+
+	logs.Reset()
+
+	if _, err = Parse("FWD", "", nil, &testConf{}, WithLogger(log.New(&logs, "", 0)), WithEnvironment(nil),
+		WithArgs("", "-config", `{"id": 1, "retries": 3}`)); err != nil || logs.Len() > 0 {
+		t.Errorf("expected unknown options to be ignored silently, but found: (%q, %v)", logs.String(), err)
+	}
+
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "in.json"), filepath.Join(dir, "out.json")
+
+	if err = os.WriteFile(in, []byte(`{"id": 1, "retries": 3}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = Parse("FWD", "", nil, &testConf{}, WithEnvironment(nil), WithArgs("", "-convert", in, out)); err == nil || !strings.Contains(err.Error(), `unknown field "retries"`) {
+		t.Errorf("expected the conversion to fail on unknown options, but found: %v", err)
+	}
+
+	logs.Reset()
+
+	_, err = Parse("FWD", "", nil, &testConf{}, WithForwardCompatibility(), WithLogger(log.New(&logs, "", 0)), WithEnvironment(nil), WithArgs("", "-convert", in, out))
+
+	if data, _ := os.ReadFile(out); err != nil || string(data) != "{\n  \"id\": 1,\n  \"retries\": 3\n}\n" || !strings.Contains(logs.String(), "retries") {
+		t.Errorf("expected the unknown options to be kept, but found: (%q, %q, %v)", data, logs.String(), err)
+	}
+}
+
+func TestCliStrictKeys(t *testing.T) {
+	cases := []struct {
+		config string
+		opts   []Option
+		err    string
+	}{
+		{`{"id": 1, "name": "app"}`, []Option{WithStrictKeys()}, ""},
+		{`{"id": 1, "nmae": "app", "prot": 80}`, []Option{WithStrictKeys()}, "unknown configuration options: nmae, prot"},
+		{`{"id": 1, "prot": 80}`, []Option{WithStrictKeys(), WithForwardCompatibility()}, "unknown configuration options: prot"},
+		{`config = {"id": 1, "prot": 80}`, []Option{WithStrictKeys(), WithStarlark(), WithArgs("", "-config-format", "star")}, "unknown configuration options: prot"},
+		{`{"id": 1, "prot": 80}`, nil, ""},
+	}
+
+	for _, c := range cases {
+		_, err := Parse("STRICT", "", nil, &testConf{}, append([]Option{WithEnvironment(map[string]string{"STRICT_CONFIG": c.config}), WithArgs("")}, c.opts...)...)
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) {
+			t.Errorf("expected error: %v, but found: %v", c.err, err)
+		}
+	}
+}
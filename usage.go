@@ -0,0 +1,136 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// UsageReport tells how an application is configured, to help deciding which of its options are worth
+// keeping, it is written as JSON by Parse when WithUsageReport is set, so that reports can be gathered
+// across a fleet. Options are described by their dotted JSON paths, e.g. database.host, and listed in the
+// order their fields are declared, the ones the structure lacks following in the order of the document.
+type UsageReport struct {
+	// Version is the release version of the application, if known.
+	Version string `json:"version,omitempty"`
+
+	// Changed lists the options whose values differ from the default ones.
+	Changed []string `json:"changed"`
+
+	// Default lists the options left to their default values.
+	Default []string `json:"default"`
+
+	// Unused lists the options of the configuration documents that the application never reads, since
+	// they do not match any field of its configuration structure.
+	Unused []string `json:"unused"`
+}
+
+// newUsageReport compares the values of the configuration structure conf to its default ones, defaults
+// being conf encoded as JSON before it was parsed, and looks up the options of the configuration
+// document doc that are not part of the structure.
+func newUsageReport(info *ReleaseInfo, defaults, doc []byte, conf interface{}) (*UsageReport, error) {
+	report := &UsageReport{Changed: []string{}, Default: []string{}, Unused: []string{}}
+
+	if info != nil {
+		report.Version = info.ReleaseVersion
+	}
+
+	values, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	before, after, given := make(map[string]interface{}), make(map[string]interface{}), make(map[string]interface{})
+
+	for _, f := range []struct {
+		doc    []byte
+		leaves map[string]interface{}
+	}{{defaults, before}, {values, after}, {doc, given}} {
+		var val interface{}
+		if err = json.Unmarshal(f.doc, &val); err != nil {
+			return nil, err
+		}
+
+		flatten("", val, f.leaves)
+	}
+
+	for path, val := range after {
+		if prev, found := before[path]; found && jsonEqual(prev, val) {
+			report.Default = append(report.Default, path)
+		} else {
+			report.Changed = append(report.Changed, path)
+		}
+	}
+
+	for path := range given {
+		if _, found := after[path]; !found {
+			report.Unused = append(report.Unused, path)
+		}
+	}
+
+	// options are listed in the order their fields are declared, then in the order of the document.
+	order := leafOrder(values, doc)
+
+	sortPaths(report.Changed, order)
+	sortPaths(report.Default, order)
+	sortPaths(report.Unused, order)
+
+	return report, nil
+}
+
+// flatten adds the leaves of the decoded JSON value val to leaves, keyed by their dotted paths
+// under prefix. Objects are walked while any other value, including arrays, is a leaf.
+func flatten(prefix string, val interface{}, leaves map[string]interface{}) {
+	obj, ok := val.(map[string]interface{})
+	if !ok || (len(obj) == 0 && len(prefix) > 0) {
+		if len(prefix) > 0 {
+			leaves[prefix] = val
+		}
+		return
+	}
+
+	for key, v := range obj {
+		if len(prefix) > 0 {
+			key = prefix + "." + key
+		}
+
+		flatten(key, v, leaves)
+	}
+}
+
+// jsonEqual reports whether the decoded JSON values a and b are equal.
+func jsonEqual(a, b interface{}) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+
+	return string(x) == string(y)
+}
+
+// writeUsageReport writes the usage report of the configuration to the writer set by WithUsageReport.
+func writeUsageReport(o *options, info *ReleaseInfo, defaults, doc []byte, conf interface{}) error {
+	report, err := newUsageReport(info, defaults, doc, conf)
+	if err != nil {
+		return fmt.Errorf("failed to create the configuration usage report: %v", err)
+	}
+
+	if err = json.NewEncoder(o.usageReport).Encode(report); err != nil {
+		return fmt.Errorf("failed to write the configuration usage report: %v", err)
+	}
+
+	return nil
+}
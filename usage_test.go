@@ -0,0 +1,64 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type usageConf struct {
+	Port     int `json:"port"`
+	Database struct {
+		Host string `json:"host"`
+		Pool int    `json:"pool"`
+	} `json:"database"`
+	Tags []string `json:"tags"`
+}
+
+func TestCliUsageReport(t *testing.T) {
+	cases := []struct {
+		config   string
+		expected UsageReport
+	}{
+		{`{}`, UsageReport{Version: "1.2.0", Changed: []string{}, Default: []string{"port", "database.host", "database.pool", "tags"}, Unused: []string{}}},
+		{`{"debug": false, "tags": ["a"], "database": {"legacy": true, "pool": 4, "host": "db"}, "port": 9090}`,
+			UsageReport{Version: "1.2.0", Changed: []string{"port", "database.host", "tags"}, Default: []string{"database.pool"}, Unused: []string{"debug", "database.legacy"}}},
+	}
+
+	for _, c := range cases {
+		var out bytes.Buffer
+
+		conf := &usageConf{Port: 8080}
+		conf.Database.Pool = 4
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config", c.config}}, func(in *input) (string, error) {
+			return Parse("TEST", "", &ReleaseInfo{ReleaseVersion: "1.2.0"}, conf, WithUsageReport(&out))
+		})
+
+		report := UsageReport{}
+		if err == nil {
+			err = json.Unmarshal(out.Bytes(), &report)
+		}
+
+		if err != nil || !reflect.DeepEqual(report, c.expected) {
+			t.Errorf("expected report: %+v, but found: (%+v, %v)", c.expected, report, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// validateTags checks the configuration structure conf against the constraints of the `validate` tags of
+// its fields, e.g. `validate:"min=1,url"`, when enabled by WithValidatorTags, and fails listing every
+// violated constraint along with the dotted JSON path of its field, e.g. [server.port] min=1.
+func validateTags(o *options, conf interface{}) error {
+	if !o.validatorTags {
+		return nil
+	}
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
+	// fields are named after their JSON names, the ones operators know.
+	validate.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if len(name) == 0 {
+			return f.Name
+		}
+		return name
+	})
+
+	err := validate.Struct(conf)
+
+	var violations validator.ValidationErrors
+	if !errors.As(err, &violations) {
+		if err != nil {
+			return fmt.Errorf("failed to validate the configuration: %v", err)
+		}
+		return nil
+	}
+
+	constraints := make([]string, 0, len(violations))
+
+	for _, v := range violations {
+		// the namespace of the field starts with the name of the configuration structure.
+		_, path, _ := strings.Cut(v.Namespace(), ".")
+
+		constraint := v.Tag()
+		if len(v.Param()) > 0 {
+			constraint += "=" + v.Param()
+		}
+
+		constraints = append(constraints, fmt.Sprintf("[%v] %v", path, constraint))
+	}
+
+	return fmt.Errorf("invalid configuration, violated constraints: %v", strings.Join(constraints, ", "))
+}
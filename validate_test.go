@@ -0,0 +1,53 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+type validateConf struct {
+	Level  string `json:"level" validate:"oneof=debug info"`
+	Server struct {
+		Port     int    `json:"port" validate:"min=1,max=65535"`
+		Endpoint string `json:"endpoint" validate:"omitempty,url"`
+	} `json:"server"`
+	Workers []int `json:"workers" validate:"dive,gt=0"`
+	Name    string
+}
+
+func TestCliValidatorTags(t *testing.T) {
+	cases := []struct {
+		config string
+		opts   []Option
+		err    string
+	}{
+		{`{"level": "info", "server": {"port": 80, "endpoint": "https://x"}, "workers": [1]}`, []Option{WithValidatorTags()}, ""},
+		{`{"level": "trace", "server": {"port": 0, "endpoint": "x"}, "workers": [1, 0]}`, []Option{WithValidatorTags()},
+			"invalid configuration, violated constraints: [level] oneof=debug info, [server.port] min=1, [server.endpoint] url, [workers[1]] gt=0"},
+		{`{"level": "trace"}`, nil, ""},
+	}
+
+	for _, c := range cases {
+		_, err := Parse("VAL", "", nil, &validateConf{}, append(c.opts, WithEnvironment(nil), WithArgs("", "-config", c.config))...)
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) {
+			t.Errorf("expected error: %v, but found: %v", c.err, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Validator is implemented by the configuration structure, or any of the values it holds, checking itself
+// once loaded, e.g. constraints between several of its options.
+type Validator interface {
+	Validate() error
+}
+
+// validatorType is the type of the values checking themselves.
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// callValidators calls the Validate methods of the values of the configuration structure conf implementing
+// Validator, the nested values before the ones holding them so that the latter may rely on the former, and
+// fails listing every failure along with the dotted JSON path of the value that failed.
+func callValidators(conf interface{}) error {
+	v := reflect.ValueOf(conf)
+	if !v.IsValid() {
+		return nil
+	}
+
+	var failures []string
+
+	walkValues(v, "", make(map[uintptr]bool), func(v reflect.Value, path string) {
+		checker, ok := implementer(v, validatorType)
+		if !ok {
+			return
+		}
+
+		err := checker.(Validator).Validate()
+		if err == nil {
+			return
+		}
+
+		if len(path) == 0 {
+			path = "."
+		}
+
+		// the methods of embedded structures are promoted to the ones embedding them, failing twice.
+		failure := fmt.Sprintf("[%v] %v", path, err)
+		for _, f := range failures {
+			if f == failure {
+				return
+			}
+		}
+
+		failures = append(failures, failure)
+	})
+
+	if len(failures) > 0 {
+		return fmt.Errorf("invalid configuration: %v", strings.Join(failures, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type PortRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+func (r PortRange) Validate() error {
+	if r.Min > r.Max {
+		return errors.New("min must not exceed max")
+	}
+	return nil
+}
+
+type tlsSettings struct {
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+}
+
+func (s *tlsSettings) Validate() error {
+	if (len(s.Cert) == 0) != (len(s.Key) == 0) {
+		return errors.New("cert and key must be set together")
+	}
+	return nil
+}
+
+type validatorConf struct {
+	PortRange
+	TLS      *tlsSettings         `json:"tls"`
+	Ranges   []PortRange          `json:"ranges"`
+	Backends map[string]PortRange `json:"backends"`
+	Mode     string               `json:"mode"`
+}
+
+func (c *validatorConf) Validate() error {
+	if c.Mode == "secure" && c.TLS == nil {
+		return errors.New("secure mode requires tls")
+	}
+	return nil
+}
+
+func TestCliValidators(t *testing.T) {
+	cases := []struct {
+		config string
+		err    string
+	}{
+		{`{"min": 1, "max": 2, "tls": {"cert": "c", "key": "k"}, "mode": "secure"}`, ""},
+		{`{"mode": "secure"}`, "invalid configuration: [.] secure mode requires tls"},
+		{`{"min": 3, "max": 2, "tls": {"cert": "c"}, "ranges": [{"min": 1, "max": 2}, {"min": 2, "max": 1}], "backends": {"a": {"min": 5}}}`,
+			"invalid configuration: [.] min must not exceed max, [tls] cert and key must be set together, [ranges[1]] min must not exceed max, [backends.a] min must not exceed max"},
+	}
+
+	for _, c := range cases {
+		_, err := Parse("VLD", "", nil, &validatorConf{}, WithEnvironment(nil), WithArgs("", "-config", c.config))
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) {
+			t.Errorf("expected error: %v, but found: %v", c.err, err)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// walkValues calls visit with every value v holds, located at path, along with the dotted JSON path of the
+// value, the values held before the ones holding them. The values pointed to are visited once, seen holding
+// the pointers already followed.
+func walkValues(v reflect.Value, path string, seen map[uintptr]bool, visit func(v reflect.Value, path string)) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			return
+		}
+
+		seen[v.Pointer()] = true
+		walkValues(v.Elem(), path, seen, visit)
+
+		return
+	case reflect.Interface:
+		if !v.IsNil() {
+			walkValues(v.Elem(), path, seen, visit)
+		}
+		return
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+
+			name, found := jsonField(f)
+			if !found {
+				continue
+			}
+
+			// embedded structures share the path of the structure embedding them.
+			fieldPath := path
+			if len(name) > 0 {
+				fieldPath = joinPath(path, name)
+			}
+
+			walkValues(v.Field(i), fieldPath, seen, visit)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkValues(v.Index(i), fmt.Sprintf("%v[%v]", path, i), seen, visit)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			walkValues(iter.Value(), joinPath(path, fmt.Sprint(iter.Key().Interface())), seen, visit)
+		}
+	}
+
+	visit(v, path)
+}
+
+// walkFields calls visit with every field of the structure v, located at path, along with the dotted JSON
+// path of the field, in the order they are declared, skipping the fields left out of JSON. Embedded structures
+// share the path of the structure embedding them. The fields visit returns true for are walked in turn when
+// they hold structures, including the ones pointed to unless nil, or slices and arrays of structures whose
+// elements are located by their indexes, e.g. servers[0].host. The walk stops at the first error of visit.
+// The types of the structures being walked, v and the ones holding it, are recorded in parents, which may be
+// nil, so that visitors walking the structures of nil pointers do not recurse forever, see walkType.
+func walkFields(v reflect.Value, path string, parents map[reflect.Type]bool, visit func(field reflect.Value, f reflect.StructField, path string) (bool, error)) error {
+	if parents == nil {
+		parents = make(map[reflect.Type]bool)
+	}
+
+	if t := v.Type(); !parents[t] {
+		parents[t] = true
+		defer delete(parents, t)
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field, f := v.Field(i), v.Type().Field(i)
+
+		name, found := jsonField(f)
+		if !found {
+			continue
+		}
+
+		fieldPath := path
+		if len(name) > 0 {
+			fieldPath = joinPath(path, name)
+		}
+
+		descend, err := visit(field, f, fieldPath)
+		if err != nil {
+			return err
+		}
+
+		if descend {
+			if err = walkNested(field, fieldPath, parents, visit); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// walkNested walks the fields of the structure v holds, or the ones of its elements, located at path, as
+// walkFields does.
+func walkNested(v reflect.Value, path string, parents map[reflect.Type]bool, visit func(field reflect.Value, f reflect.StructField, path string) (bool, error)) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case isNestedStruct(v.Type()):
+		return walkFields(v, path, parents, visit)
+	case isStructList(v.Type()):
+		for i := 0; i < v.Len(); i++ {
+			if err := walkNested(v.Index(i), fmt.Sprintf("%v[%v]", path, i), parents, visit); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// walkType walks the fields of the structure type t, or the one it points to, located at path, as walkFields
+// does with the fields of a zero value of t, unless t is one of the parents being walked already, e.g. the
+// type of Next in type node struct{ Next *node }, so that recursive structures are not walked forever.
+func walkType(t reflect.Type, path string, parents map[reflect.Type]bool, visit func(field reflect.Value, f reflect.StructField, path string) (bool, error)) error {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || parents[t] {
+		return nil
+	}
+
+	return walkFields(reflect.New(t).Elem(), path, parents, visit)
+}
+
+// isStructList reports whether t is a slice or an array of structures, or of pointers to ones, whose fields
+// are walked individually.
+func isStructList(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && isNestedStruct(t.Elem())
+}
+
+// jsonField returns the JSON name of the field f, or an empty name for the embedded structures sharing the
+// path of the structure embedding them, and whether f is encoded at all, following the rules of encoding/json:
+// fields tagged with `json:"-"` are left out, and so are unexported fields unless they embed structures,
+// whose exported fields are promoted.
+func jsonField(f reflect.StructField) (string, bool) {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case name == "-" || (!f.IsExported() && (!f.Anonymous || t.Kind() != reflect.Struct)):
+		return "", false
+	case len(name) > 0:
+		return name, true
+	case f.Anonymous && t.Kind() == reflect.Struct:
+		return "", true
+	default:
+		return f.Name, true
+	}
+}
+
+// implementer returns v as a value of the interface type iface, or its address when only its pointer
+// implements iface, e.g. to call methods of pointer receivers.
+func implementer(v reflect.Value, iface reflect.Type) (interface{}, bool) {
+	if !v.CanInterface() {
+		return nil, false
+	}
+
+	if v.CanAddr() && v.Kind() != reflect.Ptr && reflect.PointerTo(v.Type()).Implements(iface) {
+		return v.Addr().Interface(), true
+	}
+
+	if v.Type().Implements(iface) {
+		return v.Interface(), true
+	}
+
+	return nil, false
+}
+
+// joinPath returns the dotted JSON path of key within path.
+func joinPath(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+
+	return path + "." + key
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type walkNode struct {
+	Name     string      `json:"name" default:"node" desc:"The name of the node." deprecated:"use id instead"`
+	Weight   int         `json:"weight" spread:"10%" required:"true"`
+	Next     *walkNode   `json:"next" envprefix:"NEXT"`
+	Children []walkNode  `json:"children"`
+	Peers    []*walkNode `json:"peers"`
+}
+
+func TestCliRecursiveStructures(t *testing.T) {
+	conf := &walkNode{}
+
+	_, err := Parse("WALK", "", nil, conf, WithAutoEnv(), WithZeroValueWarnings(), WithEnvironment(map[string]string{"WALK_NAME": "root", "WALK_NEXT_NAME": "next"}),
+		WithArgs("", "-config", `{"weight": 10, "next": {"weight": 20, "name": "${NAME:-x}"}, "children": [{"weight": 30}]}`))
+
+	if err != nil || conf.Name != "root" || conf.Next == nil || conf.Next.Name != "next" || len(conf.Children) != 1 || conf.Children[0].Name != "node" {
+		t.Errorf("expected the recursive configuration to be parsed, but found: (%+v, %v)", conf, err)
+	}
+
+	names, err := EnvVars("WALK", &walkNode{}, WithAutoEnv(), WithEnvironment(nil), WithArgs("", "-config", `{}`))
+	if all := strings.Join(names, ","); err != nil || !strings.Contains(all, "WALK_NAME,WALK_WEIGHT,WALK_CHILDREN,WALK_PEERS") || strings.Contains(all, "WALK_NEXT_") {
+		t.Errorf("expected the environment variables of the recursive configuration, but found: (%v, %v)", names, err)
+	}
+
+	// the fields of recursive structures are described once.
+	sample, err := Sample(&walkNode{Next: &walkNode{}})
+	if err != nil || strings.Count(string(sample), "// The name of the node.") != 1 {
+		t.Errorf("expected the sample of the recursive configuration, but found: (%s, %v)", sample, err)
+	}
+}
+
+type walkBase struct {
+	Host string `json:"host"`
+}
+
+type walkName string
+
+func TestJSONField(t *testing.T) {
+	type conf struct {
+		Plain    string
+		Tagged   string `json:"tagged,omitempty"`
+		Ignored  string `json:"-"`
+		private  string
+		walkBase
+		*walkNode
+		walkName
+		Named walkBase `json:"named"`
+	}
+
+	expected := []struct {
+		name  string
+		found bool
+	}{{"Plain", true}, {"tagged", true}, {"", false}, {"", false}, {"", true}, {"", true}, {"", false}, {"named", true}}
+
+	typ := reflect.TypeOf(conf{})
+	for i, e := range expected {
+		if name, found := jsonField(typ.Field(i)); name != e.name || found != e.found {
+			t.Errorf("expected JSON field of [%v] to be (%q, %v), but found: (%q, %v)", typ.Field(i).Name, e.name, e.found, name, found)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// warnZeroValues warns about the fields of the configuration structure conf holding zero values once every
+// source is applied, when enabled by WithZeroValueWarnings, along with their dotted JSON paths in the order
+// they are declared, so that half-filled configurations are noticed early. Fields tagged with `optional:"true"`,
+// or `required:"<bool>"` since the required ones fail loading already, are not reported. The fields of nested
+// structures, and of the elements of slices, are reported individually, while the nested structures that
+// are not set at all, i.e. nil pointers, are reported as a whole.
+func warnZeroValues(o *options, conf interface{}) error {
+	if !o.zeroValueWarnings {
+		return nil
+	}
+
+	v := reflect.ValueOf(conf)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	zero, err := zeroFields(v.Elem(), "")
+	if err != nil {
+		return err
+	}
+
+	for _, path := range zero {
+		o.logger.Printf("WARNING: option [%v] holds its zero value, tag its field with `optional:\"true\"` if it is meant to", path)
+	}
+
+	return nil
+}
+
+// zeroFields returns the dotted JSON paths of the fields of the structure v holding zero values that are not
+// marked optional, where path is the path of v.
+func zeroFields(v reflect.Value, path string) ([]string, error) {
+	var zero []string
+
+	err := walkFields(v, path, nil, func(field reflect.Value, f reflect.StructField, path string) (bool, error) {
+		if tag, found := f.Tag.Lookup("optional"); found {
+			optional, err := strconv.ParseBool(tag)
+			if err != nil {
+				return false, fmt.Errorf("invalid optional tag [%v] of field [%v], a boolean is expected", tag, path)
+			}
+
+			if optional {
+				return false, nil
+			}
+		}
+
+		if _, found := f.Tag.Lookup("required"); found {
+			return false, nil
+		}
+
+		// structures that are set, and the elements of slices, are reported field by field.
+		switch {
+		case isNestedStruct(field.Type()) && (field.Kind() != reflect.Ptr || !field.IsNil()):
+			return true, nil
+		case isStructList(field.Type()) && field.Len() > 0:
+			return true, nil
+		}
+
+		if field.IsZero() {
+			zero = append(zero, path)
+		}
+
+		return false, nil
+	})
+
+	return zero, err
+}
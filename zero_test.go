@@ -0,0 +1,92 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+type ZeroBase struct {
+	Region string `json:"region"`
+}
+
+type zeroConf struct {
+	ZeroBase
+	Name     string   `json:"name"`
+	Port     int      `json:"port" env:"PORT"`
+	Comment  string   `json:"comment" optional:"true"`
+	ID       string   `json:"id" required:"false"`
+	Tags     []string `json:"tags"`
+	Database struct {
+		Host string `json:"host"`
+		User string `json:"user"`
+	} `json:"database"`
+	Cache *struct {
+		Host string `json:"host"`
+	} `json:"cache"`
+	Tracing *struct {
+		Endpoint string `json:"endpoint"`
+	} `json:"tracing"`
+	Servers []struct {
+		Host string `json:"host"`
+		Port int    `json:"port" optional:"true"`
+	} `json:"servers"`
+}
+
+func TestCliZeroValueWarnings(t *testing.T) {
+	cases := []struct {
+		config   string
+		env      map[string]string
+		opts     []Option
+		warnings []string
+	}{
+		{`{"name": "app"}`, nil, nil, nil},
+		// the nested structures that are not set, e.g. cache, are reported as a whole.
+		{`{"name": "app", "database": {"host": "db"}, "tracing": {}}`, map[string]string{"ZERO_PORT": "80"}, []Option{WithZeroValueWarnings()}, []string{"region", "tags", "database.user", "cache", "tracing.endpoint", "servers"}},
+		{`{"region": "eu", "name": "app", "port": 80, "tags": ["a"], "database": {"host": "db", "user": "app"}, "cache": {"host": "cache"}, "tracing": {"endpoint": "otel"}, "servers": [{"host": "a"}]}`, nil, []Option{WithZeroValueWarnings()}, nil},
+		{`{"region": "eu", "name": "app", "port": 80, "tags": ["a"], "database": {"host": "db", "user": "app"}, "cache": {"host": "cache"}, "tracing": {"endpoint": "otel"}, "servers": [{"host": "a"}, {"port": 80}]}`, nil, []Option{WithZeroValueWarnings()}, []string{"servers[1].host"}},
+	}
+
+	for _, c := range cases {
+		var logs bytes.Buffer
+
+		_, err := Parse("ZERO", "", nil, &zeroConf{}, append(c.opts, WithEnvironment(c.env), WithArgs("", "-config", c.config), WithLogger(log.New(&logs, "", 0)))...)
+		if err != nil {
+			t.Fatalf("expected no error, but found: %v", err)
+		}
+
+		var expected string
+		for _, path := range c.warnings {
+			expected += "WARNING: option [" + path + "] holds its zero value, tag its field with `optional:\"true\"` if it is meant to\n"
+		}
+
+		if logs.String() != expected {
+			t.Errorf("expected warnings:\n%v\nbut found:\n%v", expected, logs.String())
+		}
+	}
+
+	_, err := Parse("ZERO", "", nil, &struct {
+		Name string `json:"name" optional:"maybe"`
+	}{}, WithEnvironment(nil), WithArgs("", "-config", `{}`), WithZeroValueWarnings())
+
+	if expected := "invalid optional tag [maybe] of field [name], a boolean is expected"; err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected error: %v, but found: %v", expected, err)
+	}
+}
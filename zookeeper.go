@@ -0,0 +1,98 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// zkSessionTimeout is the session timeout requested from ZooKeeper.
+const zkSessionTimeout = 10 * time.Second
+
+// fetchZooKeeper reads the data of a znode, located by a zk://[<user>:<password>@]<host>[:<port>][,<host>[:<port>]...]/<path>
+// URI, e.g. zk://zk1:2181,zk2:2181/services/api/config or zk://[fd00::1]:2181,[fd00::2]:2181/services/api/config.
+// Credentials if specified are added to the session
+// with the digest scheme.
+func fetchZooKeeper(ctx context.Context, o *options, u *url.URL) ([]byte, error) {
+	if len(u.Host) == 0 || len(u.Path) <= 1 {
+		return nil, errors.New("URI must be in the form zk://[<user>:<password>@]<host>[:<port>][,<host>[:<port>]...]/<path>")
+	}
+
+	servers := strings.Split(u.Host, ",")
+	for i, server := range servers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			servers[i] = net.JoinHostPort(strings.Trim(server, "[]"), "2181")
+		}
+	}
+
+	dialer := zk.WithDialer(func(_, addr string, timeout time.Duration) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		return dial(ctx, o, addr)
+	})
+
+	conn, _, err := zk.Connect(servers, zkSessionTimeout, zk.WithLogInfo(false), zk.WithLogger(zkLogger{}), dialer)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	type result struct {
+		data []byte
+		err  error
+	}
+
+	done := make(chan result, 1)
+
+	// requests block until a session is established, so they are bounded by ctx.
+	go func() {
+		if u.User != nil {
+			if err := conn.AddAuth("digest", []byte(u.User.String())); err != nil {
+				done <- result{nil, err}
+				return
+			}
+		}
+
+		data, _, err := conn.Get(u.Path)
+		done <- result{data, err}
+	}()
+
+	select {
+	case res := <-done:
+		if errors.Is(res.err, zk.ErrNoNode) {
+			return nil, fmt.Errorf("znode [%v] does not exist", u.Path)
+		}
+		return res.data, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// zkLogger discards the logs of the ZooKeeper client, errors are reported by fetchZooKeeper instead.
+type zkLogger struct{}
+
+// Printf discards the message.
+func (zkLogger) Printf(string, ...interface{}) {}
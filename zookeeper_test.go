@@ -0,0 +1,140 @@
+/*
+Copyright 2018 Ahmed Zaher
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeZooKeeper starts a ZooKeeper server speaking just enough of the protocol to serve the data
+// of the specified znodes to sessions authenticated with auth, it returns the server address.
+func fakeZooKeeper(t *testing.T, auth string, znodes map[string]string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	read := func(conn net.Conn) ([]byte, error) {
+		var n int32
+		if err := binary.Read(conn, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, n)
+		_, err := io.ReadFull(conn, buf)
+		return buf, err
+	}
+
+	write := func(conn net.Conn, fields ...interface{}) {
+		var buf []byte
+		for _, f := range fields {
+			buf, _ = binary.Append(buf, binary.BigEndian, f)
+		}
+		binary.Write(conn, binary.BigEndian, int32(len(buf)))
+		conn.Write(buf)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+
+				// connect request, answered with protocol version, timeout, session id and password.
+				if _, err := read(conn); err != nil {
+					return
+				}
+				write(conn, int32(0), int32(10000), int64(1), int32(16), make([]byte, 16), false)
+
+				authenticated := len(auth) == 0
+
+				for {
+					req, err := read(conn)
+					if err != nil {
+						return
+					}
+
+					xid, op := int32(binary.BigEndian.Uint32(req)), int32(binary.BigEndian.Uint32(req[4:]))
+
+					switch op {
+					case 100: // auth: type, scheme, credentials.
+						n := binary.BigEndian.Uint32(req[12:])
+						cred := string(req[20+n:])
+						authenticated = authenticated || cred == auth
+						write(conn, xid, int64(1), int32(0))
+					case 4: // get data: path, watch.
+						n := binary.BigEndian.Uint32(req[8:])
+						data, found := znodes[string(req[12:12+n])]
+						switch {
+						case !authenticated:
+							write(conn, xid, int64(1), int32(-102))
+						case !found:
+							write(conn, xid, int64(1), int32(-101))
+						default:
+							write(conn, xid, int64(1), int32(0), int32(len(data)), []byte(data), make([]byte, 68))
+						}
+					default:
+						write(conn, xid, int64(1), int32(0))
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestCliZooKeeper(t *testing.T) {
+	addr := fakeZooKeeper(t, "ops:secret", map[string]string{"/services/api": `{"id":23,"name":"zk"}`})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cases := []struct {
+		uri      string
+		expected testConf
+		err      string
+	}{
+		{"zk://ops:secret@127.0.0.1:1," + addr + "/services/api", testConf{ID: 23, Name: "zk"}, ""},
+		{"zk://ops:secret@[::1]:1," + addr + "/services/api", testConf{ID: 23, Name: "zk"}, ""},
+		{"zk://ops:secret@" + addr + "/services/missing", testConf{}, "znode [/services/missing] does not exist"},
+		{"zk://" + addr + "/services/api", testConf{}, "zk: not authenticated"},
+		{"zk://" + addr, testConf{}, "URI must be in the form zk://"},
+	}
+
+	for _, c := range cases {
+		conf := &testConf{}
+
+		_, err := withMockedArgs(&input{args: []string{"", "-config-uri", c.uri}}, func(in *input) (string, error) {
+			return Parse("TEST", "", nil, conf, WithContext(ctx))
+		})
+
+		if (err == nil && len(c.err) > 0) || (err != nil && (len(c.err) == 0 || !strings.Contains(err.Error(), c.err))) || *conf != c.expected {
+			t.Errorf("expected output: (%+v, %v), but found: (%+v, %v)", c.expected, c.err, *conf, err)
+		}
+	}
+}